@@ -0,0 +1,312 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReadAIFFTags reads AIFF/AIFC metadata from the io.ReadSeeker, returning the resulting
+// metadata in a Metadata implementation, or non-nil error if there was a problem.
+// The Metadata returned can be asserted to *MetadataAIFF to access AIFF-specific metadata.
+// See http://www-mmsp.ece.mcgill.ca/Documents/AudioFormats/AIFF/AIFF.html for details.
+func ReadAIFFTags(r io.ReadSeeker) (Metadata, error) {
+	return readAIFFTags(r, Options{})
+}
+
+// readAIFFTags implements ReadAIFFTags, with the options opts (for the ID3 chunk).
+func readAIFFTags(r io.ReadSeeker, opts Options) (Metadata, error) {
+	form, err := readString(r, 4)
+	if err != nil {
+		return nil, err
+	}
+	if form != "FORM" {
+		return nil, errors.New("expected 'FORM'")
+	}
+
+	// FORM chunk size
+	_, err = readUint(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	formType, err := readString(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MetadataAIFF{
+		compressionType: "NONE",
+		compressionName: "not compressed",
+		text:            make(map[string]string),
+	}
+	switch formType {
+	case "AIFF":
+		m.fileType = AIFF
+	case "AIFC":
+		m.fileType = AIFC
+	default:
+		return nil, fmt.Errorf("expected 'AIFF' or 'AIFC', got %q", formType)
+	}
+
+	for {
+		id, err := readString(r, 4)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		size, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+
+		switch id {
+		case "COMM":
+			err = m.readCommChunk(r, size)
+
+		case "NAME", "AUTH", "(c) ", "ANNO":
+			err = m.readTextChunk(r, id, size)
+
+		case "ID3 ", "id3 ":
+			err = m.readID3Chunk(r, size, opts)
+
+		default:
+			_, err = r.Seek(int64(size), io.SeekCurrent)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Chunks are word aligned, the pad byte is not included in the size.
+		if size%2 == 1 {
+			_, err = r.Seek(1, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// MetadataAIFF is the implementation of Metadata for AIFF and AIFC files.  The Metadata
+// returned by ReadAIFFTags can be asserted to *MetadataAIFF to access AIFF-specific metadata.
+type MetadataAIFF struct {
+	fileType        FileType
+	compressionType string
+	compressionName string
+	text            map[string]string // NAME, AUTH and (c) chunks, keyed by chunk ID
+	annotations     []string          // ANNO chunks, in the order they appear
+	id3             Metadata          // embedded ID3v2 tag, or nil
+}
+
+// COMM chunk
+// Channels            <16>
+// Sample frames       <32>
+// Sample size         <16>
+// Sample rate         <80> (IEEE 754 extended)
+// -- AIFC only
+// Compression type    <4 bytes>
+// Compression name    <pascal string>
+func (m *MetadataAIFF) readCommChunk(r io.Reader, size uint) error {
+	b, err := readBytes(r, size)
+	if err != nil {
+		return err
+	}
+
+	if m.fileType != AIFC {
+		return nil
+	}
+
+	if len(b) < 22 {
+		return fmt.Errorf("invalid AIFC COMM chunk: expected at least %d bytes, got %d", 22, len(b))
+	}
+	m.compressionType = string(b[18:22])
+	m.compressionName = ""
+
+	b = b[22:]
+	if len(b) > 0 && int(b[0]) < len(b) {
+		m.compressionName = string(b[1 : 1+int(b[0])])
+	}
+	return nil
+}
+
+// NAME, AUTH, (c) and ANNO chunks
+// Text  <size bytes>
+func (m *MetadataAIFF) readTextChunk(r io.Reader, id string, size uint) error {
+	b, err := readBytes(r, size)
+	if err != nil {
+		return err
+	}
+
+	if id == "ANNO" {
+		m.annotations = append(m.annotations, trimString(string(b)))
+		return nil
+	}
+	m.text[id] = trimString(string(b))
+	return nil
+}
+
+// ID3 chunk
+// ID3v2 tag  <size bytes>
+func (m *MetadataAIFF) readID3Chunk(r io.Reader, size uint, opts Options) error {
+	b, err := readBytes(r, size)
+	if err != nil {
+		return err
+	}
+
+	m.id3, err = readID3v2Chunk(b, opts)
+	return err
+}
+
+// getText returns the text of the chunk id, falling back to the value from the embedded
+// ID3v2 tag (using id3) if there is one.
+func (m *MetadataAIFF) getText(id string, id3 func(Metadata) string) string {
+	if v := m.text[id]; v != "" {
+		return v
+	}
+	if m.id3 != nil && id3 != nil {
+		return id3(m.id3)
+	}
+	return ""
+}
+
+// Annotations returns the text of the ANNO chunks in the order they appear, or nil if
+// there are none.
+func (m *MetadataAIFF) Annotations() []string {
+	return m.annotations
+}
+
+// Copyright returns the copyright notice (from the "(c) " chunk), or an empty string if
+// unavailable.
+func (m *MetadataAIFF) Copyright() string {
+	return m.text["(c) "]
+}
+
+// CompressionType returns the compression type of the audio data (i.e. "NONE", "sowt",
+// "fl32"), which is always "NONE" for AIFF files.
+func (m *MetadataAIFF) CompressionType() string {
+	return m.compressionType
+}
+
+// CompressionName returns the human readable name of the compression type of the audio data.
+func (m *MetadataAIFF) CompressionName() string {
+	return m.compressionName
+}
+
+// Format returns the format of the embedded ID3v2 tag, or UnknownFormat if there is none.
+func (m *MetadataAIFF) Format() Format {
+	if m.id3 != nil {
+		return m.id3.Format()
+	}
+	return UnknownFormat
+}
+
+func (m *MetadataAIFF) FileType() FileType { return m.fileType }
+
+func (m *MetadataAIFF) Raw() map[string]interface{} {
+	raw := make(map[string]interface{}, len(m.text)+len(m.annotations))
+	if m.id3 != nil {
+		for k, v := range m.id3.Raw() {
+			raw[k] = v
+		}
+	}
+	for k, v := range m.text {
+		raw[k] = v
+	}
+	for _, v := range m.annotations {
+		raw[numberedName(raw, "ANNO")] = v
+	}
+	return raw
+}
+
+func (m *MetadataAIFF) Title() string       { return m.getText("NAME", Metadata.Title) }
+func (m *MetadataAIFF) Album() string       { return m.getText("", Metadata.Album) }
+func (m *MetadataAIFF) Artist() string      { return m.getText("AUTH", Metadata.Artist) }
+func (m *MetadataAIFF) AlbumArtist() string { return m.getText("", Metadata.AlbumArtist) }
+func (m *MetadataAIFF) Composer() string    { return m.getText("", Metadata.Composer) }
+func (m *MetadataAIFF) Genre() string       { return m.getText("", Metadata.Genre) }
+func (m *MetadataAIFF) Lyrics() string      { return m.getText("", Metadata.Lyrics) }
+func (m *MetadataAIFF) ISRC() string        { return m.getText("", isrc) }
+
+func (m *MetadataAIFF) DiscSubtitle() string { return m.getText("", discSubtitle) }
+
+func (m *MetadataAIFF) Comment() string {
+	if len(m.annotations) > 0 && m.annotations[0] != "" {
+		return m.annotations[0]
+	}
+	return m.getText("", Metadata.Comment)
+}
+
+func (m *MetadataAIFF) Year() int {
+	if m.id3 != nil {
+		return m.id3.Year()
+	}
+	return 0
+}
+
+func (m *MetadataAIFF) Track() (int, int) {
+	if m.id3 != nil {
+		return m.id3.Track()
+	}
+	return 0, 0
+}
+
+func (m *MetadataAIFF) Disc() (int, int) {
+	if m.id3 != nil {
+		return m.id3.Disc()
+	}
+	return 0, 0
+}
+
+func (m *MetadataAIFF) TrackPosition() Position {
+	if p, ok := m.id3.(PositionMetadata); ok {
+		return p.TrackPosition()
+	}
+	return Position{}
+}
+
+func (m *MetadataAIFF) DiscPosition() Position {
+	if p, ok := m.id3.(PositionMetadata); ok {
+		return p.DiscPosition()
+	}
+	return Position{}
+}
+
+func (m *MetadataAIFF) Picture() *Picture {
+	if m.id3 != nil {
+		return m.id3.Picture()
+	}
+	return nil
+}
+
+func (m *MetadataAIFF) Pictures() []*Picture {
+	if p, ok := m.id3.(PicturesMetadata); ok {
+		return p.Pictures()
+	}
+	return nil
+}
+
+func (m *MetadataAIFF) ReplayGain() ReplayGainInfo {
+	if r, ok := m.id3.(ReplayGainMetadata); ok {
+		return r.ReplayGain()
+	}
+	return ReplayGainInfo{}
+}
+
+func (m *MetadataAIFF) BPM() int {
+	if b, ok := m.id3.(BPMMetadata); ok {
+		return b.BPM()
+	}
+	return 0
+}
+
+func (m *MetadataAIFF) Compilation() bool {
+	return compilation(m.id3)
+}
@@ -0,0 +1,80 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestAIFFCompression(t *testing.T) {
+	tests := []struct {
+		path            string
+		fileType        FileType
+		compressionType string
+		compressionName string
+	}{
+		{"without_tags/sample.aiff", AIFF, "NONE", "not compressed"},
+		{"without_tags/sample.aifc", AIFC, "sowt", "little-endian"},
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[*MetadataAIFF](t, tt.path)
+		if got := m.FileType(); got != tt.fileType {
+			t.Errorf("%v: FileType() = %v, expected: %v", tt.path, got, tt.fileType)
+		}
+		if got := m.CompressionType(); got != tt.compressionType {
+			t.Errorf("%v: CompressionType() = %q, expected: %q", tt.path, got, tt.compressionType)
+		}
+		if got := m.CompressionName(); got != tt.compressionName {
+			t.Errorf("%v: CompressionName() = %q, expected: %q", tt.path, got, tt.compressionName)
+		}
+	}
+}
+
+func TestAIFFTextChunks(t *testing.T) {
+	m := readTestFile[*MetadataAIFF](t, "with_tags/sample.text.aifc")
+
+	testValue(t, "Test Title", m.Title())
+	testValue(t, "Test Artist", m.Artist())
+	testValue(t, "Test Comment", m.Comment())
+	testValue(t, "2000 Test Label", m.Copyright())
+	if got, want := m.Annotations(), []string{"Test Comment", "Second note"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Annotations() = %q, expected: %q", got, want)
+	}
+	if got := m.Raw()["ANNO_0"]; got != "Second note" {
+		t.Errorf("Raw()[%q] = %#v, expected: %q", "ANNO_0", got, "Second note")
+	}
+	if got := m.Format(); got != UnknownFormat {
+		t.Errorf("Format() = %v, expected: %v", got, UnknownFormat)
+	}
+	if got := m.CompressionType(); got != "sowt" {
+		t.Errorf("CompressionType() = %q, expected: %q", got, "sowt")
+	}
+}
+
+func TestAIFFID3Chunk(t *testing.T) {
+	// sample.id3.aiff has an odd length ID3 chunk (with a pad byte) before SSND.
+	f, err := os.Open("testdata/with_tags/sample.id3.aiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	if got := m.FileType(); got != AIFF {
+		t.Errorf("FileType() = %v, expected: %v", got, AIFF)
+	}
+	if got := m.Format(); got != ID3v2_3 {
+		t.Errorf("Format() = %v, expected: %v", got, ID3v2_3)
+	}
+	if _, ok := m.Raw()["TIT2"]; !ok {
+		t.Errorf("Raw() missing %q", "TIT2")
+	}
+}
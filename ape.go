@@ -0,0 +1,298 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// apeHeaderSize is the size of the APEv2 tag header and footer.
+const apeHeaderSize = 32
+
+var apePreamble = []byte("APETAGEX")
+
+// apeHeader is a type which represents an APEv2 tag header (or footer).
+type apeHeader struct {
+	Preamble  [8]byte // "APETAGEX"
+	Version   uint32  // 1000 (APEv1) or 2000 (APEv2)
+	Size      uint32  // Tag size in bytes including footer and all items, excluding the header.
+	ItemCount uint32
+	Flags     uint32
+	Reserved  [8]byte
+}
+
+// APEv2 item flags.  Bits 29-31 are only meaningful in the tag header/footer flags
+// and are ignored for items.
+const (
+	apeItemReadOnly  = 1 << 0 // Item is read only (not enforced when reading)
+	apeItemTypeShift = 1      // Item type (bits 1-2, see below)
+	apeItemTypeMask  = 0x3
+)
+
+// APEv2 item types (bits 1-2 of the item flags).
+const (
+	apeItemText     = 0 // UTF-8 text
+	apeItemBinary   = 1 // Binary information
+	apeItemExternal = 2 // Locator of external stored information (UTF-8 text)
+)
+
+// APEv2 binary items which contain cover art (a NUL-terminated filename followed by the image data),
+// and the corresponding picture type (see pictureTypes).
+var apeCoverArtTypes = map[string]byte{
+	"cover art (front)": 0x03,
+	"cover art (back)":  0x04,
+}
+
+// apeFileTypes are the file types which use APEv2 tags, keyed by the magic at the start of
+// the file.
+var apeFileTypes = map[string]FileType{
+	"MAC ": APE, // Monkey's Audio
+	"wvpk": WV,  // WavPack
+	"MPCK": MPC, // Musepack SV8
+	"MP+":  MPC, // Musepack SV7
+	"OFR ": OFR, // OptimFROG
+}
+
+// apeFileType returns the file type identified by the magic at the start of b, or
+// UnknownFileType if it is not a file type which uses APEv2 tags.
+func apeFileType(b []byte) FileType {
+	for magic, fileType := range apeFileTypes {
+		if bytes.HasPrefix(b, []byte(magic)) {
+			return fileType
+		}
+	}
+	return UnknownFileType
+}
+
+// ReadAPETags reads APEv2 tags from the io.ReadSeeker, returning the resulting metadata in a
+// Metadata implementation, or non-nil error if there was a problem.  The tag is expected at
+// the end of the data, optionally followed by an ID3v1 tag.  The file type is identified from
+// the start of the data, which is read from the current position of r.
+// See https://wiki.hydrogenaud.io/index.php?title=APEv2_specification for details.
+func ReadAPETags(r io.ReadSeeker) (Metadata, error) {
+	b, err := readBytes(r, 4)
+	if err != nil {
+		return nil, err
+	}
+	fileType := apeFileType(b)
+
+	h, err := findAPEFooter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Size < apeHeaderSize {
+		return nil, fmt.Errorf("invalid APEv2 tag size: %d", h.Size)
+	}
+
+	// Seek back over the footer and items to the first item.
+	_, err = r.Seek(-int64(h.Size), io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("could not seek to start of APEv2 items: %w", err)
+	}
+
+	b, err = readBytes(r, uint(h.Size-apeHeaderSize))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &metadataAPE{
+		c:        make(map[string]interface{}),
+		fileType: fileType,
+	}
+	for i := uint32(0); i < h.ItemCount; i++ {
+		b, err = m.readAPEItem(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// findAPEFooter looks for the APEv2 footer at the end of the data (or before an ID3v1 tag),
+// leaving r positioned after the footer.
+func findAPEFooter(r io.ReadSeeker) (*apeHeader, error) {
+	for _, offset := range []int64{-apeHeaderSize, -apeHeaderSize - 128} {
+		_, err := r.Seek(offset, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		var h apeHeader
+		err = binary.Read(r, binary.LittleEndian, &h)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Equal(h.Preamble[:], apePreamble) {
+			return &h, nil
+		}
+	}
+	return nil, errors.New("expected 'APETAGEX'")
+}
+
+// APEv2 item
+// Value size    <32> (little endian)
+// Item flags    <32> (little endian)
+// Key           <ASCII text> $00
+// Value         <value size bytes>
+func (m *metadataAPE) readAPEItem(b []byte) ([]byte, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("invalid APEv2 item: expected at least %d bytes, got %d", 8, len(b))
+	}
+	size := binary.LittleEndian.Uint32(b[0:4])
+	flags := binary.LittleEndian.Uint32(b[4:8])
+	b = b[8:]
+
+	keyValue := bytes.SplitN(b, singleZero, 2)
+	if len(keyValue) != 2 {
+		return nil, errors.New("invalid APEv2 item: expected NUL-terminated key")
+	}
+	key := string(keyValue[0])
+	b = keyValue[1]
+
+	if uint32(len(b)) < size {
+		return nil, fmt.Errorf("invalid APEv2 item %q: expected %d bytes of data, got %d", key, size, len(b))
+	}
+	value := b[:size]
+
+	switch (flags >> apeItemTypeShift) & apeItemTypeMask {
+	case apeItemText:
+		// Multiple values are separated by NUL.
+		m.c[strings.ToLower(key)] = strings.Replace(string(value), "\x00", ";", -1)
+
+	case apeItemExternal:
+		// A locator (i.e. URL) for the data, which is stored elsewhere.
+		m.c[strings.ToLower(key)] = trimString(string(value))
+
+	case apeItemBinary:
+		if t, ok := apeCoverArtTypes[strings.ToLower(key)]; ok {
+			p, err := readAPECoverArt(value, t)
+			if err != nil {
+				return nil, fmt.Errorf("could not read %q: %w", key, err)
+			}
+			m.c[strings.ToLower(key)] = p
+			break
+		}
+		m.c[strings.ToLower(key)] = value
+
+	default:
+		// Reserved item type, skipped.
+	}
+	return b[size:], nil
+}
+
+// readAPECoverArt reads the cover art from the value of a binary APEv2 item, which is
+// a NUL-terminated filename followed by the image data.
+func readAPECoverArt(b []byte, pictureType byte) (*Picture, error) {
+	descData := bytes.SplitN(b, singleZero, 2)
+	if len(descData) != 2 {
+		return nil, errors.New("expected NUL-terminated filename")
+	}
+
+	mimeType, ext := sniffImageType(descData[1])
+	return &Picture{
+		Ext:         ext,
+		MIMEType:    mimeType,
+		Type:        pictureTypes[pictureType],
+		Description: string(descData[0]),
+		Data:        descData[1],
+	}, nil
+}
+
+// metadataAPE is the implementation of Metadata used for APEv2 tags.
+type metadataAPE struct {
+	c        map[string]interface{} // the APEv2 items, keyed by lower case item key
+	fileType FileType
+}
+
+func (m *metadataAPE) getString(k string) string {
+	s, _ := m.c[k].(string)
+	return s
+}
+
+func (m *metadataAPE) Format() Format     { return APEv2 }
+func (m *metadataAPE) FileType() FileType { return m.fileType }
+
+func (m *metadataAPE) Raw() map[string]interface{} {
+	raw := make(map[string]interface{}, len(m.c))
+	for k, v := range m.c {
+		raw[k] = v
+	}
+	return raw
+}
+
+func (m *metadataAPE) Title() string    { return m.getString("title") }
+func (m *metadataAPE) Album() string    { return m.getString("album") }
+func (m *metadataAPE) Artist() string   { return m.getString("artist") }
+func (m *metadataAPE) Composer() string { return m.getString("composer") }
+func (m *metadataAPE) Genre() string    { return m.getString("genre") }
+func (m *metadataAPE) Lyrics() string   { return m.getString("lyrics") }
+func (m *metadataAPE) Comment() string  { return m.getString("comment") }
+func (m *metadataAPE) ISRC() string     { return m.getString("isrc") }
+
+func (m *metadataAPE) DiscSubtitle() string { return m.getString("discsubtitle") }
+func (m *metadataAPE) BPM() int             { return parseBPM(m.getString("bpm")) }
+
+func (m *metadataAPE) ReplayGain() ReplayGainInfo { return ReplayGainInfo{} }
+func (m *metadataAPE) Compilation() bool          { return m.getString("compilation") == "1" }
+
+func (m *metadataAPE) AlbumArtist() string {
+	if s := m.getString("album artist"); s != "" {
+		return s
+	}
+	return m.getString("albumartist")
+}
+
+func (m *metadataAPE) Year() int {
+	// Year can be a full date (i.e. 2006-01-02).
+	date := m.getString("year")
+	if len(date) >= 4 {
+		year, _ := strconv.Atoi(date[:4])
+		return year
+	}
+	return 0
+}
+
+func (m *metadataAPE) Track() (int, int) {
+	return parseXofN(m.getString("track"))
+}
+
+func (m *metadataAPE) Disc() (int, int) {
+	return parseXofN(m.getString("disc"))
+}
+
+func (m *metadataAPE) TrackPosition() Position {
+	return parsePosition(m.getString("track"))
+}
+
+func (m *metadataAPE) DiscPosition() Position {
+	return parsePosition(m.getString("disc"))
+}
+
+func (m *metadataAPE) Picture() *Picture {
+	if p, ok := m.c["cover art (front)"].(*Picture); ok {
+		return p
+	}
+	p, _ := m.c["cover art (back)"].(*Picture)
+	return p
+}
+
+// Pictures returns the front and back cover art.
+func (m *metadataAPE) Pictures() []*Picture {
+	var pictures []*Picture
+	for _, k := range []string{"cover art (front)", "cover art (back)"} {
+		if p, ok := m.c[k].(*Picture); ok {
+			pictures = append(pictures, p)
+		}
+	}
+	return pictures
+}
@@ -0,0 +1,92 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReadAPETags(t *testing.T) {
+	m := readTestFile[Metadata](t, "with_tags/sample.ape")
+	if m.Format() != APEv2 {
+		t.Errorf("Format() = %v, expected: %v", m.Format(), APEv2)
+	}
+	compareMetadata(t, m, fullMetadata)
+}
+
+func TestAPECoverArt(t *testing.T) {
+	m := readTestFile[Metadata](t, "with_tags/sample.ape")
+
+	p := m.Picture()
+	if p == nil {
+		t.Fatal("Picture() = nil, expected front cover")
+	}
+	if p.Type != "Cover (front)" {
+		t.Errorf("Picture().Type = %q, expected: %q", p.Type, "Cover (front)")
+	}
+	if p.MIMEType != "image/png" || p.Ext != "png" {
+		t.Errorf("Picture() MIMEType, Ext = %q, %q, expected: %q, %q", p.MIMEType, p.Ext, "image/png", "png")
+	}
+	if p.Description != "cover.png" {
+		t.Errorf("Picture().Description = %q, expected: %q", p.Description, "cover.png")
+	}
+	if !bytes.HasPrefix(p.Data, pngHeader) {
+		t.Errorf("Picture().Data does not start with PNG header: %x", p.Data)
+	}
+}
+
+func TestAPEItemTypes(t *testing.T) {
+	// sample.items.ape has a read-only text item, a text item with a (header only) flag bit
+	// set, a binary item, an external item and a binary cover art item.
+	m := readTestFile[Metadata](t, "with_tags/sample.items.ape")
+
+	if m.Title() != "Test Title" {
+		t.Errorf("Title() = %q, expected: %q", m.Title(), "Test Title")
+	}
+	if m.Artist() != "Test Artist" {
+		t.Errorf("Artist() = %q, expected: %q", m.Artist(), "Test Artist")
+	}
+
+	raw := m.Raw()
+	want := []byte("\x00\x01\x02TEXT\xff")
+	if b, ok := raw["notes"].([]byte); !ok || !bytes.Equal(b, want) {
+		t.Errorf("Raw()[\"notes\"] = %#v, expected: %#v", raw["notes"], want)
+	}
+	if s, ok := raw["related"].(string); !ok || s != "http://example.com/info" {
+		t.Errorf("Raw()[\"related\"] = %#v, expected: %q", raw["related"], "http://example.com/info")
+	}
+	if p, ok := raw["cover art (front)"].(*Picture); !ok || p.MIMEType != "image/png" {
+		t.Errorf("Raw()[\"cover art (front)\"] = %#v, expected PNG *Picture", raw["cover art (front)"])
+	}
+}
+
+func TestAPEFileType(t *testing.T) {
+	tests := []struct {
+		path     string
+		fileType FileType
+	}{
+		{"with_tags/sample.ape", APE},
+		{"with_tags/sample.wv", WV},
+		{"with_tags/sample.mpc", MPC},
+		{"with_tags/sample.apev2.mp3", UnknownFileType},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+		} else if m.Format() != APEv2 || m.FileType() != tt.fileType {
+			t.Errorf("%v: Format(), FileType() = %v, %v, expected: %v, %v", tt.path, m.Format(), m.FileType(), APEv2, tt.fileType)
+		}
+		f.Close()
+	}
+}
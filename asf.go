@@ -0,0 +1,357 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ASF object GUIDs, as they appear in the data.
+var (
+	asfHeaderGUID                       = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	asfContentDescriptionGUID           = []byte{0x33, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	asfExtendedContentDescriptionGUID   = []byte{0x40, 0xA4, 0xD0, 0xD2, 0x07, 0xE3, 0xD2, 0x11, 0x97, 0xF0, 0x00, 0xA0, 0xC9, 0x5E, 0xA8, 0x50}
+	asfContentDescriptionAttributeNames = []string{"Title", "Author", "Copyright", "Description", "Rating"}
+)
+
+// asfObjectHeaderSize is the size of an ASF object header: GUID <16 bytes>, size <64>.
+const asfObjectHeaderSize = 24
+
+// ASF Extended Content Description attribute value types.
+const (
+	asfUnicode   = 0 // UTF-16LE string, NUL-terminated
+	asfByteArray = 1
+	asfBool      = 2 // 32 bit
+	asfDWORD     = 3
+	asfQWORD     = 4
+	asfWORD      = 5
+)
+
+// ReadASFTags reads ASF (WMA) metadata from the io.ReadSeeker, returning the resulting
+// metadata in a Metadata implementation, or non-nil error if there was a problem.  Attributes
+// are read from the Content Description and Extended Content Description objects in the
+// ASF header object.
+// See https://docs.microsoft.com/en-us/windows/win32/wmformat/asf-specification
+func ReadASFTags(r io.ReadSeeker) (Metadata, error) {
+	// Header Object
+	// Object ID         <16 bytes>
+	// Object size       <64>
+	// Number of objects <32>
+	// Reserved          <2 bytes>
+	h, err := readBytes(r, 30)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h[0:16], asfHeaderGUID) {
+		return nil, errors.New("expected ASF header object")
+	}
+
+	size := binary.LittleEndian.Uint64(h[16:24])
+	if size < 30 {
+		return nil, fmt.Errorf("invalid ASF header object size: %d", size)
+	}
+	b, err := readBytes(r, uint(size-30))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &metadataASF{
+		c: make(map[string]interface{}),
+	}
+	for len(b) >= asfObjectHeaderSize {
+		guid := b[0:16]
+		objectSize := binary.LittleEndian.Uint64(b[16:24])
+		if objectSize < asfObjectHeaderSize || objectSize > uint64(len(b)) {
+			return nil, fmt.Errorf("invalid ASF object size: %d", objectSize)
+		}
+		data := b[asfObjectHeaderSize:objectSize]
+		b = b[objectSize:]
+
+		switch {
+		case bytes.Equal(guid, asfContentDescriptionGUID):
+			err = m.readContentDescription(data)
+		case bytes.Equal(guid, asfExtendedContentDescriptionGUID):
+			err = m.readExtendedContentDescription(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Content Description Object
+// Title length       <16>
+// Author length      <16>
+// Copyright length   <16>
+// Description length <16>
+// Rating length      <16>
+// Title, Author, Copyright, Description, Rating <UTF-16LE strings>
+func (m *metadataASF) readContentDescription(b []byte) error {
+	n := len(asfContentDescriptionAttributeNames)
+	if len(b) < 2*n {
+		return errors.New("invalid ASF content description object")
+	}
+	lengths := make([]int, n)
+	for i := range lengths {
+		lengths[i] = int(binary.LittleEndian.Uint16(b[2*i:]))
+	}
+	b = b[2*n:]
+
+	for i, name := range asfContentDescriptionAttributeNames {
+		if len(b) < lengths[i] {
+			return fmt.Errorf("invalid ASF content description %q: expected %d bytes, got %d", name, lengths[i], len(b))
+		}
+		s, err := decodeASFString(b[:lengths[i]])
+		if err != nil {
+			return err
+		}
+		if s != "" {
+			m.c[name] = s
+		}
+		b = b[lengths[i]:]
+	}
+	return nil
+}
+
+// Extended Content Description Object
+// Content descriptors count <16>
+// Content descriptors:
+//
+//	Name length  <16>
+//	Name         <UTF-16LE string>
+//	Value type   <16>
+//	Value length <16>
+//	Value        <value length bytes>
+func (m *metadataASF) readExtendedContentDescription(b []byte) error {
+	if len(b) < 2 {
+		return errors.New("invalid ASF extended content description object")
+	}
+	count := binary.LittleEndian.Uint16(b)
+	b = b[2:]
+
+	for i := uint16(0); i < count; i++ {
+		if len(b) < 2 {
+			return errors.New("invalid ASF content descriptor")
+		}
+		nameLen := int(binary.LittleEndian.Uint16(b))
+		b = b[2:]
+		if len(b) < nameLen+4 {
+			return errors.New("invalid ASF content descriptor name")
+		}
+		name, err := decodeASFString(b[:nameLen])
+		if err != nil {
+			return err
+		}
+		valueType := binary.LittleEndian.Uint16(b[nameLen:])
+		valueLen := int(binary.LittleEndian.Uint16(b[nameLen+2:]))
+		b = b[nameLen+4:]
+		if len(b) < valueLen {
+			return fmt.Errorf("invalid ASF content descriptor %q: expected %d bytes, got %d", name, valueLen, len(b))
+		}
+		value := b[:valueLen]
+		b = b[valueLen:]
+
+		v, err := readASFValue(name, valueType, value)
+		if err != nil {
+			return fmt.Errorf("could not read ASF content descriptor %q: %w", name, err)
+		}
+		if _, ok := v.(*Picture); ok {
+			// There can be more than one picture (i.e. front and back covers), which are
+			// kept as "WM/Picture", "WM/Picture_0", etc.
+			name = numberedName(m.c, name)
+		}
+		if v != nil {
+			m.c[name] = v
+		}
+	}
+	return nil
+}
+
+// readASFValue returns the value of the attribute name from the bytes b, according to the
+// value type (see the asf* constants).  WM/Picture is returned as a *Picture.  Returns nil
+// if the type is not recognised.
+func readASFValue(name string, valueType uint16, b []byte) (interface{}, error) {
+	if valueType == asfByteArray && name == "WM/Picture" {
+		return readASFPicture(b)
+	}
+
+	sizes := map[uint16]int{asfBool: 4, asfDWORD: 4, asfQWORD: 8, asfWORD: 2}
+	if n, ok := sizes[valueType]; ok && len(b) < n {
+		return nil, fmt.Errorf("expected %d bytes, got %d", n, len(b))
+	}
+
+	switch valueType {
+	case asfUnicode:
+		return decodeASFString(b)
+	case asfByteArray:
+		return b, nil
+	case asfBool:
+		return binary.LittleEndian.Uint32(b) != 0, nil
+	case asfDWORD:
+		return binary.LittleEndian.Uint32(b), nil
+	case asfQWORD:
+		return binary.LittleEndian.Uint64(b), nil
+	case asfWORD:
+		return binary.LittleEndian.Uint16(b), nil
+	}
+	return nil, nil
+}
+
+// WM/Picture
+// Picture type <8>
+// Data length  <32>
+// MIME type    <UTF-16LE string> $00 00
+// Description  <UTF-16LE string> $00 00
+// Data         <data length bytes>
+func readASFPicture(b []byte) (*Picture, error) {
+	if len(b) < 5 {
+		return nil, errors.New("invalid WM/Picture")
+	}
+	pictureType, ok := pictureTypes[b[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid picture type: %v", b[0])
+	}
+	dataLen := binary.LittleEndian.Uint32(b[1:5])
+	b = b[5:]
+
+	mimeType, b, err := readASFTerminatedString(b)
+	if err != nil {
+		return nil, err
+	}
+	desc, b, err := readASFTerminatedString(b)
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(b)) < dataLen {
+		return nil, fmt.Errorf("invalid WM/Picture: expected %d bytes of data, got %d", dataLen, len(b))
+	}
+	data := b[:dataLen]
+
+	_, ext := sniffImageType(data)
+	return &Picture{
+		Ext:         ext,
+		MIMEType:    mimeType,
+		Type:        pictureType,
+		Description: desc,
+		Data:        data,
+	}, nil
+}
+
+// readASFTerminatedString reads a NUL-terminated UTF-16LE string from the start of b,
+// returning the string and the remaining bytes.
+func readASFTerminatedString(b []byte) (string, []byte, error) {
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			s, err := decodeUTF16(b[:i], binary.LittleEndian)
+			return s, b[i+2:], err
+		}
+	}
+	return "", nil, errors.New("expected NUL-terminated UTF-16 string")
+}
+
+// decodeASFString decodes the UTF-16LE string b, removing any NUL terminator.
+func decodeASFString(b []byte) (string, error) {
+	s, err := decodeUTF16(b, binary.LittleEndian)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s, "\x00"), nil
+}
+
+// metadataASF is the implementation of Metadata used for ASF attributes.
+type metadataASF struct {
+	c map[string]interface{} // the attributes, keyed by name (i.e. "Title", "WM/AlbumTitle")
+}
+
+func (m *metadataASF) getString(k string) string {
+	switch v := m.c[k].(type) {
+	case string:
+		return v
+	case uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func (m *metadataASF) Format() Format     { return ASF }
+func (m *metadataASF) FileType() FileType { return WMA }
+
+func (m *metadataASF) Raw() map[string]interface{} {
+	raw := make(map[string]interface{}, len(m.c))
+	for k, v := range m.c {
+		raw[k] = v
+	}
+	return raw
+}
+
+func (m *metadataASF) Title() string       { return m.getString("Title") }
+func (m *metadataASF) Album() string       { return m.getString("WM/AlbumTitle") }
+func (m *metadataASF) Artist() string      { return m.getString("Author") }
+func (m *metadataASF) AlbumArtist() string { return m.getString("WM/AlbumArtist") }
+func (m *metadataASF) Composer() string    { return m.getString("WM/Composer") }
+func (m *metadataASF) Genre() string       { return m.getString("WM/Genre") }
+func (m *metadataASF) Lyrics() string      { return m.getString("WM/Lyrics") }
+func (m *metadataASF) Comment() string     { return m.getString("Description") }
+func (m *metadataASF) ISRC() string        { return m.getString("WM/ISRC") }
+
+func (m *metadataASF) DiscSubtitle() string { return m.getString("WM/SetSubTitle") }
+func (m *metadataASF) BPM() int             { return parseBPM(m.getString("WM/BeatsPerMinute")) }
+
+func (m *metadataASF) ReplayGain() ReplayGainInfo {
+	return ReplayGainInfo{
+		TrackGain: parseReplayGain(m.getString("replaygain_track_gain")),
+		TrackPeak: parseReplayGain(m.getString("replaygain_track_peak")),
+		AlbumGain: parseReplayGain(m.getString("replaygain_album_gain")),
+		AlbumPeak: parseReplayGain(m.getString("replaygain_album_peak")),
+	}
+}
+
+func (m *metadataASF) Compilation() bool {
+	v, _ := m.c["WM/IsCompilation"].(bool)
+	return v
+}
+
+func (m *metadataASF) Year() int {
+	// Year can be a full date (i.e. 2006-01-02).
+	date := m.getString("WM/Year")
+	if len(date) >= 4 {
+		year, _ := strconv.Atoi(date[:4])
+		return year
+	}
+	return 0
+}
+
+func (m *metadataASF) Track() (int, int) {
+	return parseXofN(m.getString("WM/TrackNumber"))
+}
+
+func (m *metadataASF) Disc() (int, int) {
+	return parseXofN(m.getString("WM/PartOfSet"))
+}
+
+func (m *metadataASF) TrackPosition() Position {
+	return parsePosition(m.getString("WM/TrackNumber"))
+}
+
+func (m *metadataASF) DiscPosition() Position {
+	return parsePosition(m.getString("WM/PartOfSet"))
+}
+
+func (m *metadataASF) Picture() *Picture {
+	p, _ := m.c["WM/Picture"].(*Picture)
+	return p
+}
+
+// Pictures returns the pictures from each of the WM/Picture attributes.
+func (m *metadataASF) Pictures() []*Picture {
+	return numberedPictures(m.c, "WM/Picture")
+}
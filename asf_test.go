@@ -0,0 +1,51 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReadASFTags(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.wma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	format, fileType, err := Identify(f)
+	if err != nil || format != ASF || fileType != WMA {
+		t.Errorf("Identify() = %v, %v, %v, expected: %v, %v, <nil>", format, fileType, err, ASF, WMA)
+	}
+
+	m, err := ReadASFTags(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareMetadata(t, m, asfMetadata)
+
+	if !m.(CompilationMetadata).Compilation() {
+		t.Errorf("Compilation() = false, expected: true")
+	}
+	if got := m.(ReplayGainMetadata).ReplayGain().TrackGain; got != -6.54 {
+		t.Errorf("ReplayGain().TrackGain = %v, expected: %v", got, -6.54)
+	}
+	if got := m.Raw()["Copyright"]; got != "Test Copyright" {
+		t.Errorf("Raw()[\"Copyright\"] = %#v, expected: %q", got, "Test Copyright")
+	}
+
+	p := m.Picture()
+	if p == nil {
+		t.Fatal("Picture() = nil, expected front cover")
+	}
+	if p.Type != "Cover (front)" || p.MIMEType != "image/png" || p.Ext != "png" || p.Description != "cover" {
+		t.Errorf("Picture() = %q, %q, %q, %q, expected: %q, %q, %q, %q", p.Type, p.MIMEType, p.Ext, p.Description, "Cover (front)", "image/png", "png", "cover")
+	}
+	if !bytes.HasPrefix(p.Data, pngHeader) {
+		t.Errorf("Picture().Data does not start with PNG header: %x", p.Data)
+	}
+}
@@ -31,9 +31,10 @@ func decodeLocation(l string) (string, error) {
 }
 
 var (
-	itlXML = flag.String("itlXML", "", "iTunes Library Path")
-	path   = flag.String("path", "", "path to directory containing audio files")
-	sum    = flag.Bool("sum", false, "compute the checksum of the audio file (doesn't work for .flac or .ogg yet)")
+	itlXML   = flag.String("itlXML", "", "iTunes Library Path")
+	path     = flag.String("path", "", "path to directory containing audio files")
+	sum      = flag.Bool("sum", false, "compute the checksum of the audio file")
+	validate = flag.Bool("validate", false, "report problems with the tags")
 )
 
 func main() {
@@ -175,6 +176,22 @@ func (p *processor) do(ch <-chan string) {
 				p.decodingErrors[err.Error()]++
 			}
 
+			if *validate {
+				_, err = tf.Seek(0, io.SeekStart)
+				if err != nil {
+					fmt.Println("DIED:", path, "error seeking back to 0:", err)
+					return
+				}
+
+				issues, err := tag.Validate(tf)
+				if err != nil {
+					fmt.Println("VALIDATE:", path, err.Error())
+				}
+				for _, i := range issues {
+					fmt.Println("VALIDATE:", path, i)
+				}
+			}
+
 			if *sum {
 				_, err = tf.Seek(0, io.SeekStart)
 				if err != nil {
@@ -36,14 +36,7 @@ func main() {
 		return
 	}
 
-	f, err := os.Open(flag.Arg(0))
-	if err != nil {
-		fmt.Printf("error loading file: %v", err)
-		return
-	}
-	defer f.Close()
-
-	m, err := tag.ReadFrom(f)
+	m, err := tag.ReadFromFile(flag.Arg(0))
 	if err != nil {
 		fmt.Printf("error reading file: %v\n", err)
 		return
@@ -87,13 +80,25 @@ func printMetadata(m tag.Metadata) {
 	fmt.Printf(" Genre: %v\n", m.Genre())
 	fmt.Printf(" Year: %v\n", m.Year())
 
-	track, trackCount := m.Track()
-	fmt.Printf(" Track: %v of %v\n", track, trackCount)
-
-	disc, discCount := m.Disc()
-	fmt.Printf(" Disc: %v of %v\n", disc, discCount)
+	if p, ok := m.(tag.PositionMetadata); ok {
+		fmt.Printf(" Track: %v\n", formatPosition(p.TrackPosition()))
+		fmt.Printf(" Disc: %v\n", formatPosition(p.DiscPosition()))
+	} else {
+		x, n := m.Track()
+		fmt.Printf(" Track: %v of %v\n", x, n)
+		x, n = m.Disc()
+		fmt.Printf(" Disc: %v of %v\n", x, n)
+	}
 
 	fmt.Printf(" Picture: %v\n", m.Picture())
 	fmt.Printf(" Lyrics: %v\n", m.Lyrics())
 	fmt.Printf(" Comment: %v\n", m.Comment())
 }
+
+// formatPosition returns the position as "x of n", or "x" if the total is not given.
+func formatPosition(p tag.Position) string {
+	if !p.HasTotal {
+		return fmt.Sprint(p.Number)
+	}
+	return fmt.Sprintf("%v of %v", p.Number, p.Total)
+}
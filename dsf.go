@@ -13,6 +13,11 @@ import (
 // metadata in a Metadata implementation, or non-nil error if there was a problem.
 // samples: http://www.2l.no/hires/index.html
 func ReadDSFTags(r io.ReadSeeker) (Metadata, error) {
+	return readDSFTags(r, Options{})
+}
+
+// readDSFTags implements ReadDSFTags, with the options opts (for the ID3v2 tag).
+func readDSFTags(r io.ReadSeeker, opts Options) (Metadata, error) {
 	dsd, err := readString(r, 4)
 	if err != nil {
 		return nil, err
@@ -36,7 +41,7 @@ func ReadDSFTags(r io.ReadSeeker) (Metadata, error) {
 		return nil, err
 	}
 
-	id3, err := ReadID3v2Tags(r)
+	id3, err := readID3v2Tags(r, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -92,10 +97,35 @@ func (m metadataDSF) Disc() (int, int) {
 	return m.id3.Disc()
 }
 
+func (m metadataDSF) TrackPosition() Position {
+	if p, ok := m.id3.(PositionMetadata); ok {
+		return p.TrackPosition()
+	}
+	return Position{}
+}
+
+func (m metadataDSF) DiscPosition() Position {
+	if p, ok := m.id3.(PositionMetadata); ok {
+		return p.DiscPosition()
+	}
+	return Position{}
+}
+
+func (m metadataDSF) DiscSubtitle() string {
+	return discSubtitle(m.id3)
+}
+
 func (m metadataDSF) Picture() *Picture {
 	return m.id3.Picture()
 }
 
+func (m metadataDSF) Pictures() []*Picture {
+	if p, ok := m.id3.(PicturesMetadata); ok {
+		return p.Pictures()
+	}
+	return nil
+}
+
 func (m metadataDSF) Lyrics() string {
 	return m.id3.Lyrics()
 }
@@ -104,6 +134,28 @@ func (m metadataDSF) Comment() string {
 	return m.id3.Comment()
 }
 
+func (m metadataDSF) ISRC() string {
+	return isrc(m.id3)
+}
+
+func (m metadataDSF) ReplayGain() ReplayGainInfo {
+	if r, ok := m.id3.(ReplayGainMetadata); ok {
+		return r.ReplayGain()
+	}
+	return ReplayGainInfo{}
+}
+
+func (m metadataDSF) BPM() int {
+	if b, ok := m.id3.(BPMMetadata); ok {
+		return b.BPM()
+	}
+	return 0
+}
+
+func (m metadataDSF) Compilation() bool {
+	return compilation(m.id3)
+}
+
 func (m metadataDSF) Raw() map[string]interface{} {
 	return m.id3.Raw()
 }
@@ -6,6 +6,7 @@ package tag
 
 import (
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -14,11 +15,11 @@ type blockType byte
 
 // FLAC block types.
 const (
-	// Stream Info Block           0
 	// Padding Block               1
-	// Application Block           2
 	// Seektable Block             3
 	// Cue Sheet Block             5
+	streamInfoBlock    blockType = 0
+	applicationBlock   blockType = 2
 	vorbisCommentBlock blockType = 4
 	pictureBlock       blockType = 6
 )
@@ -26,6 +27,11 @@ const (
 // ReadFLACTags reads FLAC metadata from the io.ReadSeeker, returning the resulting
 // metadata in a Metadata implementation, or non-nil error if there was a problem.
 func ReadFLACTags(r io.ReadSeeker) (Metadata, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
 	flac, err := readString(r, 4)
 	if err != nil {
 		return nil, err
@@ -34,8 +40,10 @@ func ReadFLACTags(r io.ReadSeeker) (Metadata, error) {
 		return nil, errors.New("expected 'fLaC'")
 	}
 
-	m := &metadataFLAC{
-		newMetadataVorbis(),
+	m := &MetadataFLAC{
+		metadataVorbis: newMetadataVorbis(),
+		src:            r,
+		start:          start,
 	}
 
 	for {
@@ -51,11 +59,37 @@ func ReadFLACTags(r io.ReadSeeker) (Metadata, error) {
 	return m, nil
 }
 
-type metadataFLAC struct {
+// MetadataFLAC is the implementation of Metadata for FLAC files.  The Metadata returned
+// by ReadFLACTags can be asserted to *MetadataFLAC to access FLAC-specific metadata.
+type MetadataFLAC struct {
 	*metadataVorbis
+
+	streamInfo   FLACStreamInfo
+	applications []FLACApplication
+
+	src   io.ReadSeeker // the file the metadata was read from (nil for Ogg FLAC)
+	start int64         // offset of the file in src
+}
+
+// FLACApplication is a type which contains the data from a FLAC APPLICATION block.
+type FLACApplication struct {
+	ID   string // Registered application ID (4 bytes).
+	Data []byte // Application data.
 }
 
-func (m *metadataFLAC) readFLACMetadataBlock(r io.ReadSeeker) (last bool, err error) {
+// FLACStreamInfo is a type which contains the information from the FLAC STREAMINFO block.
+type FLACStreamInfo struct {
+	MinBlockSize  int    // Minimum block size (in samples) used in the stream.
+	MaxBlockSize  int    // Maximum block size (in samples) used in the stream.
+	MinFrameSize  int    // Minimum frame size (in bytes) used in the stream, 0 if unknown.
+	MaxFrameSize  int    // Maximum frame size (in bytes) used in the stream, 0 if unknown.
+	SampleRate    int    // Sample rate in Hz.
+	Channels      int    // Number of channels.
+	BitsPerSample int    // Bits per sample.
+	TotalSamples  uint64 // Total samples in the stream, 0 if unknown.
+}
+
+func (m *MetadataFLAC) readFLACMetadataBlock(r io.ReadSeeker) (last bool, err error) {
 	blockHeader, err := readBytes(r, 1)
 	if err != nil {
 		return
@@ -72,6 +106,12 @@ func (m *metadataFLAC) readFLACMetadataBlock(r io.ReadSeeker) (last bool, err er
 	}
 
 	switch blockType(blockHeader[0]) {
+	case streamInfoBlock:
+		err = m.readStreamInfo(r, uint(blockLen))
+
+	case applicationBlock:
+		err = m.readApplication(r, uint(blockLen))
+
 	case vorbisCommentBlock:
 		err = m.readVorbisComment(r)
 
@@ -84,6 +124,77 @@ func (m *metadataFLAC) readFLACMetadataBlock(r io.ReadSeeker) (last bool, err er
 	return
 }
 
-func (m *metadataFLAC) FileType() FileType {
+// STREAMINFO
+// Min block size    <16>
+// Max block size    <16>
+// Min frame size    <24>
+// Max frame size    <24>
+// Sample rate       <20>
+// Channels - 1      <3>
+// Bits/sample - 1   <5>
+// Total samples     <36>
+// MD5 signature     <128>
+func (m *MetadataFLAC) readStreamInfo(r io.Reader, n uint) error {
+	if n < 18 {
+		return fmt.Errorf("invalid STREAMINFO block: expected at least %d bytes, got %d", 18, n)
+	}
+
+	b, err := readBytes(r, n)
+	if err != nil {
+		return err
+	}
+
+	x := uint64(getInt(b[10:18]))
+	m.streamInfo = FLACStreamInfo{
+		MinBlockSize:  getInt(b[0:2]),
+		MaxBlockSize:  getInt(b[2:4]),
+		MinFrameSize:  getInt(b[4:7]),
+		MaxFrameSize:  getInt(b[7:10]),
+		SampleRate:    int(x >> 44),
+		Channels:      int((x>>41)&0x7) + 1,
+		BitsPerSample: int((x>>36)&0x1f) + 1,
+		TotalSamples:  x & (1<<36 - 1),
+	}
+	return nil
+}
+
+// APPLICATION
+// Application ID    <32>
+// Data              <n-32>
+func (m *MetadataFLAC) readApplication(r io.Reader, n uint) error {
+	if n < 4 {
+		return fmt.Errorf("invalid APPLICATION block: expected at least %d bytes, got %d", 4, n)
+	}
+
+	b, err := readBytes(r, n)
+	if err != nil {
+		return err
+	}
+
+	m.applications = append(m.applications, FLACApplication{
+		ID:   string(b[:4]),
+		Data: b[4:],
+	})
+	return nil
+}
+
+// Applications returns the APPLICATION blocks in the order they appear, or nil if there
+// are none.
+func (m *MetadataFLAC) Applications() []FLACApplication {
+	return m.applications
+}
+
+// StreamInfo returns the information from the FLAC STREAMINFO block.
+func (m *MetadataFLAC) StreamInfo() FLACStreamInfo {
+	return m.streamInfo
+}
+
+// PictureSummary returns the type, MIME type and size of each PICTURE block in the
+// order they appear, or nil if there are none.
+func (m *MetadataFLAC) PictureSummary() []PictureInfo {
+	return summarizePictures(m.Pictures())
+}
+
+func (m *MetadataFLAC) FileType() FileType {
 	return FLAC
 }
@@ -0,0 +1,132 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFLACStreamInfo(t *testing.T) {
+	want := FLACStreamInfo{
+		MinBlockSize:  1152,
+		MaxBlockSize:  1152,
+		MinFrameSize:  659,
+		MaxFrameSize:  2069,
+		SampleRate:    11025,
+		Channels:      1,
+		BitsPerSample: 16,
+		TotalSamples:  37478,
+	}
+
+	for _, path := range []string{"with_tags/sample.flac", "without_tags/sample.flac"} {
+		m := readTestFile[*MetadataFLAC](t, path)
+		if got := m.StreamInfo(); got != want {
+			t.Errorf("%v: StreamInfo() = %#v, expected: %#v", path, got, want)
+		}
+	}
+}
+
+func TestFLACApplications(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Insert an APPLICATION block (type 2, not the last block) after STREAMINFO, with the
+	// registered ID "xmcd".
+	app := append([]byte{0x02, 0x00, 0x00, 0x08}, "xmcd\x01\x02\x03\x04"...)
+	b = append(append(append([]byte{}, b[:42]...), app...), b[42:]...)
+
+	m, err := ReadFLACTags(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FLACApplication{{ID: "xmcd", Data: []byte{1, 2, 3, 4}}}
+	if got := m.(*MetadataFLAC).Applications(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Applications() = %v, expected: %v", got, want)
+	}
+	compareMetadata(t, m, fullMetadata)
+
+	if got := readTestFile[*MetadataFLAC](t, "with_tags/sample.flac").Applications(); got != nil {
+		t.Errorf("Applications() = %v, expected: nil", got)
+	}
+}
+
+func TestFLACPictureBlock(t *testing.T) {
+	// picture type <32>, MIME type length <32>, MIME type, description length <32>,
+	// description, width <32>, height <32>, color depth <32>, colors used <32>,
+	// data length <32>, data
+	var b []byte
+	for _, x := range []interface{}{
+		uint32(4), uint32(10), "image/jpeg", uint32(4), "Back",
+		uint32(640), uint32(480), uint32(24), uint32(0), uint32(3), "\xff\xd8\xff",
+	} {
+		switch x := x.(type) {
+		case uint32:
+			b = binary.BigEndian.AppendUint32(b, x)
+		case string:
+			b = append(b, x...)
+		}
+	}
+
+	m := newMetadataVorbis()
+	if err := m.readPictureBlock(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	want := &Picture{
+		Ext:         "jpg",
+		MIMEType:    "image/jpeg",
+		Type:        "Cover (back)",
+		Description: "Back",
+		Data:        []byte("\xff\xd8\xff"),
+		Width:       640,
+		Height:      480,
+		ColorDepth:  24,
+	}
+	if got := m.Picture(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Picture() = %#v, expected: %#v", got, want)
+	}
+
+	// sample.pictures.flac has three 1x1 24-bit PNG pictures.
+	for _, p := range readTestFile[*MetadataFLAC](t, "with_tags/sample.pictures.flac").Pictures() {
+		if p.Width != 1 || p.Height != 1 || p.ColorDepth != 24 {
+			t.Errorf("%v: Width, Height, ColorDepth = %d, %d, %d, expected: 1, 1, 24", p.Type, p.Width, p.Height, p.ColorDepth)
+		}
+	}
+}
+
+func TestFLACEmptyVorbisComment(t *testing.T) {
+	for _, path := range []string{
+		"without_tags/sample.flac",              // followed by a PADDING block
+		"without_tags/sample.emptycomment.flac", // the last metadata block
+	} {
+		m := readTestFile[*MetadataFLAC](t, path)
+		compareMetadata(t, m, emptyMetadata)
+		for name, f := range map[string]func() string{
+			"ISRC":         m.ISRC,
+			"DiscSubtitle": m.DiscSubtitle,
+		} {
+			if got := f(); got != "" {
+				t.Errorf("%v: %v() = %q, expected: \"\"", path, name, got)
+			}
+		}
+		if p := m.Pictures(); p != nil {
+			t.Errorf("%v: Pictures() = %v, expected: nil", path, p)
+		}
+		if len(m.Raw()) != 1 || m.Raw()["vendor"] == "" {
+			t.Errorf("%v: Raw() = %v, expected only the vendor", path, m.Raw())
+		}
+	}
+
+	// vendor length <32> (0), comment count <32> (0)
+	m := newMetadataVorbis()
+	err := m.readVorbisComment(bytes.NewReader(make([]byte, 8)))
+	if err != nil {
+		t.Errorf("readVorbisComment() with empty vendor and no comments returned unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Gapless is a type which contains the iTunes gapless playback values (stored by iTunes
+// as "iTunSMPB").
+type Gapless struct {
+	// EncoderDelay is the number of priming samples added by the encoder at the start.
+	EncoderDelay uint32
+
+	// Padding is the number of samples added by the encoder at the end.
+	Padding uint32
+
+	// SampleCount is the number of samples in the original audio (excluding the encoder
+	// delay and padding).
+	SampleCount uint64
+}
+
+// ParseGapless parses the iTunSMPB string s, which contains space-separated hexadecimal
+// values: a reserved value, the encoder delay, the padding and the original sample count,
+// followed by values which are not used.
+func ParseGapless(s string) (Gapless, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return Gapless{}, fmt.Errorf("invalid iTunSMPB: expected at least 4 values, got %d", len(fields))
+	}
+
+	var v [3]uint64
+	for i, f := range fields[1:4] {
+		bits := 32
+		if i == 2 {
+			bits = 64
+		}
+		n, err := strconv.ParseUint(f, 16, bits)
+		if err != nil {
+			return Gapless{}, fmt.Errorf("invalid iTunSMPB value %q: %w", f, err)
+		}
+		v[i] = n
+	}
+	return Gapless{
+		EncoderDelay: uint32(v[0]),
+		Padding:      uint32(v[1]),
+		SampleCount:  v[2],
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseGapless(t *testing.T) {
+	g, err := ParseGapless(" 00000000 00000210 000003C0 0000000000A9B330 00000000 00000000 00000000 00000000 00000000 00000000 00000000 00000000")
+	if err != nil {
+		t.Fatalf("ParseGapless() returned unexpected error: %v", err)
+	}
+	want := Gapless{EncoderDelay: 0x210, Padding: 0x3C0, SampleCount: 0xA9B330}
+	if g != want {
+		t.Errorf("ParseGapless() = %+v, expected: %+v", g, want)
+	}
+
+	for _, s := range []string{"", "00000000 00000210 000003C0", "00000000 00000210 0000X3C0 0000000000A9B330"} {
+		if _, err := ParseGapless(s); err == nil {
+			t.Errorf("ParseGapless(%q) expected error", s)
+		}
+	}
+}
+
+func TestID3v2Gapless(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.itunsmpb.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The iTunSMPB and iTunNORM COMM frames precede the comment, and must not be returned
+	// by Comment().
+	compareMetadata(t, m, fullMetadata)
+
+	g := m.(MetadataID3v2).Gapless()
+	if g == nil {
+		t.Fatal("Gapless() = nil, expected iTunSMPB values")
+	}
+	if g.EncoderDelay != 0x210 || g.Padding != 0x3C0 || g.SampleCount != 0xA9B330 {
+		t.Errorf("Gapless() = %+v, expected: 528, 960, 11121456", *g)
+	}
+	if sc := m.(MetadataID3v2).SoundCheck(); sc == nil || sc.Values[1] != 0xB2C {
+		t.Errorf("SoundCheck() = %v, expected iTunNORM values", sc)
+	}
+
+	// Files without iTunSMPB.
+	f, err = os.Open("testdata/with_tags/sample.itunnorm.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err = ReadFrom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := m.(MetadataID3v2).Gapless(); g != nil {
+		t.Errorf("Gapless() = %+v, expected: nil", *g)
+	}
+}
+
+func TestID3v2GaplessOrder(t *testing.T) {
+	// With more than one iTunSMPB COMM frame, the first in the tag is used.
+	m := MetadataID3v2{
+		header: &id3v2Header{Version: ID3v2_4},
+		frames: map[string]interface{}{
+			"COMM":   &Comm{Description: "iTunSMPB", Text: " 00000000 00000210 000003E0 0000000000AC4A10"},
+			"COMM_0": &Comm{Description: "iTunSMPB", Text: " 00000000 00000000 00000000 0000000000000000"},
+		},
+	}
+	for i := 0; i < 100; i++ {
+		if g := m.Gapless(); g == nil || g.EncoderDelay != 0x210 {
+			t.Fatalf("Gapless() = %+v, expected EncoderDelay: %d", g, 0x210)
+		}
+	}
+}
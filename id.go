@@ -1,20 +1,46 @@
 package tag
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 )
 
-// Identify identifies the format and file type of the data in the ReadSeeker.
+// Identify identifies the format and file type of the data in the ReadSeeker, without
+// parsing the tags.  The leading bytes are checked in the same way as ReadFrom (falling
+// back to an ID3v1 or APEv2 tag at the end of the data), and the result is the Format and
+// FileType of the Metadata that ReadFrom would return, except that:
+//   - MP4: the FileType is from the ftyp brand only (UnknownFileType if the brand is not
+//     recognised, and M4A for Apple Lossless files), as the tracks are not read,
+//   - WAV and AIFF: the Format is UnknownFormat, as it depends on which chunks are present.
+//
+// r is returned to its original position.  Returns ErrNoTagsFound if the format could not
+// be identified.
 func Identify(r io.ReadSeeker) (format Format, fileType FileType, err error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+
+	format, fileType, err = identify(r, start)
+
+	_, seekErr := r.Seek(start, io.SeekStart)
+	if err == nil && seekErr != nil {
+		err = fmt.Errorf("could not seek back to original position: %w", seekErr)
+	}
+	return
+}
+
+// identify implements Identify, where start is the original position of r.  r can be left
+// at any position.
+func identify(r io.ReadSeeker, start int64) (format Format, fileType FileType, err error) {
 	b, err := readBytes(r, 11)
 	if err != nil {
 		return
 	}
 
-	_, err = r.Seek(-11, io.SeekCurrent)
+	_, err = r.Seek(start, io.SeekStart)
 	if err != nil {
-		err = fmt.Errorf("could not seek back to original position: %v", err)
 		return
 	}
 
@@ -23,59 +49,88 @@ func Identify(r io.ReadSeeker) (format Format, fileType FileType, err error) {
 		return VORBIS, FLAC, nil
 
 	case string(b[0:4]) == "OggS":
-		return VORBIS, OGG, nil
+		fileType, err = identifyOGG(r)
+		return VORBIS, fileType, err
 
 	case string(b[4:8]) == "ftyp":
-		b = b[8:11]
-		fileType = UnknownFileType
-		switch string(b) {
-		case "M4A":
-			fileType = M4A
+		return MP4, mp4BrandFileType(b[8:11]), nil
 
-		case "M4B":
-			fileType = M4B
+	case string(b[0:3]) == "ID3":
+		format, err = id3v2Version(b[3])
+		return format, MP3, err
 
-		case "M4P":
-			fileType = M4P
-		}
-		return MP4, fileType, nil
+	case string(b[0:4]) == "DSD ":
+		_, _, format, err = regionDSF(r)
+		return format, DSF, err
 
-	case string(b[0:3]) == "ID3":
-		b := b[3:]
-		switch uint(b[0]) {
-		case 2:
-			format = ID3v2_2
-		case 3:
-			format = ID3v2_3
-		case 4:
-			format = ID3v2_4
-		case 0, 1:
-			fallthrough
-		default:
-			err = fmt.Errorf("ID3 version: %v, expected: 2, 3 or 4", uint(b[0]))
+	case string(b[0:4]) == "FORM" && string(b[8:11]) == "AIF":
+		// The form type is "AIFF" or "AIFC".
+		b, err = readBytes(r, 12)
+		if err != nil {
 			return
 		}
-		return format, MP3, nil
+		if b[11] == 'C' {
+			return UnknownFormat, AIFC, nil
+		}
+		return UnknownFormat, AIFF, nil
+
+	case (string(b[0:4]) == "RIFF" || string(b[0:4]) == "RIFX") && string(b[8:11]) == "WAV":
+		return UnknownFormat, WAV, nil
+
+	case apeFileType(b) != UnknownFileType:
+		return APEv2, apeFileType(b), nil
+
+	case bytes.Equal(b, asfHeaderGUID[:11]):
+		return ASF, WMA, nil
 	}
 
-	n, err := r.Seek(-128, io.SeekEnd)
+	// Some files have a UTF-8 BOM or other junk before the ID3v2 header or the start of
+	// the file.
+	magic, err := seekHeader(r, defaultSearchLimit, "ID3", "fLaC", "OggS", "ftyp")
 	if err != nil {
 		return
 	}
+	switch magic {
+	case "fLaC", "OggS", "ftyp":
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return UnknownFormat, UnknownFileType, err
+		}
+		return identify(r, pos)
 
-	tag, err := readString(r, 3)
-	if err != nil {
-		return
+	case "ID3":
+		b, err = readBytes(r, 4)
+		if err != nil {
+			return
+		}
+		format, err = id3v2Version(b[3])
+		return format, MP3, err
 	}
 
-	_, err = r.Seek(-n, io.SeekCurrent)
-	if err != nil {
-		return
+	// The leading bytes are not recognised, so the only remaining possibilities are an
+	// ID3v1 or APEv2 tag at the end of the data.
+	_, err = r.Seek(-128, io.SeekEnd)
+	if err == nil {
+		b, err = readBytes(r, 3)
+		if err == nil && string(b) == "TAG" {
+			return ID3v1, MP3, nil
+		}
 	}
+	if _, err := findAPEFooter(r); err == nil {
+		return APEv2, UnknownFileType, nil
+	}
+	return UnknownFormat, UnknownFileType, ErrNoTagsFound
+}
 
-	if tag != "TAG" {
-		err = ErrNoTagsFound
-		return
+// id3v2Version returns the Format for the ID3v2 major version v.
+func id3v2Version(v byte) (Format, error) {
+	switch v {
+	case 2:
+		return ID3v2_2, nil
+	case 3:
+		return ID3v2_3, nil
+	case 4:
+		return ID3v2_4, nil
 	}
-	return ID3v1, MP3, nil
+	return UnknownFormat, fmt.Errorf("ID3 version: %v, expected: 2, 3 or 4", v)
 }
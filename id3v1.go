@@ -5,7 +5,6 @@
 package tag
 
 import (
-	"errors"
 	"io"
 	"strconv"
 	"strings"
@@ -37,13 +36,30 @@ var id3v1Genres = [...]string{
 	"Duet", "Punk Rock", "Drum Solo", "Acapella", "Euro-House", "Dance Hall",
 }
 
-// ErrNotID3v1 is an error which is returned when no ID3v1 header is found.
-var ErrNotID3v1 = errors.New("invalid ID3v1 header")
+// ErrNotID3v1 is an error which is returned by ReadID3v1Tags when no ID3v1 header is
+// found.  As this means there are no tags, errors.Is(ErrNotID3v1, ErrNoTagsFound) is true.
+var ErrNotID3v1 error = notID3v1Error{}
+
+type notID3v1Error struct{}
+
+func (notID3v1Error) Error() string { return "invalid ID3v1 header" }
+
+// Is reports whether target is ErrNoTagsFound, so that callers only interested in whether
+// any tags were found do not need to check for ErrNotID3v1 separately.
+func (notID3v1Error) Is(target error) bool { return target == ErrNoTagsFound }
 
 // ReadID3v1Tags reads ID3v1 tags from the io.ReadSeeker.  Returns ErrNotID3v1
 // if there are no ID3v1 tags, otherwise non-nil error if there was a problem.
 func ReadID3v1Tags(r io.ReadSeeker) (Metadata, error) {
-	_, err := r.Seek(-128, io.SeekEnd)
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if size < 128 {
+		return nil, ErrNotID3v1
+	}
+
+	_, err = r.Seek(-128, io.SeekEnd)
 	if err != nil {
 		return nil, err
 	}
@@ -136,9 +152,19 @@ func (m metadataID3v1) Year() int {
 
 func (m metadataID3v1) Track() (int, int) { return m["track"].(int), 0 }
 
+func (m metadataID3v1) TrackPosition() Position { return Position{Number: m["track"].(int)} }
+
 func (m metadataID3v1) AlbumArtist() string { return "" }
 func (m metadataID3v1) Composer() string    { return "" }
 func (metadataID3v1) Disc() (int, int)      { return 0, 0 }
+func (metadataID3v1) DiscSubtitle() string  { return "" }
 func (m metadataID3v1) Picture() *Picture   { return nil }
+func (metadataID3v1) Pictures() []*Picture  { return nil }
 func (m metadataID3v1) Lyrics() string      { return "" }
 func (m metadataID3v1) Comment() string     { return m["comment"].(string) }
+func (metadataID3v1) ISRC() string          { return "" }
+
+func (metadataID3v1) ReplayGain() ReplayGainInfo { return ReplayGainInfo{} }
+func (metadataID3v1) Compilation() bool          { return false }
+func (metadataID3v1) BPM() int                   { return 0 }
+func (metadataID3v1) DiscPosition() Position     { return Position{} }
@@ -5,10 +5,10 @@
 package tag
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"regexp"
 	"strconv"
 	"strings"
 )
@@ -60,20 +60,39 @@ type id3v2Header struct {
 	Unsynchronisation bool
 	ExtendedHeader    bool
 	Experimental      bool
+	Footer            bool // ID3v2.4 only, a copy of the header follows the tag.
 	Size              uint
+
+	// PlainFrameSizes is set for ID3v2.4 tags which were written with (ID3v2.3 style)
+	// plain 32-bit frame sizes rather than synchsafe integers.
+	PlainFrameSizes bool
 }
 
+// tagSize returns the size of the whole tag: the header, the tag data (Size) and the
+// footer, if any.
+func (h *id3v2Header) tagSize() int64 {
+	size := int64(h.Size) + 10
+	if h.Footer {
+		size += 10
+	}
+	return size
+}
+
+// ErrInvalidID3v2Header is the error returned (wrapped) when the data does not start with
+// a valid ID3v2 header, i.e. when ReadID3v2Tags is used on data without an ID3v2 tag.
+var ErrInvalidID3v2Header = errors.New("invalid ID3v2 header")
+
 // readID3v2Header reads the ID3v2 header from the given io.Reader.
 // offset it number of bytes of header that was read
 func readID3v2Header(r io.Reader) (h *id3v2Header, offset uint, err error) {
 	offset = 10
 	b, err := readBytes(r, offset)
 	if err != nil {
-		return nil, 0, fmt.Errorf("expected to read 10 bytes (ID3v2Header): %v", err)
+		return nil, 0, fmt.Errorf("expected to read 10 bytes (ID3v2Header): %w", err)
 	}
 
 	if string(b[0:3]) != "ID3" {
-		return nil, 0, fmt.Errorf("expected to read \"ID3\"")
+		return nil, 0, fmt.Errorf("%w: expected to read \"ID3\"", ErrInvalidID3v2Header)
 	}
 
 	b = b[3:]
@@ -88,7 +107,7 @@ func readID3v2Header(r io.Reader) (h *id3v2Header, offset uint, err error) {
 	case 0, 1:
 		fallthrough
 	default:
-		return nil, 0, fmt.Errorf("ID3 version: %v, expected: 2, 3 or 4", uint(b[0]))
+		return nil, 0, fmt.Errorf("%w: ID3 version: %v, expected: 2, 3 or 4", ErrInvalidID3v2Header, uint(b[0]))
 	}
 
 	// NB: We ignore b[1] (the revision) as we don't currently rely on it.
@@ -97,6 +116,7 @@ func readID3v2Header(r io.Reader) (h *id3v2Header, offset uint, err error) {
 		Unsynchronisation: getBit(b[2], 7),
 		ExtendedHeader:    getBit(b[2], 6),
 		Experimental:      getBit(b[2], 5),
+		Footer:            vers == ID3v2_4 && getBit(b[2], 4),
 		Size:              uint(get7BitChunkedInt(b[3:7])),
 	}
 
@@ -105,27 +125,31 @@ func readID3v2Header(r io.Reader) (h *id3v2Header, offset uint, err error) {
 		case ID3v2_3:
 			b, err := readBytes(r, 4)
 			if err != nil {
-				return nil, 0, fmt.Errorf("expected to read 4 bytes (ID3v23 extended header len): %v", err)
+				return nil, 0, fmt.Errorf("expected to read 4 bytes (ID3v23 extended header len): %w", err)
 			}
 			// skip header, size is excluding len bytes
 			extendedHeaderSize := uint(getInt(b))
 			_, err = readBytes(r, extendedHeaderSize)
 			if err != nil {
-				return nil, 0, fmt.Errorf("expected to read %d bytes (ID3v23 skip extended header): %v", extendedHeaderSize, err)
+				return nil, 0, fmt.Errorf("expected to read %d bytes (ID3v23 skip extended header): %w", extendedHeaderSize, err)
 			}
-			offset += extendedHeaderSize
+			offset += 4 + extendedHeaderSize
 		case ID3v2_4:
 			b, err := readBytes(r, 4)
 			if err != nil {
-				return nil, 0, fmt.Errorf("expected to read 4 bytes (ID3v24 extended header len): %v", err)
+				return nil, 0, fmt.Errorf("expected to read 4 bytes (ID3v24 extended header len): %w", err)
 			}
 			// skip header, size is synchsafe int including len bytes
-			extendedHeaderSize := uint(get7BitChunkedInt(b)) - 4
+			extendedHeaderSize := uint(get7BitChunkedInt(b))
+			if extendedHeaderSize < 4 {
+				return nil, 0, fmt.Errorf("%w: ID3v24 extended header size: %d", ErrInvalidID3v2Header, extendedHeaderSize)
+			}
+			extendedHeaderSize -= 4
 			_, err = readBytes(r, extendedHeaderSize)
 			if err != nil {
-				return nil, 0, fmt.Errorf("expected to read %d bytes (ID3v24 skip extended header): %v", extendedHeaderSize, err)
+				return nil, 0, fmt.Errorf("expected to read %d bytes (ID3v24 skip extended header): %w", extendedHeaderSize, err)
 			}
-			offset += extendedHeaderSize
+			offset += 4 + extendedHeaderSize
 		default:
 			// nop, only 2.3 and 2.4 should have extended header
 		}
@@ -134,6 +158,112 @@ func readID3v2Header(r io.Reader) (h *id3v2Header, offset uint, err error) {
 	return h, offset, nil
 }
 
+// defaultSearchLimit is the number of bytes searched for the start of the tags when the
+// data does not start with a recognised format, if Options.SearchLimit is 0.
+const defaultSearchLimit = 4096
+
+// seekID3v2Header searches the first defaultSearchLimit bytes from the current position
+// for an ID3v2 header (see seekHeader).
+func seekID3v2Header(r io.ReadSeeker) (bool, error) {
+	magic, err := seekHeader(r, defaultSearchLimit, "ID3")
+	return magic != "", err
+}
+
+// seekHeader searches the first limit bytes from the current position for the first of the
+// magics, which can be "ID3" (an ID3v2 header), "fLaC", "OggS" or "ftyp" (the start of a
+// FLAC, OGG or MP4 file), i.e. after a UTF-8 BOM or other junk.  If one is found, r is
+// positioned at the start of the header or file and the magic is returned, otherwise r is
+// returned to its original position and "" is returned.
+func seekHeader(r io.ReadSeeker, limit int, magics ...string) (string, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil || limit <= 0 {
+		return "", err
+	}
+
+	b := make([]byte, limit)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	b = b[:n]
+
+	// valid returns the offset of the header or file if magic at i is the start of one,
+	// or -1 if not.
+	valid := func(magic string, i int) int {
+		switch magic {
+		case "ID3":
+			// Version must be 2, 3 or 4, the size a synchsafe integer, and the header
+			// followed by a frame (unless there is an extended header).
+			if i+10 > len(b) {
+				return -1
+			}
+			h := b[i : i+10]
+			if h[3] >= 2 && h[3] <= 4 && h[4] != 0xFF && h[6]|h[7]|h[8]|h[9] < 0x80 &&
+				(getBit(h[5], 6) || validID3v2FrameStart(b[i+10:], h[3])) {
+				return i
+			}
+
+		case "fLaC":
+			// The first metadata block is STREAMINFO.
+			if i+5 <= len(b) && b[i+4]&0x7f == byte(streamInfoBlock) {
+				return i
+			}
+
+		case "OggS":
+			// Version 0, and the first page of the stream.
+			if i+6 <= len(b) && b[i+4] == 0 && getBit(b[i+5], 1) {
+				return i
+			}
+
+		case "ftyp":
+			// The ftyp atom is the first atom, so starts 4 bytes earlier (its size).
+			if i >= 4 && getInt(b[i-4:i]) >= 8 {
+				return i - 4
+			}
+		}
+		return -1
+	}
+
+	for i := range b {
+		for _, magic := range magics {
+			if !bytes.HasPrefix(b[i:], []byte(magic)) {
+				continue
+			}
+			if offset := valid(magic, i); offset >= 0 {
+				_, err = r.Seek(start+int64(offset), io.SeekStart)
+				if err != nil {
+					return "", err
+				}
+				return magic, nil
+			}
+		}
+	}
+
+	_, err = r.Seek(start, io.SeekStart)
+	return "", err
+}
+
+// validID3v2FrameStart returns true if b starts with a valid frame ID (or padding) for
+// the given ID3v2 major version.  A truncated b is assumed to be valid.
+func validID3v2FrameStart(b []byte, version byte) bool {
+	n := 4
+	if version == 2 {
+		n = 3
+	}
+	if len(b) < n {
+		return true
+	}
+	if b[0] == 0 {
+		return true // padding
+	}
+	for _, c := range b[:n] {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
 // id3v2FrameFlags is a type which represents the flags which can be set on an ID3v2 frame.
 type id3v2FrameFlags struct {
 	// Message (ID3 2.3.0 and 2.4.0)
@@ -148,6 +278,8 @@ type id3v2FrameFlags struct {
 	// ID3 2.4.0 only (see http://id3.org/id3v2.4.0-structure sec 4.1)
 	Unsynchronisation   bool
 	DataLengthIndicator bool
+
+	raw [2]byte // flags as they appear in the frame header
 }
 
 func readID3v23FrameFlags(r io.Reader) (*id3v2FrameFlags, error) {
@@ -160,6 +292,7 @@ func readID3v23FrameFlags(r io.Reader) (*id3v2FrameFlags, error) {
 	fmt := b[1]
 
 	return &id3v2FrameFlags{
+		raw:                   [2]byte{msg, fmt},
 		TagAlterPreservation:  getBit(msg, 7),
 		FileAlterPreservation: getBit(msg, 6),
 		ReadOnly:              getBit(msg, 5),
@@ -179,6 +312,7 @@ func readID3v24FrameFlags(r io.Reader) (*id3v2FrameFlags, error) {
 	fmt := b[1]
 
 	return &id3v2FrameFlags{
+		raw:                   [2]byte{msg, fmt},
 		TagAlterPreservation:  getBit(msg, 6),
 		FileAlterPreservation: getBit(msg, 5),
 		ReadOnly:              getBit(msg, 4),
@@ -230,11 +364,50 @@ func readID3v2_4FrameHeader(r io.Reader) (name string, size uint, headerSize uin
 	return
 }
 
-// readID3v2Frames reads ID3v2 frames from the given reader using the ID3v2Header.
-func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]interface{}, error) {
+// validID3v24Frames returns true if b (the frames and padding of an ID3v2.4 tag) can be
+// read as a sequence of frames with valid IDs which ends exactly at the end of b or at
+// the start of padding, where frame sizes are synchsafe integers or, if plain is true,
+// plain 32-bit integers.
+func validID3v24Frames(b []byte, plain bool) bool {
+	for len(b) >= 10 && b[0] != 0 {
+		if !validID3v2FrameStart(b, 4) {
+			return false
+		}
+		size := getInt(b[4:8])
+		if !plain {
+			if b[4]|b[5]|b[6]|b[7] >= 0x80 {
+				return false
+			}
+			size = get7BitChunkedInt(b[4:8])
+		}
+		if size > len(b)-10 {
+			return false
+		}
+		b = b[10+size:]
+	}
+	return true
+}
+
+// readID3v2Frames reads ID3v2 frames from the given reader using the ID3v2Header, returning
+// the frames, (for ID3v2.3 and ID3v2.4) their header flags and the values of text frames
+// which have more than one, keyed by the same names.  The frames are read from offset up
+// to end, which both count the 10 byte header (end is h.Size + 10, less any bytes removed
+// by unsynchronising the whole tag).  ISO-8859-1 text is decoded with charset (if not nil).
+// If v is not nil (see Validate), the problems found are reported to it, and only the
+// structure of the frames is read: the values are not decoded, so the maps are empty.
+func readID3v2Frames(r io.Reader, offset, end uint, h *id3v2Header, charset charsetDecoder, v *validator) (map[string]interface{}, map[string][2]byte, map[string][]string, error) {
 	result := make(map[string]interface{})
+	frameFlags := make(map[string][2]byte)
+	textValues := make(map[string][]string)
 
-	for offset < h.Size {
+	frameHeaderSize := uint(10)
+	if h.Version == ID3v2_2 {
+		frameHeaderSize = 6
+	}
+
+	// Anything too short to hold a frame header is padding.
+	for offset+frameHeaderSize <= end {
+		frameOffset := offset
 		var err error
 		var name string
 		var size, headerSize uint
@@ -247,22 +420,26 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 		case ID3v2_3:
 			name, size, headerSize, err = readID3v2_3FrameHeader(r)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			flags, err = readID3v23FrameFlags(r)
 			headerSize += 2
 
 		case ID3v2_4:
-			name, size, headerSize, err = readID3v2_4FrameHeader(r)
+			if h.PlainFrameSizes {
+				name, size, headerSize, err = readID3v2_3FrameHeader(r)
+			} else {
+				name, size, headerSize, err = readID3v2_4FrameHeader(r)
+			}
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			flags, err = readID3v24FrameFlags(r)
 			headerSize += 2
 		}
 
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 
 		// FIXME: Do we still need this?
@@ -275,46 +452,73 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 		offset += headerSize + size
 
 		// Avoid corrupted padding (see http://id3.org/Compliance%20Issues).
-		if !validID3Frame(h.Version, name) && offset > h.Size {
+		if !validID3Frame(h.Version, name) && offset > end {
 			break
 		}
+		if offset > end {
+			v.id3v2Issue(SeverityError, IssueFrameSize, frameOffset,
+				"%v frame size %d overshoots the end of the tag by %d bytes", name, size, offset-end)
+		}
+
+		// ID3v2.4 frames are unsynchronised individually, which the tag header flag
+		// indicates for all of the frames.
+		unsynchronised := flags != nil && (flags.Unsynchronisation || h.Version == ID3v2_4 && h.Unsynchronisation)
 
 		if flags != nil {
+			if flags.Encryption {
+				v.id3v2Issue(SeverityWarning, IssueFrameDropped, frameOffset, "%v frame is encrypted, so cannot be read", name)
+			} else if flags.Compression {
+				v.id3v2Issue(SeverityWarning, IssueFrameDropped, frameOffset, "%v frame is compressed, so cannot be read", name)
+			}
+
 			if flags.Compression {
 				switch h.Version {
 				case ID3v2_3:
 					// No data length indicator defined.
 					if _, err := read7BitChunkedUint(r, 4); err != nil { // read 4
-						return nil, err
+						return nil, nil, nil, err
 					}
 					size -= 4
 
 				case ID3v2_4:
 					// Must have a data length indicator (to give the size) if compression is enabled.
 					if !flags.DataLengthIndicator {
-						return nil, errors.New("compression without data length indicator")
+						return nil, nil, nil, errors.New("compression without data length indicator")
 					}
 
 				default:
-					return nil, fmt.Errorf("unsupported compression flag used in %v", h.Version)
+					return nil, nil, nil, fmt.Errorf("unsupported compression flag used in %v", h.Version)
 				}
 			}
 
 			if flags.DataLengthIndicator {
 				if h.Version == ID3v2_3 {
-					return nil, fmt.Errorf("data length indicator set but not defined for %v", ID3v2_3)
+					return nil, nil, nil, fmt.Errorf("data length indicator set but not defined for %v", ID3v2_3)
 				}
 
-				size, err = read7BitChunkedUint(r, 4)
+				dataLength, err := read7BitChunkedUint(r, 4)
 				if err != nil { // read 4
-					return nil, err
+					return nil, nil, nil, err
+				}
+
+				// The frame size includes the data length indicator and the
+				// unsynchronisation bytes, the data length does not.
+				switch {
+				case !unsynchronised:
+					size = dataLength
+				case size >= 4:
+					size -= 4
+				default:
+					v.id3v2Issue(SeverityError, IssueFrameDropped, frameOffset,
+						"%v frame size %d is too small for its data length indicator", name, size)
+					return nil, nil, nil, fmt.Errorf("invalid %v frame size: %d", name, size)
 				}
 			}
 
 			if flags.Encryption {
 				_, err = readBytes(r, 1) // read 1 byte of encryption method
 				if err != nil {
-					return nil, err
+					return nil, nil, nil, err
 				}
 				size--
 			}
@@ -322,7 +526,20 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 
 		b, err := readBytes(r, size)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
+		}
+
+		if unsynchronised {
+			b, err = io.ReadAll(&unsynchroniser{Reader: bytes.NewReader(b)})
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if v != nil {
+			if flags == nil || !flags.Compression && !flags.Encryption {
+				v.id3v2Frame(name, b, h, frameOffset)
+			}
+			continue
 		}
 
 		// There can be multiple tag with the same name. Append a number to the
@@ -335,68 +552,109 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 			}
 		}
 
+		if flags != nil {
+			frameFlags[rawName] = flags.raw
+		}
+
 		switch {
 		case name == "TXXX" || name == "TXX":
-			t, err := readTextWithDescrFrame(b, false, true) // no lang, but enc
+			t, err := readTextWithDescrFrame(b, false, true, charset) // no lang, but enc
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			result[rawName] = t
 
-		case name[0] == 'T':
-			txt, err := readTFrame(b)
+		case name[0] == 'T' || id3v2iTunesTextFrames[name]:
+			values, err := readTFrameValues(b, charset)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
+			}
+			result[rawName] = strings.Join(values, ";")
+			if len(values) > 1 {
+				textValues[rawName] = values
 			}
-			result[rawName] = txt
 
 		case name == "UFID" || name == "UFI":
 			t, err := readUFID(b)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			result[rawName] = t
 
 		case name == "WXXX" || name == "WXX":
-			t, err := readTextWithDescrFrame(b, false, false) // no lang, no enc
+			t, err := readTextWithDescrFrame(b, false, false, nil) // no lang, no enc
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			result[rawName] = t
 
 		case name[0] == 'W':
 			txt, err := readWFrame(b)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			result[rawName] = txt
 
 		case name == "COMM" || name == "COM" || name == "USLT" || name == "ULT":
-			t, err := readTextWithDescrFrame(b, true, true) // both lang and enc
+			t, err := readTextWithDescrFrame(b, true, true, charset) // both lang and enc
 			if err != nil {
-				return nil, fmt.Errorf("could not read %q (%q): %v", name, rawName, err)
+				return nil, nil, nil, fmt.Errorf("could not read %q (%q): %w", name, rawName, err)
 			}
 			result[rawName] = t
 
+		case name == "LINK" || name == "LNK":
+			idLen := 3
+			if h.Version == ID3v2_4 {
+				idLen = 4
+			}
+			l, err := readLINKFrame(b, idLen)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			result[rawName] = l
+
+		case name == "POPM" || name == "POP":
+			p, err := readPOPMFrame(b)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			result[rawName] = p
+
+		case name == "RVAD" || name == "RVA":
+			v, err := readRVADFrame(b)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			result[rawName] = v
+
 		case name == "APIC":
-			p, err := readAPICFrame(b)
+			p, err := readAPICFrame(b, charset)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			result[rawName] = p
 
 		case name == "PIC":
-			p, err := readPICFrame(b)
+			p, err := readPICFrame(b, charset)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			result[rawName] = p
 
 		default:
+			// Frames which are not parsed are kept as their raw data.
 			result[rawName] = b
 		}
 	}
-	return result, nil
+	return result, frameFlags, textValues, nil
+}
+
+// id3v2iTunesTextFrames are the frames (outside of the T frames) which iTunes writes as
+// text frames.
+var id3v2iTunesTextFrames = map[string]bool{
+	"GRP1": true,
+	"MVNM": true,
+	"MVIN": true,
 }
 
 type unsynchroniser struct {
@@ -423,47 +681,150 @@ func (r *unsynchroniser) Read(p []byte) (int, error) {
 	return i, nil
 }
 
+// readID3v2Chunk reads the ID3v2 tag in b, the data of an "ID3 " (or "id3 ") chunk in a
+// WAV or AIFF file.  Chunks are padded to an even length, so the tag is read from exactly
+// the chunk data to keep the pad byte out of the frames.
+func readID3v2Chunk(b []byte, opts Options) (Metadata, error) {
+	br := bytes.NewReader(b)
+	ok, err := seekID3v2Header(br)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid ID3 chunk: expected ID3v2 header")
+	}
+
+	m, err := readID3v2Tags(br, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID3 chunk: %w", err)
+	}
+	return m, nil
+}
+
 // ReadID3v2Tags parses ID3v2.{2,3,4} tags from the io.ReadSeeker into a Metadata, returning
 // non-nil error on failure.
 func ReadID3v2Tags(r io.ReadSeeker) (Metadata, error) {
+	return ReadID3v2TagsReader(r)
+}
+
+// ReadID3v2TagsReader parses ID3v2.{2,3,4} tags from the io.Reader into a Metadata, as for
+// ReadID3v2Tags, without seeking (i.e. from a network stream).  Only the tag is read, so r
+// is left at the start of the data which follows it.
+func ReadID3v2TagsReader(r io.Reader) (Metadata, error) {
+	return readID3v2Tags(r, Options{})
+}
+
+// readID3v2Tags implements ReadID3v2TagsReader, with the options opts.
+func readID3v2Tags(r io.Reader, opts Options) (Metadata, error) {
 	h, offset, err := readID3v2Header(r)
 	if err != nil {
 		return nil, err
 	}
 
+	// The frames are read with many small reads, so read the whole tag into memory
+	// first rather than reading from r (which may be unbuffered, i.e. an *os.File).
 	var ur io.Reader = r
-	if h.Unsynchronisation {
-		ur = &unsynchroniser{Reader: r}
+	end := h.Size + 10
+	if end > offset {
+		b, err := readBytes(r, end-offset)
+		if err != nil {
+			opts.validator.id3v2Issue(SeverityError, IssueTagSize, 0, "ID3v2 tag size %d extends past the end of the data", h.Size)
+			return nil, err
+		}
+
+		// ID3v2.2 and ID3v2.3 tags are unsynchronised as a whole, and the frame sizes are
+		// of the data before unsynchronisation.  ID3v2.4 frames are unsynchronised
+		// individually (see readID3v2Frames).
+		if h.Unsynchronisation && h.Version != ID3v2_4 {
+			n := len(b)
+			b, err = io.ReadAll(&unsynchroniser{Reader: bytes.NewReader(b)})
+			if err != nil {
+				return nil, err
+			}
+			end -= uint(n - len(b))
+		}
+		ur = bytes.NewReader(b)
+
+		// Some writers (notably older versions of iTunes and FFmpeg) label tags as ID3v2.4
+		// but write ID3v2.3 frame sizes.  The whole tag is checked, rather than each frame,
+		// as frames smaller than 128 bytes have the same size either way.
+		if h.Version == ID3v2_4 {
+			h.PlainFrameSizes = !validID3v24Frames(b, false) && validID3v24Frames(b, true)
+		}
 	}
 
-	f, err := readID3v2Frames(ur, offset, h)
+	f, flags, values, err := readID3v2Frames(ur, offset, end, h, opts.Charset, opts.validator)
 	if err != nil {
 		return nil, err
 	}
-	return metadataID3v2{header: h, frames: f}, nil
+	return MetadataID3v2{header: h, frames: f, flags: flags, values: values}, nil
 }
 
-var id3v2genreRe = regexp.MustCompile(`(.*[^(]|.* |^)\(([0-9]+)\) *(.*)$`)
-
 //  id3v2genre parse a id3v2 genre tag and expand the numeric genres
+//
+// Numeric references, i.e. "(17)", are expanded wherever they appear outside of free
+// text parentheses, so "(4)Eurodisco" becomes "Disco Eurodisco" but "Mix (Vol (2))" is
+// unchanged.  A literal "(" at the start of free text is escaped as "((".
 func id3v2genre(genre string) string {
-	c := true
-	for c {
-		orig := genre
-		if match := id3v2genreRe.FindStringSubmatch(genre); len(match) > 0 {
-			if genreID, err := strconv.Atoi(match[2]); err == nil {
-				if genreID < len(id3v2Genres) {
-					genre = id3v2Genres[genreID]
-					if match[1] != "" {
-						genre = strings.TrimSpace(match[1]) + " " + genre
-					}
-					if match[3] != "" {
-						genre = genre + " " + match[3]
-					}
-				}
+	var parts []string
+	var text strings.Builder
+	flush := func() {
+		if t := strings.TrimSpace(text.String()); t != "" {
+			parts = append(parts, t)
+		}
+		text.Reset()
+	}
+
+	depth := 0 // depth of free text parentheses
+	for i := 0; i < len(genre); i++ {
+		c := genre[i]
+		switch {
+		case c == '(' && depth == 0 && strings.HasPrefix(genre[i:], "(("):
+			text.WriteByte('(')
+			depth++
+			i++
+
+		case c == '(' && depth == 0:
+			if n, ok := id3v2GenreRef(genre[i:]); ok {
+				flush()
+				parts = append(parts, id3v2Genres[n])
+				i += strings.IndexByte(genre[i:], ')')
+				break
 			}
+			text.WriteByte(c)
+			depth++
+
+		case c == '(':
+			text.WriteByte(c)
+			depth++
+
+		case c == ')' && depth > 0:
+			text.WriteByte(c)
+			depth--
+
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flush()
+	return strings.Join(parts, " ")
+}
+
+// id3v2GenreRef returns the genre ID of the numeric genre reference at the start of s,
+// i.e. "(17)".
+func id3v2GenreRef(s string) (int, bool) {
+	end := strings.IndexByte(s, ')')
+	if end < 2 {
+		return 0, false
+	}
+	for _, c := range s[1:end] {
+		if c < '0' || c > '9' {
+			return 0, false
 		}
-		c = (orig != genre)
 	}
-	return strings.Replace(genre, "((", "(", -1)
+	n, err := strconv.Atoi(s[1:end])
+	if err != nil || n >= len(id3v2Genres) {
+		return 0, false
+	}
+	return n, true
 }
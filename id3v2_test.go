@@ -6,6 +6,9 @@ package tag
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -144,6 +147,13 @@ func TestGenreExpension(t *testing.T) {
 		"(175)":		"Post-Punk",
 		"(187)":		"Indie Rock",
 		"(191)":		"Psybient",
+		"(4)Eurodisco":                "Disco Eurodisco",
+		"Rock (with (nested) parens)": "Rock (with (nested) parens)",
+		"(17)Live (at (93) Club)":     "Rock Live (at (93) Club)",
+		"Mix (Vol (2))":               "Mix (Vol (2))",
+		"(17)((93) Mix)":              "Rock (93) Mix)",
+		"(300)":                       "(300)",
+		"(RX)(17)":                    "(RX) Rock",
 	}
 	for g, r := range tests {
 		got := id3v2genre(g)
@@ -153,3 +163,352 @@ func TestGenreExpension(t *testing.T) {
 		}
 	}
 }
+
+func TestValidID3v24Frames(t *testing.T) {
+	frame := func(size []byte, n int) []byte {
+		return append(append([]byte("TIT2"), append(size, 0, 0)...), bytes.Repeat([]byte{'x'}, n)...)
+	}
+	padding := make([]byte, 16)
+
+	tests := []struct {
+		name             string
+		b                []byte
+		synchsafe, plain bool
+	}{
+		{"small frame", append(frame([]byte{0, 0, 0, 10}, 10), padding...), true, true},
+		{"synchsafe 256", append(frame([]byte{0, 0, 2, 0}, 256), padding...), true, false},
+		{"plain 256", append(frame([]byte{0, 0, 1, 0}, 256), padding...), false, true},
+		{"plain 200", frame([]byte{0, 0, 0, 200}, 200), false, true},
+		{"overshoot", frame([]byte{0, 0, 0, 20}, 10), false, false},
+		{"invalid ID", []byte("ab\x00\x00\x00\x00\x00\x00\x00\x00"), false, false},
+		{"padding only", padding, true, true},
+	}
+
+	for _, tt := range tests {
+		if got := validID3v24Frames(tt.b, false); got != tt.synchsafe {
+			t.Errorf("%v: validID3v24Frames(b, false) = %v, expected: %v", tt.name, got, tt.synchsafe)
+		}
+		if got := validID3v24Frames(tt.b, true); got != tt.plain {
+			t.Errorf("%v: validID3v24Frames(b, true) = %v, expected: %v", tt.name, got, tt.plain)
+		}
+	}
+}
+
+func BenchmarkReadID3v2TagsFile(b *testing.B) {
+	for _, path := range []string{"sample.id3v23.mp3", "sample.id3v24.mp3"} {
+		b.Run(path, func(b *testing.B) {
+			// An *os.File is unbuffered, so each small read is a system call.
+			f, err := os.Open(filepath.Join("testdata", "with_tags", path))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := ReadID3v2Tags(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestReadLINKFrame(t *testing.T) {
+	tests := []struct {
+		b     []byte
+		idLen int
+		want  *Link
+	}{
+		{[]byte("TALhttp://example.com/a.mp3\x00"), 3, &Link{FrameID: "TAL", URL: "http://example.com/a.mp3", AdditionalData: []byte{}}},
+		{[]byte("COMMhttp://example.com/a.mp3\x00eng"), 4, &Link{FrameID: "COMM", URL: "http://example.com/a.mp3", AdditionalData: []byte("eng")}},
+		{[]byte("TALBhttp://example.com/a.mp3"), 4, &Link{FrameID: "TALB", URL: "http://example.com/a.mp3"}},
+	}
+
+	for _, tt := range tests {
+		got, err := readLINKFrame(tt.b, tt.idLen)
+		if err != nil {
+			t.Errorf("readLINKFrame(%q, %d) returned unexpected error: %v", tt.b, tt.idLen, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readLINKFrame(%q, %d) = %#v, expected: %#v", tt.b, tt.idLen, got, tt.want)
+		}
+	}
+
+	if _, err := readLINKFrame([]byte("TA"), 3); err == nil {
+		t.Errorf("readLINKFrame() expected error for short frame")
+	}
+}
+
+func TestID3v2LINKFrames(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "with_tags", "sample.link.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+
+	want := map[string]*Link{
+		"LINK":   {FrameID: "TALB", URL: "http://example.com/album.mp3", AdditionalData: []byte{}},
+		"LINK_0": {FrameID: "COMM", URL: "http://example.com/notes.mp3", AdditionalData: []byte("engLiner notes")},
+	}
+	for k, w := range want {
+		if got := m.Raw()[k]; !reflect.DeepEqual(got, w) {
+			t.Errorf("Raw()[%q] = %#v, expected: %#v", k, got, w)
+		}
+	}
+}
+
+func TestID3v2UnknownFrames(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "with_tags", "sample.unknown-frames.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	id3, ok := m.(MetadataID3v2)
+	if !ok {
+		t.Fatalf("ReadFrom() returned %T, expected MetadataID3v2", m)
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		flags [2]byte
+	}{
+		{"PCNT", []byte{0, 0, 1, 44}, [2]byte{0x40, 0}},
+		{"MCDI", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, [2]byte{}},
+	}
+	for _, tt := range tests {
+		if got, _ := id3.Raw()[tt.name].([]byte); !bytes.Equal(got, tt.data) {
+			t.Errorf("Raw()[%q] = %#v, expected: %#v", tt.name, id3.Raw()[tt.name], tt.data)
+		}
+		if got, ok := id3.FrameFlags(tt.name); !ok || got != tt.flags {
+			t.Errorf("FrameFlags(%q) = %v, %v, expected: %v, true", tt.name, got, ok, tt.flags)
+		}
+	}
+
+	if _, ok := id3.FrameFlags("XXXX"); ok {
+		t.Errorf("FrameFlags(%q) returned ok for missing frame", "XXXX")
+	}
+}
+
+func TestID3v2FrameUnsynchronisation(t *testing.T) {
+	synchsafe := func(n int) []byte {
+		return []byte{byte(n>>21) & 0x7f, byte(n>>14) & 0x7f, byte(n>>7) & 0x7f, byte(n) & 0x7f}
+	}
+	frame := func(name string, flags byte, body []byte) []byte {
+		b := append([]byte(name), synchsafe(len(body))...)
+		b = append(b, 0x00, flags)
+		return append(b, body...)
+	}
+
+	// ISO-8859-1 "Test\xff\xe0Title", with 0x00 inserted after 0xFF.
+	title := []byte("\x00Test\xff\x00\xe0Title")
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"unsynchronisation", frame("TIT2", 0x02, title)},
+		{"unsynchronisation and data length indicator", frame("TIT2", 0x03, append(synchsafe(len(title)-1), title...))},
+	}
+
+	for _, tt := range tests {
+		frames := append(tt.frame, frame("TPE1", 0x00, []byte("\x00Test Artist"))...)
+		tag := append([]byte("ID3\x04\x00\x00"), synchsafe(len(frames))...)
+		tag = append(tag, frames...)
+
+		m, err := ReadID3v2Tags(bytes.NewReader(tag))
+		if err != nil {
+			t.Errorf("%v: ReadID3v2Tags() returned unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got, want := m.Title(), "TestÿàTitle"; got != want {
+			t.Errorf("%v: Title() = %q, expected: %q", tt.name, got, want)
+		}
+		if got, want := m.Artist(), "Test Artist"; got != want {
+			t.Errorf("%v: Artist() = %q, expected: %q", tt.name, got, want)
+		}
+	}
+}
+
+// unsynchronise applies the ID3v2 unsynchronisation scheme to b, inserting 0x00 after
+// each 0xFF which is followed by 0x00, a byte >= 0xE0, or the end of b.
+func unsynchronise(b []byte) []byte {
+	var out []byte
+	for i, c := range b {
+		out = append(out, c)
+		if c == 0xFF && (i == len(b)-1 || b[i+1] == 0x00 || b[i+1] >= 0xE0) {
+			out = append(out, 0x00)
+		}
+	}
+	return out
+}
+
+func TestID3v2UnsynchronisedPicture(t *testing.T) {
+	// Image data which needs unsynchronisation, including at the end of the frame.
+	img := []byte("\x89PNG\r\n\x1a\n\xff\x00\x01\xff\xe0\xff\xff\xfb\xff\x00\x00\xff")
+	apic := append([]byte("\x00image/png\x00\x03\x00"), img...)
+	title := []byte("\x00Test Title")
+
+	// ID3v2.3 frame sizes are of the data before unsynchronisation.
+	frame23 := func(name string, body []byte) []byte {
+		b := append([]byte(name), byte(len(body)>>24), byte(len(body)>>16), byte(len(body)>>8), byte(len(body)))
+		return append(append(b, 0x00, 0x00), body...)
+	}
+	// ID3v2.4 frame sizes are of the unsynchronised data, which follows the data length
+	// indicator (if there is one).
+	frame24 := func(name string, flags byte, body []byte) []byte {
+		var dli []byte
+		if flags&0x01 != 0 {
+			dli = synchsafe(len(body))
+		}
+		body = append(dli, unsynchronise(body)...)
+		b := append([]byte(name), synchsafe(len(body))...)
+		return append(append(b, 0x00, flags), body...)
+	}
+
+	tests := []struct {
+		name string
+		tag  []byte
+	}{
+		{"ID3v2.3", func() []byte {
+			frames := unsynchronise(append(frame23("APIC", apic), frame23("TIT2", title)...))
+			return append(append([]byte("ID3\x03\x00\x80"), synchsafe(len(frames))...), frames...)
+		}()},
+		{"ID3v2.4", func() []byte {
+			frames := append(frame24("APIC", 0x02, apic), frame24("TIT2", 0x02, title)...)
+			return append(append([]byte("ID3\x04\x00\x80"), synchsafe(len(frames))...), frames...)
+		}()},
+		{"ID3v2.4 data length indicator", func() []byte {
+			frames := append(frame24("APIC", 0x03, apic), frame24("TIT2", 0x03, title)...)
+			return append(append([]byte("ID3\x04\x00\x80"), synchsafe(len(frames))...), frames...)
+		}()},
+	}
+
+	for _, tt := range tests {
+		// The tag is followed by audio data, which must not be read as part of the tag.
+		r := bytes.NewReader(append(tt.tag, 0xFF, 0xFB, 0x90, 0x00))
+		m, err := ReadID3v2Tags(r)
+		if err != nil {
+			t.Errorf("%v: ReadID3v2Tags() returned unexpected error: %v", tt.name, err)
+			continue
+		}
+		if p := m.Picture(); p == nil || !bytes.Equal(p.Data, img) {
+			t.Errorf("%v: Picture() = %v, expected data: %x", tt.name, p, img)
+		}
+		if got, want := m.Title(), "Test Title"; got != want {
+			t.Errorf("%v: Title() = %q, expected: %q", tt.name, got, want)
+		}
+		if pos := r.Size() - int64(r.Len()); pos != int64(len(tt.tag)) {
+			t.Errorf("%v: ReadID3v2Tags() read %d bytes, expected: %d", tt.name, pos, len(tt.tag))
+		}
+
+		// The tag can also be the whole file.
+		if _, err := ReadID3v2Tags(bytes.NewReader(tt.tag)); err != nil {
+			t.Errorf("%v: ReadID3v2Tags() of tag only returned unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestReadFromOptionsCharset(t *testing.T) {
+	frame := func(name string, body []byte) []byte {
+		b := append([]byte(name), byte(len(body)>>24), byte(len(body)>>16), byte(len(body)>>8), byte(len(body)))
+		return append(append(b, 0x00, 0x00), body...)
+	}
+
+	// "Тест" in Windows-1251 (ISO-8859-1 encoding byte), and "Test Artist" in UTF-16.
+	cp1251 := []byte{0xD2, 0xE5, 0xF1, 0xF2}
+	frames := append(frame("TIT2", append([]byte{0x00}, cp1251...)),
+		frame("TPE1", []byte("\x01\xff\xfeT\x00e\x00s\x00t\x00 \x00A\x00r\x00t\x00i\x00s\x00t\x00"))...)
+	frames = append(frames, frame("COMM", append([]byte("\x00eng\x00"), cp1251...))...)
+	tag := append(append([]byte("ID3\x03\x00\x00"), synchsafe(len(frames))...), frames...)
+
+	// Decodes the Cyrillic letters of Windows-1251 (0xC0-0xFF), and nothing else.
+	var encodings []byte
+	charset := func(enc byte, b []byte) (string, bool) {
+		encodings = append(encodings, enc)
+		r := make([]rune, len(b))
+		for i, c := range b {
+			if c < 0xC0 {
+				return "", false
+			}
+			r[i] = rune(c) - 0xC0 + 'А'
+		}
+		return string(r), true
+	}
+
+	m, err := ReadFromOptions(bytes.NewReader(tag), Options{Charset: charset})
+	if err != nil {
+		t.Fatalf("ReadFromOptions() returned unexpected error: %v", err)
+	}
+	if got, want := m.Title(), "Тест"; got != want {
+		t.Errorf("Title() = %q, expected: %q", got, want)
+	}
+	if got, want := m.Artist(), "Test Artist"; got != want {
+		t.Errorf("Artist() = %q, expected: %q", got, want)
+	}
+	if got, want := m.Comment(), "Тест"; got != want {
+		t.Errorf("Comment() = %q, expected: %q", got, want)
+	}
+	// Called for the title and the comment text, but not the empty comment description or
+	// the UTF-16 artist.
+	if !bytes.Equal(encodings, []byte{0, 0}) {
+		t.Errorf("Charset called with encodings %v, expected: [0 0]", encodings)
+	}
+
+	// The default is ISO-8859-1.
+	m, err = ReadFrom(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	if got, want := m.Title(), "Òåñò"; got != want {
+		t.Errorf("Title() = %q, expected: %q", got, want)
+	}
+}
+
+func TestID3v2ExtendedHeader(t *testing.T) {
+	// The first frame is after the header (10 bytes) and the extended header.
+	tests := []struct {
+		path   string
+		offset uint
+	}{
+		{"sample.exthdr.id3v23.mp3", 20}, // size, flags and padding size
+		{"sample.exthdr.id3v24.mp3", 22}, // size, flags and CRC
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, offset, err := readID3v2Header(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: readID3v2Header() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+		if !h.ExtendedHeader || offset != tt.offset {
+			t.Errorf("%v: readID3v2Header() = %v, %d, expected: true, %d", tt.path, h.ExtendedHeader, offset, tt.offset)
+		}
+	}
+
+	// The ID3v2.4 extended header size includes the size itself, so must be at least 4.
+	b := []byte("ID3\x04\x00\x40\x00\x00\x01\x00\x00\x00\x00\x02\x01\x00")
+	if _, _, err := readID3v2Header(bytes.NewReader(b)); err == nil {
+		t.Errorf("readID3v2Header() with extended header size 2 returned nil error")
+	}
+}
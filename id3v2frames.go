@@ -9,6 +9,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"unicode/utf16"
 )
@@ -107,10 +109,13 @@ var id3v23Frames = map[string]string{
 	"ETCO": "Event timing codes",
 	"GEOB": "General encapsulated object",
 	"GRID": "Group identification registration",
+	"GRP1": "iTunes uses this for Work",
 	"IPLS": "Involved people list",
 	"LINK": "Linked information",
 	"MCDI": "Music CD identifier",
 	"MLLT": "MPEG location lookup table",
+	"MVIN": "iTunes Movement Number/Count",
+	"MVNM": "iTunes Movement Name",
 	"OWNE": "Ownership frame",
 	"PRIV": "Private frame",
 	"PCNT": "Play counter",
@@ -192,11 +197,14 @@ var id3v24Frames = map[string]string{
 
 	"GEOB": "General encapsulated object",
 	"GRID": "Group identification registration",
+	"GRP1": "iTunes uses this for Work",
 
 	"LINK": "Linked information",
 
 	"MCDI": "Music CD identifier",
 	"MLLT": "MPEG location lookup table",
+	"MVIN": "iTunes Movement Number/Count",
+	"MVNM": "iTunes Movement Name",
 
 	"OWNE": "Ownership frame",
 
@@ -298,19 +306,39 @@ func validID3Frame(version Format, name string) bool {
 func readWFrame(b []byte) (string, error) {
 	// Frame text is always encoded in ISO-8859-1
 	b = append([]byte{0}, b...)
-	return readTFrame(b)
+	return readTFrame(b, nil)
 }
 
-func readTFrame(b []byte) (string, error) {
+func readTFrame(b []byte, charset charsetDecoder) (string, error) {
+	values, err := readTFrameValues(b, charset)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(values, ";"), nil
+}
+
+// readTFrameValues returns the values of the text frame b.  In ID3v2.4 a text frame can
+// have more than one value, separated by NUL.  Empty values (i.e. from a NUL terminator,
+// which some writers add) are dropped.
+func readTFrameValues(b []byte, charset charsetDecoder) ([]string, error) {
 	if len(b) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
-	txt, err := decodeText(b[0], b[1:])
+	txt, err := decodeText(b[0], b[1:], charset)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return strings.Join(strings.Split(txt, string(singleZero)), ""), nil
+
+	var values []string
+	for _, v := range strings.Split(txt, string(singleZero)) {
+		// Each UTF-16 value can have its own byte order mark.
+		v = strings.TrimPrefix(v, "\ufeff")
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values, nil
 }
 
 const (
@@ -320,13 +348,24 @@ const (
 	encodingUTF8         byte = 3
 )
 
-func decodeText(enc byte, b []byte) (string, error) {
+// charsetDecoder decodes text with encoding byte enc (see Options.Charset), returning
+// false to use the default decoding.
+type charsetDecoder func(enc byte, b []byte) (string, bool)
+
+// decodeText decodes the text b with encoding byte enc.  ISO-8859-1 text is decoded with
+// charset first, if it is not nil.
+func decodeText(enc byte, b []byte, charset charsetDecoder) (string, error) {
 	if len(b) == 0 {
 		return "", nil
 	}
 
 	switch enc {
 	case encodingISO8859: // ISO-8859-1
+		if charset != nil {
+			if s, ok := charset(enc, b); ok {
+				return s, nil
+			}
+		}
 		return decodeISO8859(b), nil
 
 	case encodingUTF16WithBOM: // UTF-16 with byte order marker
@@ -451,7 +490,7 @@ func (t Comm) String() string {
 // Text encoding       $xx
 // Description         <text string according to encoding> $00 (00)
 // Value               <text string according to encoding>
-func readTextWithDescrFrame(b []byte, hasLang bool, encoded bool) (*Comm, error) {
+func readTextWithDescrFrame(b []byte, hasLang bool, encoded bool, charset charsetDecoder) (*Comm, error) {
 	if len(b) == 0 {
 		return nil, errors.New("error decoding tag description text: invalid encoding")
 	}
@@ -472,9 +511,9 @@ func readTextWithDescrFrame(b []byte, hasLang bool, encoded bool) (*Comm, error)
 		return nil, errors.New("error decoding tag description text: invalid encoding")
 	}
 
-	desc, err := decodeText(enc, descTextSplit[0])
+	desc, err := decodeText(enc, descTextSplit[0], charset)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding tag description text: %v", err)
+		return nil, fmt.Errorf("error decoding tag description text: %w", err)
 	}
 	c.Description = desc
 
@@ -485,9 +524,9 @@ func readTextWithDescrFrame(b []byte, hasLang bool, encoded bool) (*Comm, error)
 	if !encoded {
 		enc = byte(0)
 	}
-	text, err := decodeText(enc, descTextSplit[1])
+	text, err := decodeText(enc, descTextSplit[1], charset)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding tag text: %v", err)
+		return nil, fmt.Errorf("error decoding tag text: %w", err)
 	}
 	c.Text = text
 
@@ -517,6 +556,183 @@ func readUFID(b []byte) (*UFID, error) {
 	}, nil
 }
 
+// Link is a type which represents an ID3v2 LINK (linked information) frame, which
+// references a frame in another file or tag.
+type Link struct {
+	FrameID        string // ID of the linked frame.
+	URL            string // URL of the file containing the linked frame.
+	AdditionalData []byte // Additional ID data, i.e. a language or description.
+}
+
+func (l Link) String() string {
+	return fmt.Sprintf("%v (%v)", l.FrameID, l.URL)
+}
+
+// readLINKFrame reads a LINK (LNK in ID3v2.2) frame, where the linked frame ID is idLen
+// bytes long (3 bytes before ID3v2.4).
+// Frame identifier  <idLen bytes>
+// URL               <text string> $00
+// ID and additional data <text string(s)>
+func readLINKFrame(b []byte, idLen int) (*Link, error) {
+	if len(b) < idLen {
+		return nil, fmt.Errorf("expected at least %d bytes for LINK frame, got %d", idLen, len(b))
+	}
+
+	url, data, _ := bytes.Cut(b[idLen:], singleZero)
+	return &Link{
+		FrameID:        strings.TrimRight(string(b[:idLen]), "\x00"),
+		URL:            string(url),
+		AdditionalData: data,
+	}, nil
+}
+
+// Popularimeter is a type which represents an ID3v2 POPM (popularimeter) frame, which
+// records how much a user (identified by email) likes the audio.
+type Popularimeter struct {
+	Email   string // Email address of the user the rating and counter belong to.
+	Rating  int    // Rating from 1 (worst) to 255 (best), or 0 if unknown.
+	Counter uint64 // Number of times the user has played the audio.
+}
+
+func (p Popularimeter) String() string {
+	return fmt.Sprintf("%v: %d (%d)", p.Email, p.Rating, p.Counter)
+}
+
+// Email to user   <text string> $00
+// Rating          $xx
+// Counter         $xx xx xx xx (xx ...)
+func readPOPMFrame(b []byte) (*Popularimeter, error) {
+	email, b, ok := bytes.Cut(b, singleZero)
+	if !ok || len(b) < 1 {
+		return nil, errors.New("invalid POPM frame: expected email and rating")
+	}
+
+	// The counter can be omitted, and is at least 4 bytes long when present.  Counters
+	// which do not fit into a uint64 are capped.
+	var counter uint64
+	for _, x := range b[1:] {
+		if counter > math.MaxUint64>>8 {
+			counter = math.MaxUint64
+			break
+		}
+		counter = counter<<8 | uint64(x)
+	}
+
+	return &Popularimeter{
+		Email:   string(email),
+		Rating:  int(b[0]),
+		Counter: counter,
+	}, nil
+}
+
+// RelativeVolume is a type which represents an ID3v2.3 RVAD (RVA in ID3v2.2) relative
+// volume adjustment frame, the predecessor of the ID3v2.4 RVA2 frame.  Channels which
+// are not in the frame are zero.
+type RelativeVolume struct {
+	Bits      int // Number of bits used for each volume change and peak value.
+	Right     ChannelVolume
+	Left      ChannelVolume
+	RightBack ChannelVolume
+	LeftBack  ChannelVolume
+	Center    ChannelVolume
+	Bass      ChannelVolume
+}
+
+// ChannelVolume is the relative volume change and peak volume of one channel in an RVAD
+// frame.
+type ChannelVolume struct {
+	Change int64  // Relative volume change, negative for a decrement.
+	Peak   uint64 // Peak volume, in the units chosen by the writer.
+}
+
+// Gain returns the change of the channel in dB.  The relative volume change is the
+// fraction (of 2^bits) by which the volume is increased or decreased, so with 16 bits
+// 32768 is +3.52 dB (a factor of 1.5), and -32768 is -6.02 dB (a factor of 0.5).
+// Returns 0 if the change would silence the channel.
+func (c ChannelVolume) Gain(bits int) float64 {
+	f := 1 + float64(c.Change)/math.Exp2(float64(bits))
+	if f <= 0 {
+		return 0
+	}
+	return 20 * math.Log10(f)
+}
+
+func (v RelativeVolume) String() string {
+	return fmt.Sprintf("right: %.2f dB, left: %.2f dB", v.Right.Gain(v.Bits), v.Left.Gain(v.Bits))
+}
+
+// IDv2.{2,3}
+//
+// Increment/decrement             %00fedcba
+// Bits used for volume descr.     $xx
+// Relative volume change, right   $xx xx (xx ...) // a
+// Relative volume change, left    $xx xx (xx ...) // b
+// Peak volume right               $xx xx (xx ...)
+// Peak volume left                $xx xx (xx ...)
+// Relative volume change, right back  $xx xx (xx ...) // c (optional, v2.3 only)
+// Relative volume change, left back   $xx xx (xx ...) // d
+// Peak volume right back          $xx xx (xx ...)
+// Peak volume left back           $xx xx (xx ...)
+// Relative volume change, center  $xx xx (xx ...) // e (optional, v2.3 only)
+// Peak volume center              $xx xx (xx ...)
+// Relative volume change, bass    $xx xx (xx ...) // f (optional, v2.3 only)
+// Peak volume bass                $xx xx (xx ...)
+//
+// A set increment/decrement bit means the volume of the channel is increased, otherwise
+// it is decreased.  Each volume field is (bits+7)/8 bytes long.
+func readRVADFrame(b []byte) (*RelativeVolume, error) {
+	if len(b) < 2 {
+		return nil, errors.New("invalid RVAD frame: expected increment/decrement and bits")
+	}
+	incr, bits := b[0], int(b[1])
+	if bits == 0 || bits > 64 {
+		return nil, fmt.Errorf("invalid RVAD frame: invalid number of bits: %d", bits)
+	}
+	n := (bits + 7) / 8
+	b = b[2:]
+
+	// next returns the next volume field, or false if there is none.
+	next := func() (uint64, bool) {
+		if len(b) < n {
+			return 0, false
+		}
+		var x uint64
+		for _, c := range b[:n] {
+			x = x<<8 | uint64(c)
+		}
+		b = b[n:]
+		return x, true
+	}
+	change := func(c *ChannelVolume, bit uint) {
+		x, _ := next()
+		c.Change = int64(x)
+		if incr&(1<<bit) == 0 {
+			c.Change = -c.Change
+		}
+	}
+	peak := func(c *ChannelVolume) {
+		c.Peak, _ = next()
+	}
+
+	if len(b) < 2*n {
+		return nil, errors.New("invalid RVAD frame: expected right and left volume change")
+	}
+	v := &RelativeVolume{Bits: bits}
+	change(&v.Right, 0)
+	change(&v.Left, 1)
+	peak(&v.Right)
+	peak(&v.Left)
+	change(&v.RightBack, 2)
+	change(&v.LeftBack, 3)
+	peak(&v.RightBack)
+	peak(&v.LeftBack)
+	change(&v.Center, 4)
+	peak(&v.Center)
+	change(&v.Bass, 5)
+	peak(&v.Bass)
+	return v, nil
+}
+
 var pictureTypes = map[byte]string{
 	0x00: "Other",
 	0x01: "32x32 pixels 'file icon' (PNG only)",
@@ -548,6 +764,12 @@ type Picture struct {
 	Type        string // Type of the picture (see pictureTypes).
 	Description string // Description.
 	Data        []byte // Raw picture data.
+
+	// Dimensions of the picture, where these are stored in the metadata (i.e. FLAC picture
+	// blocks), otherwise zero.
+	Width      int // Width in pixels.
+	Height     int // Height in pixels.
+	ColorDepth int // Color depth in bits per pixel.
 }
 
 // String returns a string representation of the underlying Picture instance.
@@ -556,6 +778,45 @@ func (p Picture) String() string {
 		p.Ext, p.MIMEType, p.Type, p.Description, len(p.Data))
 }
 
+// PictureInfo is a type which describes an attached picture without its data.
+type PictureInfo struct {
+	Type     string // Type of the picture (see pictureTypes).
+	MIMEType string // MIMEType of the picture.
+	Size     int    // Size of the picture data in bytes.
+}
+
+// numberedPictures returns the pictures stored in m under the key name, followed by
+// those stored under name_0, name_1, ... (as is done for repeated frames and atoms).
+func numberedPictures(m map[string]interface{}, name string) []*Picture {
+	var pictures []*Picture
+	k := name
+	for i := 0; ; i++ {
+		p, ok := m[k].(*Picture)
+		if !ok {
+			return pictures
+		}
+		pictures = append(pictures, p)
+		k = name + "_" + strconv.Itoa(i)
+	}
+}
+
+// summarizePictures returns a PictureInfo for each of the pictures, or nil if
+// there are none.
+func summarizePictures(pictures []*Picture) []PictureInfo {
+	if len(pictures) == 0 {
+		return nil
+	}
+	infos := make([]PictureInfo, 0, len(pictures))
+	for _, p := range pictures {
+		infos = append(infos, PictureInfo{
+			Type:     p.Type,
+			MIMEType: p.MIMEType,
+			Size:     len(p.Data),
+		})
+	}
+	return infos
+}
+
 // IDv2.2
 // -- Header
 // Attached picture   "PIC"
@@ -566,7 +827,7 @@ func (p Picture) String() string {
 // Picture type       $xx
 // Description        <textstring> $00 (00)
 // Picture data       <binary data>
-func readPICFrame(b []byte) (*Picture, error) {
+func readPICFrame(b []byte, charset charsetDecoder) (*Picture, error) {
 	if len(b) < 5 {
 		return nil, errors.New("invalid PIC frame")
 	}
@@ -579,9 +840,9 @@ func readPICFrame(b []byte) (*Picture, error) {
 	if len(descDataSplit) != 2 {
 		return nil, errors.New("error decoding PIC description text: invalid encoding")
 	}
-	desc, err := decodeText(enc, descDataSplit[0])
+	desc, err := decodeText(enc, descDataSplit[0], charset)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding PIC description text: %v", err)
+		return nil, fmt.Errorf("error decoding PIC description text: %w", err)
 	}
 
 	var mimeType string
@@ -610,7 +871,7 @@ func readPICFrame(b []byte) (*Picture, error) {
 // Picture type    $xx
 // Description     <text string according to encoding> $00 (00)
 // Picture data    <binary data>
-func readAPICFrame(b []byte) (*Picture, error) {
+func readAPICFrame(b []byte, charset charsetDecoder) (*Picture, error) {
 	if len(b) == 0 {
 		return nil, errors.New("error decoding APIC: invalid encoding")
 	}
@@ -632,9 +893,9 @@ func readAPICFrame(b []byte) (*Picture, error) {
 	if len(descDataSplit) != 2 {
 		return nil, errors.New("error decoding APIC description text: invalid encoding")
 	}
-	desc, err := decodeText(enc, descDataSplit[0])
+	desc, err := decodeText(enc, descDataSplit[0], charset)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding APIC description text: %v", err)
+		return nil, fmt.Errorf("error decoding APIC description text: %w", err)
 	}
 
 	var ext string
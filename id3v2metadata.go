@@ -5,6 +5,7 @@
 package tag
 
 import (
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -41,19 +42,42 @@ var frames = frameNames(map[string][2]string{
 	"year":         [2]string{"TYE", "TYER"},
 	"track":        [2]string{"TRK", "TRCK"},
 	"disc":         [2]string{"TPA", "TPOS"},
+	"set_subtitle": [2]string{"", "TSST"},
 	"genre":        [2]string{"TCO", "TCON"},
 	"picture":      [2]string{"PIC", "APIC"},
 	"lyrics":       [2]string{"", "USLT"},
 	"comment":      [2]string{"COM", "COMM"},
+	"isrc":         [2]string{"TRC", "TSRC"},
+	"compilation":  [2]string{"TCP", "TCMP"},
+	"audio_delay":  [2]string{"TDY", "TDLY"},
+	"key":          [2]string{"TKE", "TKEY"},
+	"mood":         [2]string{"", "TMOO"},
+	"size":         [2]string{"TSI", "TSIZ"},
+	"radio":        [2]string{"", "TRSN"},
+	"radio_owner":  [2]string{"", "TRSO"},
+	"rating":       [2]string{"POP", "POPM"},
+	"volume":       [2]string{"RVA", "RVAD"},
+	"bpm":          [2]string{"TBP", "TBPM"},
+	"user_text":    [2]string{"TXX", "TXXX"},
+	"copyright":    [2]string{"TCR", "TCOP"},
+	"produced":     [2]string{"", "TPRO"},
+	"owner":        [2]string{"", "TOWN"},
+	"work":         [2]string{"", "GRP1"},
+	"movement":     [2]string{"", "MVNM"},
+	"movement_no":  [2]string{"", "MVIN"},
 })
 
-// metadataID3v2 is the implementation of Metadata used for ID3v2 tags.
-type metadataID3v2 struct {
+// MetadataID3v2 is the implementation of Metadata used for ID3v2 tags.  The Metadata
+// returned by ReadID3v2Tags can be asserted to MetadataID3v2 to access ID3v2-specific
+// metadata.
+type MetadataID3v2 struct {
 	header *id3v2Header
 	frames map[string]interface{}
+	flags  map[string][2]byte
+	values map[string][]string // values of text frames with more than one
 }
 
-func (m metadataID3v2) getString(k string) string {
+func (m MetadataID3v2) getString(k string) string {
 	v, ok := m.frames[k]
 	if !ok {
 		return ""
@@ -61,69 +85,180 @@ func (m metadataID3v2) getString(k string) string {
 	return v.(string)
 }
 
-func (m metadataID3v2) Format() Format              { return m.header.Version }
-func (m metadataID3v2) FileType() FileType          { return MP3 }
-func (m metadataID3v2) Raw() map[string]interface{} { return m.frames }
+func (m MetadataID3v2) Format() Format              { return m.header.Version }
+func (m MetadataID3v2) FileType() FileType          { return MP3 }
+func (m MetadataID3v2) Raw() map[string]interface{} { return m.frames }
 
-func (m metadataID3v2) Title() string {
+// FrameFlags returns the two flag bytes from the header of the frame stored under name in
+// Raw(), so that frames (including those which are not parsed, stored as []byte) can be
+// written back unchanged.  Returns false if there is no such frame or the tag is ID3v2.2,
+// which has no frame flags.
+func (m MetadataID3v2) FrameFlags(name string) ([2]byte, bool) {
+	f, ok := m.flags[name]
+	return f, ok
+}
+
+func (m MetadataID3v2) Title() string {
 	return m.getString(frames.Name("title", m.Format()))
 }
 
-func (m metadataID3v2) Artist() string {
+func (m MetadataID3v2) Artist() string {
 	return m.getString(frames.Name("artist", m.Format()))
 }
 
-func (m metadataID3v2) Album() string {
+func (m MetadataID3v2) Album() string {
 	return m.getString(frames.Name("album", m.Format()))
 }
 
-func (m metadataID3v2) AlbumArtist() string {
+func (m MetadataID3v2) AlbumArtist() string {
 	return m.getString(frames.Name("album_artist", m.Format()))
 }
 
-func (m metadataID3v2) Composer() string {
+func (m MetadataID3v2) Composer() string {
 	return m.getString(frames.Name("composer", m.Format()))
 }
 
-func (m metadataID3v2) Genre() string {
-	return id3v2genre(m.getString(frames.Name("genre", m.Format())))
+func (m MetadataID3v2) Genre() string {
+	name := frames.Name("genre", m.Format())
+	if m.Format() != ID3v2_4 {
+		return id3v2genre(m.getString(name))
+	}
+
+	// ID3v2.4 genres can have more than one value, each of which is free text, a genre ID
+	// (without parentheses) or one of the refinements "RX" (Remix) and "CR" (Cover).
+	var genres []string
+	for _, v := range m.TextValues(name) {
+		switch v {
+		case "RX":
+			v = "Remix"
+		case "CR":
+			v = "Cover"
+		default:
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 && n < len(id3v2Genres) {
+				v = id3v2Genres[n]
+			} else {
+				v = id3v2genre(v)
+			}
+		}
+		if v != "" && !containsString(genres, v) {
+			genres = append(genres, v)
+		}
+	}
+	return strings.Join(genres, ";")
 }
 
-func (m metadataID3v2) Year() int {
-	stringYear := m.getString(frames.Name("year", m.Format()))
+// containsString returns true if s is one of values.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
 
-	if year, err := strconv.Atoi(stringYear); err == nil {
-		return year
+// TextValues returns the values of the text frame stored under name in Raw() (i.e.
+// "TPE1"), which can have more than one value in ID3v2.4.  The single-value accessors
+// (i.e. Artist) and Raw() return the values joined by ";".  Returns nil if there is no
+// such text frame.
+func (m MetadataID3v2) TextValues(name string) []string {
+	if v, ok := m.values[name]; ok {
+		return v
+	}
+	s, ok := m.frames[name].(string)
+	if !ok || s == "" {
+		return nil
 	}
+	return []string{s}
+}
 
-	date, err := time.Parse(time.DateOnly, stringYear)
-	if err != nil {
-		return 0
+func (m MetadataID3v2) Year() int {
+	return parseID3v2Year(m.Date())
+}
+
+// Date returns the recording date (TDRC in ID3v2.4, otherwise TYER) as it appears in
+// the tag, which may be only a year or incomplete.
+func (m MetadataID3v2) Date() string {
+	return strings.TrimSpace(m.getString(frames.Name("year", m.Format())))
+}
+
+// parseID3v2Year returns the year from the date s, which should be a year or an ID3v2.4
+// timestamp.  Malformed dates (i.e. "2006/01/02") use the first four digit number in s,
+// and dates without a year (i.e. "--01-02" or "T15:04") return 0.
+func parseID3v2Year(s string) int {
+	if year, err := strconv.Atoi(s); err == nil {
+		return year
+	}
+	if t := parseID3v2Timestamp(s); !t.IsZero() {
+		return t.Year()
 	}
 
-	return date.Year()
+	isDigit := func(i int) bool { return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9' }
+	for i := 0; i+4 <= len(s); i++ {
+		if isDigit(i-1) || isDigit(i+4) || !isDigit(i) || !isDigit(i+1) || !isDigit(i+2) || !isDigit(i+3) {
+			continue
+		}
+		year, _ := strconv.Atoi(s[i : i+4])
+		return year
+	}
+	return 0
 }
 
 func parseXofN(s string) (x, n int) {
+	p := parsePosition(s)
+	return p.Number, p.Total
+}
+
+// parsePosition parses a position of the form "x" or "x/n", where HasTotal is set if n
+// is a number.
+func parsePosition(s string) Position {
 	xn := strings.Split(s, "/")
 	if len(xn) != 2 {
-		x, _ = strconv.Atoi(s)
-		return x, 0
+		x, _ := strconv.Atoi(s)
+		return Position{Number: x}
 	}
-	x, _ = strconv.Atoi(strings.TrimSpace(xn[0]))
-	n, _ = strconv.Atoi(strings.TrimSpace(xn[1]))
-	return x, n
+	x, _ := strconv.Atoi(strings.TrimSpace(xn[0]))
+	n, err := strconv.Atoi(strings.TrimSpace(xn[1]))
+	return Position{Number: x, Total: n, HasTotal: err == nil}
 }
 
-func (m metadataID3v2) Track() (int, int) {
+// parseBPM parses a tempo in beats per minute.  The tempo should be an integer, but
+// fractional values (i.e. "120.5") are rounded to the nearest integer.
+func parseBPM(s string) int {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Round(f))
+}
+
+func (m MetadataID3v2) Track() (int, int) {
 	return parseXofN(m.getString(frames.Name("track", m.Format())))
 }
 
-func (m metadataID3v2) Disc() (int, int) {
+func (m MetadataID3v2) Disc() (int, int) {
 	return parseXofN(m.getString(frames.Name("disc", m.Format())))
 }
 
-func (m metadataID3v2) Lyrics() string {
+func (m MetadataID3v2) TrackPosition() Position {
+	return parsePosition(m.getString(frames.Name("track", m.Format())))
+}
+
+func (m MetadataID3v2) DiscPosition() Position {
+	return parsePosition(m.getString(frames.Name("disc", m.Format())))
+}
+
+// DiscSubtitle returns the set subtitle (TSST).  TSST was added in ID3v2.4, but is also
+// written to ID3v2.3 tags by some taggers.
+func (m MetadataID3v2) DiscSubtitle() string {
+	return m.getString(frames.Name("set_subtitle", m.Format()))
+}
+
+func (m MetadataID3v2) Lyrics() string {
 	t, ok := m.frames[frames.Name("lyrics", m.Format())]
 	if !ok {
 		return ""
@@ -131,22 +266,364 @@ func (m metadataID3v2) Lyrics() string {
 	return t.(*Comm).Text
 }
 
-func (m metadataID3v2) Comment() string {
-	t, ok := m.frames[frames.Name("comment", m.Format())]
-	if !ok {
-		return ""
-	}
-	// id3v23 has Text, id3v24 has Description
-	if t.(*Comm).Description == "" {
-		return trimString(t.(*Comm).Text)
+func (m MetadataID3v2) Comment() string {
+	name := frames.Name("comment", m.Format())
+	for i := -1; ; i++ {
+		k := name
+		if i >= 0 {
+			k = name + "_" + strconv.Itoa(i)
+		}
+		t, ok := m.frames[k]
+		if !ok {
+			return ""
+		}
+		c, ok := t.(*Comm)
+		if !ok || isITunesComment(c) {
+			continue
+		}
+		// id3v23 has Text, id3v24 has Description
+		if c.Description == "" {
+			return trimString(c.Text)
+		}
+		return trimString(c.Description)
 	}
-	return trimString(t.(*Comm).Description)
 }
 
-func (m metadataID3v2) Picture() *Picture {
+// isITunesComment returns true if the COMM frame is used by iTunes to store data
+// (i.e. "iTunNORM", "iTunSMPB") rather than a user comment.
+func isITunesComment(c *Comm) bool {
+	return strings.HasPrefix(c.Description, "iTun")
+}
+
+func (m MetadataID3v2) Picture() *Picture {
 	v, ok := m.frames[frames.Name("picture", m.Format())]
 	if !ok {
 		return nil
 	}
 	return v.(*Picture)
 }
+
+// Pictures returns the pictures from each of the attached picture (APIC or PIC) frames.
+func (m MetadataID3v2) Pictures() []*Picture {
+	return numberedPictures(m.frames, frames.Name("picture", m.Format()))
+}
+
+func (m MetadataID3v2) ISRC() string {
+	return m.getString(frames.Name("isrc", m.Format()))
+}
+
+// CatalogNumber returns the release catalog number (the TXXX frame with description
+// CATALOGNUMBER, as written by MusicBrainz Picard), or "" if unavailable.
+func (m MetadataID3v2) CatalogNumber() string {
+	return m.userText("CATALOGNUMBER")
+}
+
+// Barcode returns the release barcode, i.e. the UPC or EAN (the TXXX frame with
+// description BARCODE, as written by MusicBrainz Picard), or "" if unavailable.
+func (m MetadataID3v2) Barcode() string {
+	return m.userText("BARCODE")
+}
+
+// ReplayGain returns the loudness normalization values from the TXXX (ID3v2.2 TXX) frames
+// with descriptions replaygain_track_gain, replaygain_track_peak, replaygain_album_gain and
+// replaygain_album_peak (in any case), as written by foobar2000 and MusicBrainz Picard.
+//
+// If there is no track gain frame, the track gain is the average of the front right and
+// left volume changes of the ID3v2.3 RVAD (ID3v2.2 RVA) frame, which older files use.  RVAD
+// peaks are not used, as the specification does not define their scale.  If there is no
+// RVAD frame either, the track gain (and the track peak, if there is no track peak frame)
+// is taken from the iTunes Sound Check values (see SoundCheck).
+func (m MetadataID3v2) ReplayGain() ReplayGainInfo {
+	rg := ReplayGainInfo{
+		TrackGain: parseReplayGain(m.userText("replaygain_track_gain")),
+		TrackPeak: parseReplayGain(m.userText("replaygain_track_peak")),
+		AlbumGain: parseReplayGain(m.userText("replaygain_album_gain")),
+		AlbumPeak: parseReplayGain(m.userText("replaygain_album_peak")),
+	}
+	if m.userText("replaygain_track_gain") == "" {
+		if v := m.RelativeVolume(); v != nil {
+			rg.TrackGain = (v.Right.Gain(v.Bits) + v.Left.Gain(v.Bits)) / 2
+		} else if sc := m.SoundCheck(); sc != nil {
+			rg.TrackGain = sc.Gain()
+			if m.userText("replaygain_track_peak") == "" {
+				rg.TrackPeak = sc.Peak()
+			}
+		}
+	}
+	return rg
+}
+
+// userText returns the text of the TXXX (ID3v2.2 TXX) frame with description desc
+// (compared case-insensitively), or "" if there is none.
+func (m MetadataID3v2) userText(desc string) string {
+	c := m.findComm(frames.Name("user_text", m.Format()), func(c *Comm) bool {
+		return strings.EqualFold(c.Description, desc)
+	})
+	if c == nil {
+		return ""
+	}
+	return c.Text
+}
+
+// findComm returns the first of the frames name, name_0, name_1, etc. (in the order they
+// appear in the tag) which is a *Comm for which match returns true, or nil if there is
+// none.
+func (m MetadataID3v2) findComm(name string, match func(*Comm) bool) *Comm {
+	for i := -1; ; i++ {
+		k := name
+		if i >= 0 {
+			k = name + "_" + strconv.Itoa(i)
+		}
+		t, ok := m.frames[k]
+		if !ok {
+			return nil
+		}
+		if c, ok := t.(*Comm); ok && match(c) {
+			return c
+		}
+	}
+}
+
+// RelativeVolume returns the relative volume adjustment from the ID3v2.3 RVAD (ID3v2.2
+// RVA) frame, or nil if there is none.
+func (m MetadataID3v2) RelativeVolume() *RelativeVolume {
+	v, _ := m.frames[frames.Name("volume", m.Format())].(*RelativeVolume)
+	return v
+}
+
+func (m MetadataID3v2) BPM() int {
+	return parseBPM(m.getString(frames.Name("bpm", m.Format())))
+}
+
+func (m MetadataID3v2) Compilation() bool {
+	return m.getString(frames.Name("compilation", m.Format())) == "1"
+}
+
+// id3v2TimestampLayouts are the layouts of ID3v2.4 timestamps, which can have
+// any of the precisions yyyy, yyyy-MM, yyyy-MM-dd, yyyy-MM-ddTHH, yyyy-MM-ddTHH:mm
+// and yyyy-MM-ddTHH:mm:ss.
+var id3v2TimestampLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// parseID3v2Timestamp parses the ID3v2.4 timestamp s, returning the zero time.Time
+// if it is not a valid timestamp.
+func parseID3v2Timestamp(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range id3v2TimestampLayouts {
+		if len(s) != len(layout) {
+			continue
+		}
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// EncodingTime returns the time the audio was encoded (TDEN, ID3v2.4 only), or the
+// zero time.Time if unavailable.
+func (m MetadataID3v2) EncodingTime() time.Time {
+	return parseID3v2Timestamp(m.getString("TDEN"))
+}
+
+// TaggingTime returns the time the tag was written (TDTG, ID3v2.4 only), or the zero
+// time.Time if unavailable.
+func (m MetadataID3v2) TaggingTime() time.Time {
+	return parseID3v2Timestamp(m.getString("TDTG"))
+}
+
+// AudioDelay returns the delay (TDLY) between the end of the previous track and the
+// start of this one, or 0 if unavailable.
+func (m MetadataID3v2) AudioDelay() time.Duration {
+	ms, err := strconv.Atoi(strings.TrimSpace(m.getString(frames.Name("audio_delay", m.Format()))))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Key returns the initial key of the track (TKEY), and true if it is a valid ID3v2 key:
+// a ground key "A" to "G", optionally followed by a half key "b" or "#" and then "m" for
+// minor (i.e. "Dbm"), or "o" for off key.  Valid keys are returned with the ground key in
+// upper case.  Other values (i.e. the Camelot "8A" or "C major") are returned unchanged
+// with false.
+func (m MetadataID3v2) Key() (string, bool) {
+	s := strings.TrimSpace(m.getString(frames.Name("key", m.Format())))
+	if k, ok := parseID3v2Key(s); ok {
+		return k, true
+	}
+	return s, false
+}
+
+// parseID3v2Key returns the normalised key s, and true if it is a valid ID3v2 key.
+func parseID3v2Key(s string) (string, bool) {
+	if s == "o" {
+		return s, true
+	}
+	if s == "" || len(s) > 3 {
+		return "", false
+	}
+
+	k := strings.ToUpper(s[:1])
+	if k < "A" || k > "G" {
+		return "", false
+	}
+	rest := s[1:]
+	if len(rest) > 0 && (rest[0] == 'b' || rest[0] == '#') {
+		k += rest[:1]
+		rest = rest[1:]
+	}
+	if rest == "m" {
+		k += rest
+		rest = ""
+	}
+	if rest != "" {
+		return "", false
+	}
+	return k, true
+}
+
+// Mood returns the mood of the track (TMOO, ID3v2.4 only), or "" if unavailable.
+func (m MetadataID3v2) Mood() string {
+	return strings.TrimSpace(m.getString(frames.Name("mood", m.Format())))
+}
+
+// Work returns the name of the work (i.e. a symphony) that the track is part of (GRP1,
+// as written by iTunes), or "" if unavailable.
+func (m MetadataID3v2) Work() string {
+	return m.getString(frames.Name("work", m.Format()))
+}
+
+// Movement returns the movement name (MVNM), and the movement number and count (MVIN,
+// i.e. "2/4"), or zero values if unavailable.
+func (m MetadataID3v2) Movement() (name string, index, count int) {
+	p := parsePosition(m.getString(frames.Name("movement_no", m.Format())))
+	return m.getString(frames.Name("movement", m.Format())), p.Number, p.Total
+}
+
+// Copyright returns the copyright message (TCOP, ID3v2.2 TCR), i.e. "2000 Test Label", or
+// "" if unavailable.
+func (m MetadataID3v2) Copyright() string {
+	return strings.TrimSpace(m.getString(frames.Name("copyright", m.Format())))
+}
+
+// ProducedNotice returns the production copyright (℗) message (TPRO, ID3v2.4 only), or ""
+// if unavailable.
+func (m MetadataID3v2) ProducedNotice() string {
+	return strings.TrimSpace(m.getString(frames.Name("produced", m.Format())))
+}
+
+// FileOwner returns the owner or licensee of the file (TOWN), or "" if unavailable.
+func (m MetadataID3v2) FileOwner() string {
+	return strings.TrimSpace(m.getString(frames.Name("owner", m.Format())))
+}
+
+// RadioStation returns the name of the internet radio station the audio was broadcast
+// from (TRSN), or "" if unavailable.
+func (m MetadataID3v2) RadioStation() string {
+	return strings.TrimSpace(m.getString(frames.Name("radio", m.Format())))
+}
+
+// RadioStationOwner returns the owner of the internet radio station (TRSO), or "" if
+// unavailable.
+func (m MetadataID3v2) RadioStationOwner() string {
+	return strings.TrimSpace(m.getString(frames.Name("radio_owner", m.Format())))
+}
+
+// AudioSize returns the size of the audio data in bytes (TSIZ, deprecated in ID3v2.4),
+// or 0 if unavailable.
+func (m MetadataID3v2) AudioSize() int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(m.getString(frames.Name("size", m.Format()))), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Popularimeter returns the first popularimeter (POPM) frame in the tag, or nil if there
+// is none.
+func (m MetadataID3v2) Popularimeter() *Popularimeter {
+	p, _ := m.frames[frames.Name("rating", m.Format())].(*Popularimeter)
+	return p
+}
+
+// Rating returns the rating of the first popularimeter (POPM) frame on a scale of 1 to 5
+// stars, and true if there is a rating.  POPM ratings are stored in a byte (1-255, with 0
+// meaning unknown), and are mapped to stars using the same ranges as Windows Media Player
+// and most other players, which write 1, 64, 128, 196 and 255 for 1 to 5 stars:
+//
+//	1-31    1 star
+//	32-95   2 stars
+//	96-159  3 stars
+//	160-223 4 stars
+//	224-255 5 stars
+func (m MetadataID3v2) Rating() (int, bool) {
+	p := m.Popularimeter()
+	if p == nil || p.Rating == 0 {
+		return 0, false
+	}
+	return popmStars(p.Rating), true
+}
+
+// popmStars returns the number of stars (1-5) for the POPM rating r (1-255).
+func popmStars(r int) int {
+	switch {
+	case r < 32:
+		return 1
+	case r < 96:
+		return 2
+	case r < 160:
+		return 3
+	case r < 224:
+		return 4
+	}
+	return 5
+}
+
+// PictureSummary returns the type, MIME type and size of each attached picture (APIC
+// or PIC frame) in the order they appear in the tag, or nil if there are none.
+func (m MetadataID3v2) PictureSummary() []PictureInfo {
+	return summarizePictures(m.Pictures())
+}
+
+// SoundCheck returns the iTunes Sound Check normalization values from the COMM frame
+// with description "iTunNORM", or nil if unavailable.
+func (m MetadataID3v2) SoundCheck() *SoundCheck {
+	c := m.iTunesComment("iTunNORM")
+	if c == nil {
+		return nil
+	}
+	sc, err := ParseSoundCheck(c.Text)
+	if err != nil {
+		return nil
+	}
+	return &sc
+}
+
+// Gapless returns the iTunes gapless playback values from the COMM frame with description
+// "iTunSMPB", or nil if unavailable.
+func (m MetadataID3v2) Gapless() *Gapless {
+	c := m.iTunesComment("iTunSMPB")
+	if c == nil {
+		return nil
+	}
+	g, err := ParseGapless(c.Text)
+	if err != nil {
+		return nil
+	}
+	return &g
+}
+
+// iTunesComment returns the first COMM frame with description desc (i.e. "iTunNORM"), or
+// nil if there is none.
+func (m MetadataID3v2) iTunesComment(desc string) *Comm {
+	return m.findComm(frames.Name("comment", m.Format()), func(c *Comm) bool {
+		return c.Description == desc
+	})
+}
@@ -4,7 +4,14 @@
 
 package tag
 
-import "testing"
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
 
 func TestParseXofN(t *testing.T) {
 	table := []struct {
@@ -27,3 +34,530 @@ func TestParseXofN(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePosition(t *testing.T) {
+	table := []struct {
+		str  string
+		want Position
+	}{
+		{"", Position{}},
+		{"1", Position{Number: 1}},
+		{"1/2", Position{Number: 1, Total: 2, HasTotal: true}},
+		{"1 / 0", Position{Number: 1, Total: 0, HasTotal: true}},
+		{"1/", Position{Number: 1}},
+		{"/2", Position{Total: 2, HasTotal: true}},
+	}
+
+	for ii, tt := range table {
+		got := parsePosition(tt.str)
+		if got != tt.want {
+			t.Errorf("[%d] parsePosition(%v) = %+v, expected: %+v", ii, tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestParseID3v2Timestamp(t *testing.T) {
+	table := []struct {
+		str  string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"2019", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2019-03", time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2019-03-04", time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"2019-03-04T05", time.Date(2019, 3, 4, 5, 0, 0, 0, time.UTC)},
+		{"2019-03-04T05:06", time.Date(2019, 3, 4, 5, 6, 0, 0, time.UTC)},
+		{"2019-03-04T05:06:07", time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)},
+		{"2019-3-4", time.Time{}},
+		{"not a date", time.Time{}},
+	}
+
+	for ii, tt := range table {
+		if got := parseID3v2Timestamp(tt.str); !got.Equal(tt.want) {
+			t.Errorf("[%d] parseID3v2Timestamp(%q) = %v, expected: %v", ii, tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestID3v2Timestamps(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "with_tags", "sample.timestamps.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	id3, ok := m.(MetadataID3v2)
+	if !ok {
+		t.Fatalf("ReadFrom() returned %T, expected MetadataID3v2", m)
+	}
+
+	if got, want := id3.EncodingTime(), time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("EncodingTime() = %v, expected: %v", got, want)
+	}
+	if got, want := id3.TaggingTime(), time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("TaggingTime() = %v, expected: %v", got, want)
+	}
+	if got, want := id3.AudioDelay(), 250*time.Millisecond; got != want {
+		t.Errorf("AudioDelay() = %v, expected: %v", got, want)
+	}
+}
+
+func TestParseID3v2Year(t *testing.T) {
+	table := []struct {
+		str  string
+		year int
+	}{
+		{"", 0},
+		{"2000", 2000},
+		{"2000-05", 2000},
+		{"2000-05-24", 2000},
+		{"2000-05-24T13:45", 2000},
+		{"--05-24", 0},
+		{"T13:45", 0},
+		{"-05-24T13:45:00", 0},
+		{"2000/05/24", 2000},
+		{"24.05.2000", 2000},
+		{"12345", 12345},
+		{"+123-", 0},
+		{"year 20000", 0},
+	}
+
+	for ii, tt := range table {
+		if got := parseID3v2Year(tt.str); got != tt.year {
+			t.Errorf("[%d] parseID3v2Year(%q) = %d, expected: %d", ii, tt.str, got, tt.year)
+		}
+	}
+}
+
+func TestID3v2PartialDates(t *testing.T) {
+	tests := []struct {
+		path string
+		date string
+		year int
+	}{
+		{"sample.tdrc-noyear.mp3", "--05-24", 0},
+		{"sample.tdrc-time.mp3", "T13:45", 0},
+		{"sample.tdrc-slash.mp3", "2000/05/24", 2000},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+
+		id3 := m.(MetadataID3v2)
+		if got := id3.Date(); got != tt.date {
+			t.Errorf("%v: Date() = %q, expected: %q", tt.path, got, tt.date)
+		}
+		if got := id3.Year(); got != tt.year {
+			t.Errorf("%v: Year() = %d, expected: %d", tt.path, got, tt.year)
+		}
+	}
+}
+
+func TestParseID3v2Key(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		ok  bool
+	}{
+		{"C", "C", true},
+		{"Cm", "Cm", true},
+		{"A#", "A#", true},
+		{"Dbm", "Dbm", true},
+		{"f#m", "F#m", true},
+		{"o", "o", true},
+		{"", "", false},
+		{"H", "", false},
+		{"8A", "", false},
+		{"Cmb", "", false},
+		{"C major", "", false},
+	}
+
+	for _, tt := range tests {
+		out, ok := parseID3v2Key(tt.in)
+		if out != tt.out || ok != tt.ok {
+			t.Errorf("parseID3v2Key(%q) = %q, %v, expected: %q, %v", tt.in, out, ok, tt.out, tt.ok)
+		}
+	}
+}
+
+func TestID3v2KeyMood(t *testing.T) {
+	tests := []struct {
+		path string
+		key  string
+		ok   bool
+		mood string
+	}{
+		{"sample.tkey.mp3", "Dbm", true, "Energetic"},
+		{"sample.tkey-raw.mp3", "8A", false, ""},
+		{"sample.tkey-offkey.mp3", "o", true, ""},
+		{"sample.id3v24.mp3", "", false, ""},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+
+		id3 := m.(MetadataID3v2)
+		if key, ok := id3.Key(); key != tt.key || ok != tt.ok {
+			t.Errorf("%v: Key() = %q, %v, expected: %q, %v", tt.path, key, ok, tt.key, tt.ok)
+		}
+		if got := id3.Mood(); got != tt.mood {
+			t.Errorf("%v: Mood() = %q, expected: %q", tt.path, got, tt.mood)
+		}
+	}
+}
+
+func TestID3v2RadioStation(t *testing.T) {
+	tests := []struct {
+		path      string
+		station   string
+		owner     string
+		audioSize int64
+	}{
+		{"sample.radio.mp3", "Test Radio", "Test Owner", 55170},
+		{"sample.radio.id3v24.mp3", "Test Radio", "Test Owner", 0},
+		{"sample.id3v23.mp3", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+
+		id3 := m.(MetadataID3v2)
+		if got := id3.RadioStation(); got != tt.station {
+			t.Errorf("%v: RadioStation() = %q, expected: %q", tt.path, got, tt.station)
+		}
+		if got := id3.RadioStationOwner(); got != tt.owner {
+			t.Errorf("%v: RadioStationOwner() = %q, expected: %q", tt.path, got, tt.owner)
+		}
+		if got := id3.AudioSize(); got != tt.audioSize {
+			t.Errorf("%v: AudioSize() = %d, expected: %d", tt.path, got, tt.audioSize)
+		}
+	}
+}
+
+func TestID3v2Rights(t *testing.T) {
+	tests := []struct {
+		path      string
+		copyright string
+		produced  string
+		owner     string
+	}{
+		{"sample.rights.id3v24.mp3", "2000 Test Label", "2000 Test Records", "Test Owner"},
+		{"sample.rights.mp3", "2000 Test Label", "", "Test Owner"}, // TPRO is ID3v2.4 only
+		{"sample.id3v23.mp3", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+
+		id3 := m.(MetadataID3v2)
+		if got := id3.Copyright(); got != tt.copyright {
+			t.Errorf("%v: Copyright() = %q, expected: %q", tt.path, got, tt.copyright)
+		}
+		if got := id3.ProducedNotice(); got != tt.produced {
+			t.Errorf("%v: ProducedNotice() = %q, expected: %q", tt.path, got, tt.produced)
+		}
+		if got := id3.FileOwner(); got != tt.owner {
+			t.Errorf("%v: FileOwner() = %q, expected: %q", tt.path, got, tt.owner)
+		}
+	}
+}
+
+func TestID3v2TextValues(t *testing.T) {
+	tests := []struct {
+		path   string
+		artist string
+		values []string
+		genre  string
+	}{
+		{"sample.multivalue.id3v24.mp3", "Artist A;Artist B", []string{"Artist A", "Artist B"}, "Rock;Eurodisco;Remix"},
+		{"sample.multivalue.utf16.id3v24.mp3", "Artist A;Artist B", []string{"Artist A", "Artist B"}, "Jazz"},
+		{"sample.id3v24.mp3", "Test Artist", []string{"Test Artist"}, "Jazz"},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+
+		id3 := m.(MetadataID3v2)
+		if got := id3.Artist(); got != tt.artist {
+			t.Errorf("%v: Artist() = %q, expected: %q", tt.path, got, tt.artist)
+		}
+		if got := id3.TextValues("TPE1"); !reflect.DeepEqual(got, tt.values) {
+			t.Errorf("%v: TextValues(\"TPE1\") = %q, expected: %q", tt.path, got, tt.values)
+		}
+		if got := id3.Genre(); got != tt.genre {
+			t.Errorf("%v: Genre() = %q, expected: %q", tt.path, got, tt.genre)
+		}
+		if got := id3.TextValues("TXXX"); got != nil {
+			t.Errorf("%v: TextValues(\"TXXX\") = %q, expected: nil", tt.path, got)
+		}
+	}
+}
+
+func TestID3v2Rating(t *testing.T) {
+	tests := []struct {
+		path  string
+		popm  *Popularimeter
+		stars int
+		ok    bool
+	}{
+		{"sample.popm.mp3", &Popularimeter{Email: "Windows Media Player 9 Series", Rating: 196, Counter: 1234}, 4, true},
+		{"sample.popm.id3v24.mp3", &Popularimeter{Email: "rater@example.com", Rating: 1}, 1, true},
+		{"sample.id3v23.mp3", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+
+		id3 := m.(MetadataID3v2)
+		if got := id3.Popularimeter(); !reflect.DeepEqual(got, tt.popm) {
+			t.Errorf("%v: Popularimeter() = %v, expected: %v", tt.path, got, tt.popm)
+		}
+		stars, ok := id3.Rating()
+		if stars != tt.stars || ok != tt.ok {
+			t.Errorf("%v: Rating() = %d, %v, expected: %d, %v", tt.path, stars, ok, tt.stars, tt.ok)
+		}
+	}
+}
+
+func TestPOPMStars(t *testing.T) {
+	tests := map[int]int{
+		1: 1, 31: 1, 32: 2, 64: 2, 95: 2, 96: 3, 128: 3, 159: 3, 160: 4, 196: 4, 223: 4, 224: 5, 255: 5,
+	}
+	for r, want := range tests {
+		if got := popmStars(r); got != want {
+			t.Errorf("popmStars(%d) = %d, expected: %d", r, got, want)
+		}
+	}
+}
+
+func TestReadPOPMFrame(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want *Popularimeter
+	}{
+		{[]byte("a@b\x00\x80"), &Popularimeter{Email: "a@b", Rating: 128}},
+		{[]byte("\x00\xff\x00\x00\x01\x00"), &Popularimeter{Rating: 255, Counter: 256}},
+		{[]byte("\x00\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00"), &Popularimeter{Rating: 1, Counter: math.MaxUint64}},
+	}
+	for _, tt := range tests {
+		got, err := readPOPMFrame(tt.b)
+		if err != nil {
+			t.Errorf("readPOPMFrame(%q) = %v", tt.b, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readPOPMFrame(%q) = %v, expected: %v", tt.b, got, tt.want)
+		}
+	}
+
+	for _, b := range [][]byte{nil, []byte("a@b"), []byte("a@b\x00")} {
+		if _, err := readPOPMFrame(b); err == nil {
+			t.Errorf("readPOPMFrame(%q) expected error", b)
+		}
+	}
+}
+
+func TestID3v2RelativeVolume(t *testing.T) {
+	// sample.rvad.mp3 has an RVAD frame which increases both front channels by half.
+	f, err := os.Open(filepath.Join("testdata", "with_tags", "sample.rvad.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id3 := m.(MetadataID3v2)
+	want := &RelativeVolume{
+		Bits:  16,
+		Right: ChannelVolume{Change: 32768, Peak: 30000},
+		Left:  ChannelVolume{Change: 32768, Peak: 31000},
+	}
+	if got := id3.RelativeVolume(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RelativeVolume() = %#v, expected: %#v", got, want)
+	}
+	rg := id3.ReplayGain()
+	if math.Abs(rg.TrackGain-3.5218) > 0.0001 || rg.TrackPeak != 0 || rg.AlbumGain != 0 {
+		t.Errorf("ReplayGain() = %+v, expected: TrackGain 3.5218", rg)
+	}
+}
+
+func TestID3v2ReplayGain(t *testing.T) {
+	tests := map[string]ReplayGainInfo{
+		"sample.replaygain.mp3":              {TrackGain: -6.54, TrackPeak: 0.988547, AlbumGain: -7.03, AlbumPeak: 1},
+		"sample.replaygain-upper.id3v24.mp3": {TrackGain: 2.1, TrackPeak: 0.5},
+		"sample.id3v24.mp3":                  {},
+	}
+	for path, want := range tests {
+		f, err := os.Open(filepath.Join("testdata", "with_tags", path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		r, ok := m.(ReplayGainMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement ReplayGainMetadata", path, m)
+			continue
+		}
+		if got := r.ReplayGain(); got != want {
+			t.Errorf("%v: ReplayGain() = %+v, expected: %+v", path, got, want)
+		}
+	}
+}
+
+func TestID3v2UserTextOrder(t *testing.T) {
+	// Descriptions are compared case-insensitively, so with duplicates the first frame
+	// in the tag is used.
+	m := MetadataID3v2{
+		header: &id3v2Header{Version: ID3v2_4},
+		frames: map[string]interface{}{
+			"TXXX":   &Comm{Description: "CATALOGNUMBER", Text: "TC-1"},
+			"TXXX_0": &Comm{Description: "REPLAYGAIN_TRACK_GAIN", Text: "-6.54 dB"},
+			"TXXX_1": &Comm{Description: "catalognumber", Text: "TC-2"},
+			"TXXX_2": &Comm{Description: "replaygain_track_gain", Text: "+2.10 dB"},
+		},
+	}
+	for i := 0; i < 100; i++ {
+		if got := m.CatalogNumber(); got != "TC-1" {
+			t.Fatalf("CatalogNumber() = %q, expected: %q", got, "TC-1")
+		}
+		if got := m.ReplayGain().TrackGain; got != -6.54 {
+			t.Fatalf("ReplayGain().TrackGain = %v, expected: %v", got, -6.54)
+		}
+	}
+}
+
+func TestReadRVADFrame(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want *RelativeVolume
+	}{
+		// ID3v2.2 RVA: right increment, left decrement, 8 bits.
+		{
+			[]byte{0x01, 8, 0x40, 0x80, 0xf0, 0xe0},
+			&RelativeVolume{Bits: 8, Right: ChannelVolume{64, 0xf0}, Left: ChannelVolume{-128, 0xe0}},
+		},
+		// ID3v2.3 RVAD with all channels, 12 bits (2 bytes each).
+		{
+			[]byte{0x2a, 12,
+				0, 1, 0, 2, 0, 3, 0, 4, // right, left
+				0, 5, 0, 6, 0, 7, 0, 8, // right back, left back
+				0, 9, 0, 10, // center
+				0, 11, 0, 12, // bass
+			},
+			&RelativeVolume{
+				Bits:      12,
+				Right:     ChannelVolume{-1, 3},
+				Left:      ChannelVolume{2, 4},
+				RightBack: ChannelVolume{-5, 7},
+				LeftBack:  ChannelVolume{6, 8},
+				Center:    ChannelVolume{-9, 10},
+				Bass:      ChannelVolume{11, 12},
+			},
+		},
+		// Peaks omitted.
+		{
+			[]byte{0x03, 16, 0x00, 0x10, 0x00, 0x20},
+			&RelativeVolume{Bits: 16, Right: ChannelVolume{Change: 16}, Left: ChannelVolume{Change: 32}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := readRVADFrame(tt.b)
+		if err != nil {
+			t.Errorf("readRVADFrame(%x) = %v", tt.b, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readRVADFrame(%x) = %#v, expected: %#v", tt.b, got, tt.want)
+		}
+	}
+
+	for _, b := range [][]byte{nil, {0x03}, {0x03, 0}, {0x03, 16, 0, 1}} {
+		if _, err := readRVADFrame(b); err == nil {
+			t.Errorf("readRVADFrame(%x) expected error", b)
+		}
+	}
+}
+
+func TestChannelVolumeGain(t *testing.T) {
+	tests := []struct {
+		c    ChannelVolume
+		bits int
+		want float64
+	}{
+		{ChannelVolume{}, 16, 0},
+		{ChannelVolume{Change: 32768}, 16, 3.5218},
+		{ChannelVolume{Change: -32768}, 16, -6.0206},
+		{ChannelVolume{Change: -256}, 8, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Gain(tt.bits); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("%+v.Gain(%d) = %v, expected: %v", tt.c, tt.bits, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,640 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// id3v2Padding is the number of bytes of padding written after the frames of a new (or
+// grown) ID3v2 tag, so that it can later be changed without moving the audio data.
+const id3v2Padding = 2048
+
+// id3v2MaxSize is the largest ID3v2 tag (or frame) size, which is stored as a 28 bit
+// synchsafe integer.
+const id3v2MaxSize = 1<<28 - 1
+
+// ID3v2Tag is a type which contains the values written to an ID3v2.4 tag by WriteTo and
+// Save.  Use NewID3v2Tag to copy the values from existing Metadata, and then change them.
+// Zero values are not written.
+type ID3v2Tag struct {
+	Title        string
+	Album        string
+	Artist       string
+	AlbumArtist  string
+	Composer     string
+	Genre        string
+	Year         int
+	Track        int
+	TrackTotal   int
+	Disc         int
+	DiscTotal    int
+	DiscSubtitle string
+	Comment      string
+	Lyrics       string
+	ISRC         string
+	Compilation  bool
+	Pictures     []*Picture
+
+	src *id3v2Source
+}
+
+// id3v2Source is the ID3v2 tag an ID3v2Tag was created from, and the values copied from
+// it (see NewID3v2Tag).
+type id3v2Source struct {
+	m      MetadataID3v2
+	values ID3v2Tag
+}
+
+// NewID3v2Tag returns an ID3v2Tag with the values from m.
+//
+// If m is an ID3v2 tag (MetadataID3v2), the other frames of the tag (i.e. TXXX, UFID or
+// frames which are not parsed) are also kept, along with their status flags, and only the
+// frames for the values which are changed are replaced.  ID3v2.2 and ID3v2.3 frames which
+// have no ID3v2.4 equivalent (i.e. RVAD, TORY), and compressed, encrypted or grouped frames
+// (which cannot be written back as they were read), are not kept.  The recording time (TDRC) of an ID3v2.4 tag is
+// kept if the Year is not changed, otherwise only the year is written.
+func NewID3v2Tag(m Metadata) *ID3v2Tag {
+	t := &ID3v2Tag{
+		Title:        m.Title(),
+		Album:        m.Album(),
+		Artist:       m.Artist(),
+		AlbumArtist:  m.AlbumArtist(),
+		Composer:     m.Composer(),
+		Genre:        m.Genre(),
+		Year:         m.Year(),
+		DiscSubtitle: discSubtitle(m),
+		Comment:      m.Comment(),
+		Lyrics:       m.Lyrics(),
+		ISRC:         isrc(m),
+		Compilation:  compilation(m),
+	}
+	if p, ok := m.(PicturesMetadata); ok {
+		t.Pictures = p.Pictures()
+	} else if p := m.Picture(); p != nil {
+		t.Pictures = []*Picture{p}
+	}
+	t.Track, t.TrackTotal = m.Track()
+	t.Disc, t.DiscTotal = m.Disc()
+
+	if id3, ok := m.(MetadataID3v2); ok {
+		values := *t
+		values.Pictures = append([]*Picture(nil), t.Pictures...)
+		t.src = &id3v2Source{m: id3, values: values}
+	}
+	return t
+}
+
+// WriteID3v2Tags writes the values from m as an ID3v2.4 tag (see NewID3v2Tag) to w.
+func WriteID3v2Tags(w io.Writer, m Metadata) error {
+	_, err := NewID3v2Tag(m).WriteTo(w)
+	return err
+}
+
+// WriteTo writes the tag to w, followed by padding.  Text is written in UTF-8.
+func (t *ID3v2Tag) WriteTo(w io.Writer) (int64, error) {
+	frames, err := t.frames()
+	if err != nil {
+		return 0, err
+	}
+	return writeID3v2Tag(w, frames, id3v2Padding)
+}
+
+// Save replaces the ID3v2 tag at the start of rw (i.e. an MP3 file), or inserts the tag
+// if there is none.  If the new tag is no larger than the existing one, the difference
+// is written as padding, otherwise the audio data is moved to make room for the tag (and
+// padding).  NB: rw cannot be truncated, so the file never shrinks.
+func (t *ID3v2Tag) Save(rw io.ReadWriteSeeker) error {
+	frames, err := t.frames()
+	if err != nil {
+		return err
+	}
+
+	_, err = rw.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	oldSize, err := id3v2TagSize(rw)
+	if err != nil {
+		return err
+	}
+
+	size := int64(10 + len(frames))
+	padding := oldSize - size
+	if padding < 0 {
+		padding = id3v2Padding
+		err = moveToEnd(rw, oldSize, size+padding)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = rw.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = writeID3v2Tag(rw, frames, int(padding))
+	return err
+}
+
+// id3v2TagSize returns the size of the ID3v2 tag (including the header and any footer)
+// at the start of r, or 0 if there is no tag.
+func id3v2TagSize(r io.ReadSeeker) (int64, error) {
+	b := make([]byte, 3)
+	_, err := io.ReadFull(r, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if string(b) != "ID3" {
+		return 0, nil
+	}
+
+	_, err = r.Seek(-3, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	h, _, err := readID3v2Header(r)
+	if err != nil {
+		return 0, err
+	}
+	return h.tagSize(), nil
+}
+
+// moveToEnd moves the data in rw from offset from to the end, to start at offset to
+// (which must be greater than from).  The data is copied from the end backwards, so that
+// it is not overwritten before it is read.
+func moveToEnd(rw io.ReadWriteSeeker, from, to int64) error {
+	end, err := rw.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64<<10)
+	for pos := end; pos > from; {
+		n := int64(len(buf))
+		if pos-from < n {
+			n = pos - from
+		}
+		pos -= n
+
+		_, err = rw.Seek(pos, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadFull(rw, buf[:n])
+		if err != nil {
+			return err
+		}
+		_, err = rw.Seek(pos+to-from, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		_, err = rw.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeID3v2Tag writes an ID3v2.4 tag header, the frames and padding bytes of padding.
+func writeID3v2Tag(w io.Writer, frames []byte, padding int) (int64, error) {
+	size := len(frames) + padding
+	if size > id3v2MaxSize {
+		return 0, fmt.Errorf("ID3v2 tag too large: %d bytes", size)
+	}
+
+	// "ID3", version <8>, revision <8>, flags <8>, size <32> (synchsafe)
+	b := append([]byte("ID3\x04\x00\x00"), synchsafe(size)...)
+	b = append(b, frames...)
+	b = append(b, make([]byte, padding)...)
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// frames returns the ID3v2.4 frames for the values in t, after the frames kept from the
+// source tag (see NewID3v2Tag).
+func (t *ID3v2Tag) frames() ([]byte, error) {
+	b, err := t.sourceFrames()
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(id string, data []byte) {
+		if err != nil || t.keep(id) {
+			return
+		}
+		b, err = appendID3v2Frame(b, id, [2]byte{}, data)
+	}
+	text := func(id, s string) {
+		if s != "" {
+			add(id, append([]byte{id3v2EncodingUTF8}, s...))
+		}
+	}
+	number := func(id string, x, n int) {
+		switch {
+		case n > 0:
+			text(id, strconv.Itoa(x)+"/"+strconv.Itoa(n))
+		case x > 0:
+			text(id, strconv.Itoa(x))
+		}
+	}
+	comm := func(id, s string) {
+		// Text encoding, language, empty description, text
+		if s != "" {
+			add(id, append([]byte{id3v2EncodingUTF8, 'e', 'n', 'g', 0}, s...))
+		}
+	}
+
+	text("TIT2", t.Title)
+	text("TALB", t.Album)
+	text("TPE1", t.Artist)
+	text("TPE2", t.AlbumArtist)
+	text("TCOM", t.Composer)
+	text("TCON", t.Genre)
+	if t.Year > 0 {
+		text("TDRC", fmt.Sprintf("%04d", t.Year))
+	}
+	number("TRCK", t.Track, t.TrackTotal)
+	number("TPOS", t.Disc, t.DiscTotal)
+	text("TSST", t.DiscSubtitle)
+	comm("COMM", t.Comment)
+	comm("USLT", t.Lyrics)
+	text("TSRC", t.ISRC)
+	if t.Compilation {
+		text("TCMP", "1")
+	}
+	for _, p := range t.Pictures {
+		add("APIC", apicFrameData(p))
+	}
+	return b, err
+}
+
+// keep returns true if the frame id is kept from the source tag rather than written
+// from the values of t, because the value it holds has not been changed.  ID3v2.3 date
+// frames are never kept, as the year is written to TDRC, and nor are the other frames
+// which were removed in ID3v2.4.
+func (t *ID3v2Tag) keep(id string) bool {
+	if t.src == nil {
+		return false
+	}
+	v := &t.src.values
+	switch id {
+	case "TIT2":
+		return t.Title == v.Title
+	case "TALB":
+		return t.Album == v.Album
+	case "TPE1":
+		return t.Artist == v.Artist
+	case "TPE2":
+		return t.AlbumArtist == v.AlbumArtist
+	case "TCOM":
+		return t.Composer == v.Composer
+	case "TCON":
+		return t.Genre == v.Genre
+	case "TDRC":
+		return t.Year == v.Year && t.src.m.Format() == ID3v2_4
+	case "TYER", "TDAT", "TIME":
+		return false
+	case "TORY", "TRDA", "TSIZ", "IPLS", "RVAD", "EQUA":
+		// Removed in ID3v2.4 (replaced by TDOR, TDRC, TIPL, RVA2 and EQU2).
+		return false
+	case "TRCK":
+		return t.Track == v.Track && t.TrackTotal == v.TrackTotal
+	case "TPOS":
+		return t.Disc == v.Disc && t.DiscTotal == v.DiscTotal
+	case "TSST":
+		return t.DiscSubtitle == v.DiscSubtitle
+	case "COMM":
+		return t.Comment == v.Comment
+	case "USLT":
+		return t.Lyrics == v.Lyrics
+	case "TSRC":
+		return t.ISRC == v.ISRC
+	case "TCMP":
+		return t.Compilation == v.Compilation
+	case "APIC":
+		return reflect.DeepEqual(t.Pictures, v.Pictures)
+	}
+	return true
+}
+
+// sourceFrames returns the frames kept from the source tag as ID3v2.4 frames, in the order
+// of their names in Raw().
+func (t *ID3v2Tag) sourceFrames() ([]byte, error) {
+	if t.src == nil {
+		return nil, nil
+	}
+	m := t.src.m
+
+	keys := make([]string, 0, len(m.frames))
+	for k := range m.frames {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ni, xi := splitID3v2FrameKey(keys[i])
+		nj, xj := splitID3v2FrameKey(keys[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return xi < xj
+	})
+
+	var b []byte
+	for _, k := range keys {
+		name, _ := splitID3v2FrameKey(k)
+		id := name
+		if m.Format() == ID3v2_2 {
+			id = id3v22FrameIDs[name]
+		}
+		if id == "" {
+			continue
+		}
+
+		// iTunes data stored in COMM frames is not the comment, so is always kept.
+		if c, ok := m.frames[k].(*Comm); !t.keep(id) && !(id == "COMM" && ok && isITunesComment(c)) {
+			continue
+		}
+
+		flags, ok := id3v24FrameFlags(m, k)
+		if !ok {
+			continue
+		}
+		data, ok := id3v2FrameData(m, k, id)
+		if !ok {
+			continue
+		}
+		var err error
+		b, err = appendID3v2Frame(b, id, flags, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// splitID3v2FrameKey returns the frame name and index of the key k of Raw(), where the
+// frames after the first with the same name have keys of the form name_0, name_1, etc.
+// The index of the first frame is -1.
+func splitID3v2FrameKey(k string) (string, int) {
+	name, x, ok := strings.Cut(k, "_")
+	if !ok {
+		return k, -1
+	}
+	i, err := strconv.Atoi(x)
+	if err != nil {
+		return k, -1
+	}
+	return name, i
+}
+
+// id3v24FrameFlags returns the ID3v2.4 frame flags for the frame stored under k in m,
+// keeping only the status flags.  Returns false if the frame data is compressed,
+// encrypted or grouped, and so was not read, or if the frame is not parsed and should
+// be discarded when the tag is changed.
+func id3v24FrameFlags(m MetadataID3v2, k string) ([2]byte, bool) {
+	raw, ok := m.FrameFlags(k)
+	if !ok {
+		return [2]byte{}, true
+	}
+	var f *id3v2FrameFlags
+	if m.Format() == ID3v2_3 {
+		f, _ = readID3v23FrameFlags(bytes.NewReader(raw[:]))
+	} else {
+		f, _ = readID3v24FrameFlags(bytes.NewReader(raw[:]))
+	}
+	if f.Compression || f.Encryption || f.GroupIdentity {
+		return [2]byte{}, false
+	}
+	if _, unknown := m.frames[k].([]byte); unknown && f.TagAlterPreservation {
+		return [2]byte{}, false
+	}
+
+	var status byte
+	if f.FileAlterPreservation {
+		status |= 0x20
+	}
+	if f.ReadOnly {
+		status |= 0x10
+	}
+	return [2]byte{status, 0}, true
+}
+
+// id3v2FrameData returns the data of the ID3v2.4 frame id for the value stored under k
+// in m (see readID3v2Frames).  Text is written in UTF-8.  Returns false if the value
+// cannot be written as an ID3v2.4 frame.
+func id3v2FrameData(m MetadataID3v2, k, id string) ([]byte, bool) {
+	switch v := m.frames[k].(type) {
+	case string:
+		if id[0] == 'W' {
+			return latin1(v), true
+		}
+		return append([]byte{id3v2EncodingUTF8}, strings.Join(m.TextValues(k), "\x00")...), true
+
+	case *Comm:
+		b := []byte{id3v2EncodingUTF8}
+		switch id {
+		case "COMM", "USLT":
+			lang := v.Language
+			if len(lang) != 3 {
+				lang = "XXX"
+			}
+			b = append(b, lang...)
+
+		case "WXXX":
+			b = append(append(b, v.Description...), 0)
+			return append(b, latin1(v.Text)...), true
+		}
+		b = append(append(b, v.Description...), 0)
+		return append(b, v.Text...), true
+
+	case *UFID:
+		return append(append([]byte(v.Provider), 0), v.Identifier...), true
+
+	case *Link:
+		// ID3v2.2 and ID3v2.3 LINK frames refer to frames by their 3 character IDs.
+		if len(v.FrameID) != 4 {
+			return nil, false
+		}
+		b := append(append([]byte(v.FrameID), v.URL...), 0)
+		return append(b, v.AdditionalData...), true
+
+	case *Popularimeter:
+		b := append(append([]byte(v.Email), 0), byte(v.Rating))
+		n := 4
+		for n < 8 && v.Counter>>(8*n) != 0 {
+			n++
+		}
+		for i := n - 1; i >= 0; i-- {
+			b = append(b, byte(v.Counter>>(8*i)))
+		}
+		return b, true
+
+	case *RelativeVolume:
+		return rvadFrameData(v), true
+
+	case *Picture:
+		return apicFrameData(v), true
+
+	case []byte:
+		return v, true
+	}
+	return nil, false
+}
+
+// rvadFrameData returns the data of an RVAD frame for v (see readRVADFrame).  The optional
+// channels are written up to the last one which is set.
+func rvadFrameData(v *RelativeVolume) []byte {
+	channels := []*ChannelVolume{&v.Right, &v.Left, &v.RightBack, &v.LeftBack, &v.Center, &v.Bass}
+	n := 2
+	for i := 2; i < len(channels); i++ {
+		if *channels[i] != (ChannelVolume{}) {
+			n = i + 1
+		}
+	}
+	if n == 3 {
+		// The back channels are written together.
+		n = 4
+	}
+
+	var incr byte
+	for i, c := range channels[:n] {
+		if c.Change > 0 {
+			incr |= 1 << i
+		}
+	}
+	b := []byte{incr, byte(v.Bits)}
+	field := func(x uint64) {
+		for i := (v.Bits+7)/8 - 1; i >= 0; i-- {
+			b = append(b, byte(x>>(8*i)))
+		}
+	}
+	change := func(c *ChannelVolume) {
+		if c.Change < 0 {
+			field(uint64(-c.Change))
+		} else {
+			field(uint64(c.Change))
+		}
+	}
+
+	change(&v.Right)
+	change(&v.Left)
+	field(v.Right.Peak)
+	field(v.Left.Peak)
+	if n > 2 {
+		change(&v.RightBack)
+		change(&v.LeftBack)
+		field(v.RightBack.Peak)
+		field(v.LeftBack.Peak)
+	}
+	if n > 4 {
+		change(&v.Center)
+		field(v.Center.Peak)
+	}
+	if n > 5 {
+		change(&v.Bass)
+		field(v.Bass.Peak)
+	}
+	return b
+}
+
+// latin1 returns s encoded in ISO-8859-1, with characters which cannot be encoded
+// replaced by '?'.
+func latin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+// id3v22FrameIDs maps ID3v2.2 frame IDs to their ID3v2.3 and ID3v2.4 equivalents.
+var id3v22FrameIDs = map[string]string{
+	"BUF": "RBUF", "CNT": "PCNT", "COM": "COMM", "CRA": "AENC", "ETC": "ETCO",
+	"EQU": "EQUA", "GEO": "GEOB", "IPL": "IPLS", "LNK": "LINK", "MCI": "MCDI",
+	"MLL": "MLLT", "PIC": "APIC", "POP": "POPM", "REV": "RVRB", "RVA": "RVAD",
+	"SLT": "SYLT", "STC": "SYTC", "TAL": "TALB", "TBP": "TBPM", "TCM": "TCOM",
+	"TCO": "TCON", "TCR": "TCOP", "TDA": "TDAT", "TDY": "TDLY", "TEN": "TENC",
+	"TFT": "TFLT", "TIM": "TIME", "TKE": "TKEY", "TLA": "TLAN", "TLE": "TLEN",
+	"TMT": "TMED", "TOA": "TOPE", "TOF": "TOFN", "TOL": "TOLY", "TOR": "TORY",
+	"TOT": "TOAL", "TP1": "TPE1", "TP2": "TPE2", "TP3": "TPE3", "TP4": "TPE4",
+	"TPA": "TPOS", "TPB": "TPUB", "TRC": "TSRC", "TRD": "TRDA", "TRK": "TRCK",
+	"TSI": "TSIZ", "TSS": "TSSE", "TT1": "TIT1", "TT2": "TIT2", "TT3": "TIT3",
+	"TXT": "TEXT", "TXX": "TXXX", "TYE": "TYER", "UFI": "UFID", "ULT": "USLT",
+	"WAF": "WOAF", "WAR": "WOAR", "WAS": "WOAS", "WCM": "WCOM", "WCP": "WCOP",
+	"WPB": "WPUB", "WXX": "WXXX",
+
+	// iTunes
+	"TCP": "TCMP", "TS2": "TSO2", "TSA": "TSOA", "TSC": "TSOC", "TSP": "TSOP",
+	"TST": "TSOT", "GP1": "GRP1", "MVN": "MVNM", "MVI": "MVIN",
+}
+
+// id3v2EncodingUTF8 is the ID3v2.4 text encoding byte for UTF-8.
+const id3v2EncodingUTF8 = 3
+
+// appendID3v2Frame appends an ID3v2.4 frame with the given id, flags and data to b.
+// Frame ID  <4 bytes>
+// Size      <32> (synchsafe)
+// Flags     <16>
+func appendID3v2Frame(b []byte, id string, flags [2]byte, data []byte) ([]byte, error) {
+	if len(data) > id3v2MaxSize {
+		return nil, fmt.Errorf("ID3v2 %v frame too large: %d bytes", id, len(data))
+	}
+	b = append(b, id...)
+	b = append(b, synchsafe(len(data))...)
+	b = append(b, flags[:]...)
+	return append(b, data...), nil
+}
+
+// apicFrameData returns the data of an APIC frame for the picture p (see readAPICFrame).
+// The MIME type is taken from the data if p does not have one.
+func apicFrameData(p *Picture) []byte {
+	mimeType := p.MIMEType
+	if mimeType == "" {
+		mimeType, _ = sniffImageType(p.Data)
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(id3v2EncodingUTF8)
+	b.WriteString(mimeType)
+	b.WriteByte(0)
+	b.WriteByte(pictureTypeByte(p.Type))
+	b.WriteString(p.Description)
+	b.WriteByte(0)
+	b.Write(p.Data)
+	return b.Bytes()
+}
+
+// pictureTypeByte returns the picture type byte for the picture type name s (see
+// pictureTypes): the front cover if s is empty, or other if s is not recognised.
+func pictureTypeByte(s string) byte {
+	if s == "" {
+		return 0x03
+	}
+	for k, v := range pictureTypes {
+		if v == s {
+			return k
+		}
+	}
+	return 0x00
+}
+
+// synchsafe returns n as a 4 byte synchsafe integer (7 bits per byte, see
+// get7BitChunkedInt).
+func synchsafe(n int) []byte {
+	return []byte{byte(n>>21) & 0x7f, byte(n>>14) & 0x7f, byte(n>>7) & 0x7f, byte(n) & 0x7f}
+}
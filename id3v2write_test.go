@@ -0,0 +1,270 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// saveID3v2Tag copies the file at path to a temporary file, calls update with the tag read
+// from it, saves the tag and returns the resulting file.
+func saveID3v2Tag(t *testing.T, path string, update func(*ID3v2Tag)) (original, saved []byte) {
+	t.Helper()
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(t.TempDir(), filepath.Base(path))
+	err = os.WriteFile(tmp, original, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := &ID3v2Tag{}
+	if m, err := ReadFrom(bytes.NewReader(original)); err == nil {
+		tag = NewID3v2Tag(m)
+	}
+	update(tag)
+
+	f, err := os.OpenFile(tmp, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tag.Save(f)
+	if err != nil {
+		t.Fatalf("Save() returned unexpected error: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err = os.ReadFile(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return original, saved
+}
+
+// audioAfterID3v2 returns the data which follows the ID3v2 tag in b.
+func audioAfterID3v2(t *testing.T, b []byte) []byte {
+	t.Helper()
+	size, err := id3v2TagSize(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b[size:]
+}
+
+func TestID3v2TagRoundTrip(t *testing.T) {
+	for _, path := range []string{
+		"testdata/with_tags/sample.id3v23.mp3",
+		"testdata/with_tags/sample.id3v24.mp3",
+	} {
+		original, saved := saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+			tag.Title = "New Title"
+		})
+
+		m, err := ReadFrom(bytes.NewReader(saved))
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		if m.Format() != ID3v2_4 {
+			t.Errorf("%v: Format() = %v, expected: %v", path, m.Format(), ID3v2_4)
+		}
+		want := fullMetadata
+		want.Title = "New Title"
+		compareMetadata(t, m, want)
+
+		if !bytes.Equal(audioAfterID3v2(t, saved), audioAfterID3v2(t, original)) {
+			t.Errorf("%v: audio data was not preserved", path)
+		}
+	}
+}
+
+func TestID3v2TagSaveGrowShrink(t *testing.T) {
+	path := "testdata/with_tags/sample.id3v24.mp3"
+	picture := &Picture{
+		MIMEType:    "image/png",
+		Type:        "Cover (back)",
+		Description: "Back",
+		Data:        append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0xff}, 100000)...),
+	}
+
+	original, grown := saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+		tag.Pictures = append(tag.Pictures, picture)
+	})
+	if len(grown) <= len(original) {
+		t.Errorf("grown file is %d bytes, expected more than %d", len(grown), len(original))
+	}
+	if !bytes.Equal(audioAfterID3v2(t, grown), audioAfterID3v2(t, original)) {
+		t.Errorf("audio data was not preserved when growing the tag")
+	}
+	m, err := ReadFrom(bytes.NewReader(grown))
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	pictures := m.(MetadataID3v2).Pictures()
+	if len(pictures) == 0 {
+		t.Fatalf("Pictures() returned no pictures")
+	}
+	got := pictures[len(pictures)-1]
+	if got.Type != picture.Type || got.Description != picture.Description || !bytes.Equal(got.Data, picture.Data) {
+		t.Errorf("Pictures() = %v, expected to end with: %v", pictures, picture)
+	}
+
+	// Saving a smaller tag over the grown one pads it out to the same size.
+	tmp := filepath.Join(t.TempDir(), "grown.mp3")
+	err = os.WriteFile(tmp, grown, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, shrunk := saveID3v2Tag(t, tmp, func(tag *ID3v2Tag) {
+		tag.Pictures = nil
+	})
+	if len(shrunk) != len(grown) {
+		t.Errorf("shrunk file is %d bytes, expected: %d", len(shrunk), len(grown))
+	}
+	if !bytes.Equal(audioAfterID3v2(t, shrunk), audioAfterID3v2(t, original)) {
+		t.Errorf("audio data was not preserved when shrinking the tag")
+	}
+	m, err = ReadFrom(bytes.NewReader(shrunk))
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	if p := m.(MetadataID3v2).Pictures(); len(p) != 0 {
+		t.Errorf("Pictures() = %v, expected none", p)
+	}
+	compareMetadata(t, m, fullMetadata)
+}
+
+func TestID3v2TagSaveWithoutTag(t *testing.T) {
+	path := "testdata/without_tags/sample.mp3"
+	original, saved := saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+		tag.Title = "Test Title"
+		tag.Track, tag.TrackTotal = 3, 6
+	})
+
+	if !bytes.Equal(audioAfterID3v2(t, saved), original) {
+		t.Errorf("audio data was not preserved")
+	}
+	m, err := ReadID3v2Tags(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("ReadID3v2Tags() returned unexpected error: %v", err)
+	}
+	compareMetadata(t, m, testMetadata{Title: "Test Title", Track: 3, TrackTotal: 6})
+}
+
+func TestWriteID3v2Tags(t *testing.T) {
+	for _, path := range []string{
+		"testdata/with_tags/sample.id3v24.mp3",
+		"testdata/with_tags/sample.flac",
+		"testdata/with_tags/sample.m4a",
+		"testdata/with_tags/sample.pictures.mp3",
+	} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		err = WriteID3v2Tags(&buf, want)
+		if err != nil {
+			t.Errorf("%v: WriteID3v2Tags() returned unexpected error: %v", path, err)
+			continue
+		}
+		m, err := ReadID3v2Tags(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%v: ReadID3v2Tags() returned unexpected error: %v", path, err)
+			continue
+		}
+
+		// Compare the values, not the source tags.
+		got, expected := *NewID3v2Tag(m), *NewID3v2Tag(want)
+		got.src, expected.src = nil, nil
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%v: read back %+v, expected: %+v", path, got, expected)
+		}
+	}
+}
+
+func TestID3v2TagSaveKeepsFrames(t *testing.T) {
+	// sample.frames.id3v24.mp3 has a full recording time (TDRC), a TXXX frame, a UFID
+	// frame and a PRIV frame (which is not parsed) with the file alter preservation flag.
+	path := "testdata/with_tags/sample.frames.id3v24.mp3"
+	_, saved := saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+		tag.Title = "New Title"
+	})
+
+	m, err := ReadFrom(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	want := fullMetadata
+	want.Title = "New Title"
+	compareMetadata(t, m, want)
+
+	id3 := m.(MetadataID3v2)
+	testValue(t, "2000-01-02T10:30", id3.Date())
+	testValue(t, "TC-1", id3.CatalogNumber())
+	if got, want := id3.Raw()["UFID"], (&UFID{Provider: "http://musicbrainz.org", Identifier: []byte("abc-123")}); !reflect.DeepEqual(got, want) {
+		t.Errorf("UFID = %v, expected: %v", got, want)
+	}
+	if got, want := id3.Raw()["PRIV"], []byte("com.example\x00\x01\x02\x03"); !reflect.DeepEqual(got, want) {
+		t.Errorf("PRIV = %v, expected: %v", got, want)
+	}
+	if flags, _ := id3.FrameFlags("PRIV"); flags != [2]byte{0x20, 0x00} {
+		t.Errorf("FrameFlags(%q) = %x, expected: %x", "PRIV", flags, [2]byte{0x20, 0x00})
+	}
+
+	// Changing the year replaces the recording time.
+	_, saved = saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+		tag.Year = 2001
+	})
+	m, err = ReadFrom(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	testValue(t, "2001", m.(MetadataID3v2).Date())
+	testValue(t, "TC-1", m.(MetadataID3v2).CatalogNumber())
+}
+
+func TestID3v2TagSaveDropsID3v23Frames(t *testing.T) {
+	// Frames which were removed in ID3v2.4 must not be kept from ID3v2.2 and ID3v2.3 tags.
+	// sample.rvad.mp3 has an RVAD frame.
+	for _, path := range []string{
+		"testdata/with_tags/sample.id3v22.mp3",
+		"testdata/with_tags/sample.id3v23.mp3",
+		"testdata/with_tags/sample.rvad.mp3",
+	} {
+		_, saved := saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+			tag.Title = "New Title"
+		})
+		m, err := ReadFrom(bytes.NewReader(saved))
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		if m.Format() != ID3v2_4 {
+			t.Errorf("%v: Format() = %v, expected: %v", path, m.Format(), ID3v2_4)
+		}
+		for k := range m.Raw() {
+			switch name, _ := splitID3v2FrameKey(k); name {
+			case "TYER", "TDAT", "TIME", "TORY", "TRDA", "TSIZ", "IPLS", "RVAD", "EQUA":
+				t.Errorf("%v: saved ID3v2.4 tag has ID3v2.3 frame %v", path, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		path     string
+		format   Format
+		fileType FileType
+	}{
+		{"with_tags/sample.flac", VORBIS, FLAC},
+		{"with_tags/sample.ogg", VORBIS, OGG},
+		{"with_tags/sample.oga", VORBIS, FLAC},
+		{"with_tags/sample.r128.opus", VORBIS, OPUS},
+		{"with_tags/sample.spx", VORBIS, OGG},
+		{"with_tags/sample.m4a", MP4, M4A},
+		{"with_tags/sample.credits.m4b", MP4, M4B},
+		{"with_tags/sample.alac.m4a", MP4, M4A}, // ALAC is only identified from the tracks
+		{"with_tags/sample.boundary.mp3", ID3v2_2, MP3},
+		{"with_tags/sample.id3v23.mp3", ID3v2_3, MP3},
+		{"with_tags/sample.id3v24.mp3", ID3v2_4, MP3},
+		{"with_tags/sample.id3v11.mp3", ID3v1, MP3},
+		{"with_tags/sample.dsf", ID3v2_4, DSF},
+		{"with_tags/sample.id3.aiff", UnknownFormat, AIFF},
+		{"with_tags/sample.text.aifc", UnknownFormat, AIFC},
+		{"with_tags/sample.info.wav", UnknownFormat, WAV},
+		{"with_tags/sample.ape", APEv2, APE},
+		{"with_tags/sample.wv", APEv2, WV},
+		{"with_tags/sample.wma", ASF, WMA},
+	}
+
+	for _, tt := range tests {
+		b, err := os.ReadFile("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := bytes.NewReader(b)
+		format, fileType, err := Identify(r)
+		if err != nil || format != tt.format || fileType != tt.fileType {
+			t.Errorf("%v: Identify() = %v, %v, %v, expected: %v, %v, <nil>", tt.path, format, fileType, err, tt.format, tt.fileType)
+		}
+		if pos, _ := r.Seek(0, io.SeekCurrent); pos != 0 {
+			t.Errorf("%v: Identify() left reader at %d, expected: 0", tt.path, pos)
+		}
+
+		m, err := ReadFrom(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+		if tt.format != UnknownFormat && m.Format() != format {
+			t.Errorf("%v: Identify() format %v, but ReadFrom() format %v", tt.path, format, m.Format())
+		}
+	}
+
+	b, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = Identify(bytes.NewReader(b))
+	if !errors.Is(err, ErrNoTagsFound) {
+		t.Errorf("Identify() returned error %v, expected: %v", err, ErrNoTagsFound)
+	}
+}
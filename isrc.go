@@ -0,0 +1,55 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ISRCInfo is the decomposition of an International Standard Recording Code
+// (ISO 3901) into its component parts.
+type ISRCInfo struct {
+	Country     string // Country code (ISO 3166-1 alpha-2).
+	Registrant  string // Registrant code (3 alphanumeric characters).
+	Year        string // Year of reference (last 2 digits).
+	Designation string // Designation code (5 digits).
+}
+
+// String returns the ISRC in its hyphenated display form, i.e. CC-XXX-YY-NNNNN.
+func (i ISRCInfo) String() string {
+	return fmt.Sprintf("%v-%v-%v-%v", i.Country, i.Registrant, i.Year, i.Designation)
+}
+
+// ParseISRC parses the ISRC code s (with or without hyphens) into its component parts,
+// returning a non-nil error if s is not a valid 12 character ISRC.
+func ParseISRC(s string) (ISRCInfo, error) {
+	code := strings.ToUpper(strings.Replace(strings.TrimSpace(s), "-", "", -1))
+	if len(code) != 12 {
+		return ISRCInfo{}, fmt.Errorf("invalid ISRC %q: expected 12 characters, got %d", s, len(code))
+	}
+
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		isDigit := '0' <= c && c <= '9'
+		isLetter := 'A' <= c && c <= 'Z'
+
+		switch {
+		case i < 2 && !isLetter:
+			return ISRCInfo{}, fmt.Errorf("invalid ISRC %q: country code must be letters", s)
+		case i >= 2 && i < 5 && !isLetter && !isDigit:
+			return ISRCInfo{}, fmt.Errorf("invalid ISRC %q: registrant code must be alphanumeric", s)
+		case i >= 5 && !isDigit:
+			return ISRCInfo{}, fmt.Errorf("invalid ISRC %q: year and designation code must be digits", s)
+		}
+	}
+
+	return ISRCInfo{
+		Country:     code[0:2],
+		Registrant:  code[2:5],
+		Year:        code[5:7],
+		Designation: code[7:12],
+	}, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "testing"
+
+func TestParseISRC(t *testing.T) {
+	table := []struct {
+		str  string
+		info ISRCInfo
+	}{
+		{"USS1Z9900001", ISRCInfo{"US", "S1Z", "99", "00001"}},
+		{"US-S1Z-99-00001", ISRCInfo{"US", "S1Z", "99", "00001"}},
+		{"gbaye0601498", ISRCInfo{"GB", "AYE", "06", "01498"}},
+		{" FR-Z03-14-00123 ", ISRCInfo{"FR", "Z03", "14", "00123"}},
+	}
+
+	for ii, tt := range table {
+		got, err := ParseISRC(tt.str)
+		if err != nil {
+			t.Errorf("[%d] ParseISRC(%q) returned unexpected error: %v", ii, tt.str, err)
+			continue
+		}
+		if got != tt.info {
+			t.Errorf("[%d] ParseISRC(%q) = %#v, expected: %#v", ii, tt.str, got, tt.info)
+		}
+	}
+}
+
+func TestParseISRCInvalid(t *testing.T) {
+	table := []string{
+		"",
+		"USS1Z990000",   // too short
+		"USS1Z99000012", // too long
+		"1SS1Z9900001",  // numeric country code
+		"USS_Z9900001",  // non-alphanumeric registrant
+		"USS1ZA900001",  // non-numeric year
+		"USS1Z990000X",  // non-numeric designation
+	}
+
+	for ii, s := range table {
+		if got, err := ParseISRC(s); err == nil {
+			t.Errorf("[%d] ParseISRC(%q) = %#v, expected error", ii, s, got)
+		}
+	}
+}
+
+func TestISRCInfoString(t *testing.T) {
+	info := ISRCInfo{"US", "S1Z", "99", "00001"}
+	if got, want := info.String(), "US-S1Z-99-00001"; got != want {
+		t.Errorf("String() = %q, expected: %q", got, want)
+	}
+}
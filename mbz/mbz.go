@@ -39,6 +39,13 @@ var tags = map[string]string{
 	TRM:               "MusicBrainz TRM Id",
 }
 
+// Vorbis comment names which differ from the internal Picard tag names.  In particular
+// MUSICBRAINZ_TRACKID is the recording ID, not the release track ID.
+var vorbisTags = map[string]string{
+	"musicbrainz_trackid":        Recording,
+	"musicbrainz_releasetrackid": Track,
+}
+
 // Info is a structure which contains MusicBrainz identifier information.
 type Info map[string]string
 
@@ -91,9 +98,9 @@ func extractID3(m tag.Metadata) Info {
 	return i
 }
 
-// extractMP4Vorbis attempts to extract MusicBrainz Picard tags from m.Raw(), where m.Format
-// is assumed to be MP4 or VORBIS.
-func extractMP4Vorbis(m tag.Metadata) Info {
+// extractMP4 attempts to extract MusicBrainz Picard tags from m.Raw(), where m.Format
+// is assumed to be MP4 (or another format using the Picard tag names or aliases).
+func extractMP4(m tag.Metadata) Info {
 	i := Info{}
 	for t, v := range m.Raw() {
 		if s, ok := v.(string); ok {
@@ -103,12 +110,33 @@ func extractMP4Vorbis(m tag.Metadata) Info {
 	return i
 }
 
+// extractVorbis attempts to extract MusicBrainz Picard tags from m.Raw(), where m.Format
+// is assumed to be VORBIS.
+func extractVorbis(m tag.Metadata) Info {
+	i := Info{}
+	for t, v := range m.Raw() {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if tt, ok := vorbisTags[t]; ok {
+			i[tt] = s
+			continue
+		}
+		i.set(t, s)
+	}
+	return i
+}
+
 // Extract tags created by MusicBrainz Picard which can be used with with the MusicBrainz and LastFM APIs.
 // See https://picard.musicbrainz.org/docs/mappings/ for more information.
 func Extract(m tag.Metadata) Info {
 	switch m.Format() {
 	case tag.ID3v2_2, tag.ID3v2_3, tag.ID3v2_4:
 		return extractID3(m)
+
+	case tag.VORBIS:
+		return extractVorbis(m)
 	}
-	return extractMP4Vorbis(m)
+	return extractMP4(m)
 }
@@ -0,0 +1,44 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mbz
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+func TestExtractTrackRecording(t *testing.T) {
+	const (
+		recording = "5a6b7c8d-0000-4000-8000-00000000000a"
+		track     = "1f2e3d4c-0000-4000-8000-00000000000b"
+	)
+
+	for _, path := range []string{
+		"sample.mbz.mp3",  // UFID and TXXX:MusicBrainz Release Track Id
+		"sample.mbz.flac", // MUSICBRAINZ_TRACKID and MUSICBRAINZ_RELEASETRACKID
+		"sample.mbz.m4a",  // ----:com.apple.iTunes:MusicBrainz Track Id and Release Track Id
+	} {
+		f, err := os.Open("../testdata/with_tags/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := tag.ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+
+		i := Extract(m)
+		if got := i.Get(Recording); got != recording {
+			t.Errorf("%v: Get(Recording) = %q, expected: %q", path, got, recording)
+		}
+		if got := i.Get(Track); got != track {
+			t.Errorf("%v: Get(Track) = %q, expected: %q", path, got, track)
+		}
+	}
+}
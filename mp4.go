@@ -10,16 +10,23 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// ErrInvalidMP4 is the error returned (wrapped) by ReadAtoms when the MP4 atoms are
+// invalid (i.e. an atom size which is too small, or a malformed data atom).
+var ErrInvalidMP4 = errors.New("invalid MP4")
+
 var atomTypes = map[int]string{
 	0:  "implicit", // automatic based on atom name
 	1:  "text",
 	13: "jpeg",
 	14: "png",
 	21: "uint8",
+	27: "bmp",
 }
 
 // NB: atoms does not include "----", this is handled separately
@@ -35,23 +42,86 @@ var atoms = atomNames(map[string]string{
 	"\xa9wrt": "composer",
 	"\xa9too": "encoder",
 	"cprt":    "copyright",
+	"\xa9cpy": "qt_copyright",
+	"\xa9phg": "phonogram_rights",
 	"covr":    "picture",
 	"\xa9grp": "grouping",
 	"keyw":    "keyword",
 	"\xa9lyr": "lyrics",
 	"\xa9cmt": "comment",
+	"\xa9inf": "information",
 	"tmpo":    "tempo",
 	"cpil":    "compilation",
 	"disk":    "disc",
+	"\xa9st3": "disc_subtitle",
+	"tvsh":    "tv_show",
+	"tvnn":    "tv_network",
+	"tven":    "tv_episode_id",
+	"tvsn":    "tv_season",
+	"tves":    "tv_episode",
+	"\xa9dir": "director",
+	"\xa9prd": "producer",
+	"\xa9wrk": "work",
+	"\xa9mvn": "movement",
+	"\xa9mvi": "movement_index",
+	"\xa9mvc": "movement_count",
+	"ownr":    "owner",
+	"apID":    "apple_id",
+	"rate":    "rating",
+	"gnre":    "id3v1_genre",
+	"geID":    "apple_genre_id",
 })
 
+// AppleGenreByID maps the iTunes Store genre IDs (as stored in the geID atom) of music
+// genres to their names.
+var AppleGenreByID = map[int]string{
+	2:        "Blues",
+	3:        "Comedy",
+	4:        "Children's Music",
+	5:        "Classical",
+	6:        "Country",
+	7:        "Electronic",
+	8:        "Holiday",
+	9:        "Opera",
+	10:       "Singer/Songwriter",
+	11:       "Jazz",
+	12:       "Latin",
+	13:       "New Age",
+	14:       "Pop",
+	15:       "R&B/Soul",
+	16:       "Soundtrack",
+	17:       "Dance",
+	18:       "Hip-Hop/Rap",
+	19:       "World",
+	20:       "Alternative",
+	21:       "Rock",
+	22:       "Christian & Gospel",
+	23:       "Vocal",
+	24:       "Reggae",
+	25:       "Easy Listening",
+	27:       "J-Pop",
+	28:       "Enka",
+	29:       "Anime",
+	30:       "Kayokyoku",
+	50:       "Fitness & Workout",
+	51:       "K-Pop",
+	52:       "Karaoke",
+	53:       "Instrumental",
+	1122:     "Brazilian",
+	50000061: "Spoken Word",
+	50000063: "Disney",
+	50000064: "French Pop",
+	50000066: "German Pop",
+	50000068: "German Folk",
+}
+
 var means = map[string]bool{
 	"com.apple.iTunes":          true,
 	"com.mixedinkey.mixedinkey": true,
 	"com.serato.dj":             true,
 }
 
-// Detect PNG image if "implicit" class is used
+// Detect PNG image if "implicit" class is used (see sniffImageType).
 var pngHeader = []byte{137, 80, 78, 71, 13, 10, 26, 10}
 
 type atomNames map[string]string
@@ -66,25 +136,249 @@ func (f atomNames) Name(n string) []string {
 	return res
 }
 
-// metadataMP4 is the implementation of Metadata for MP4 tag (atom) data.
-type metadataMP4 struct {
+// MetadataMP4 is the implementation of Metadata for MP4 tag (atom) data.
+// The Metadata returned by ReadAtoms can be asserted to MetadataMP4 to access
+// MP4-specific metadata.
+type MetadataMP4 struct {
 	fileType FileType
 	data     map[string]interface{}
+	freeform map[string][]string // values of each data atom of recognised freeform atoms
+	tracks   []MP4Track
+	lazy     *mp4LazyCovers // nil unless read with ReadAtomsLazy
+}
+
+// mp4LazyCovers records the position of each 'covr' atom found by ReadAtomsLazy, so that
+// the pictures are only read when first needed.  The pictures are kept in covers (and
+// merged with the rest of the data in raw) rather than added to the shared data map, and
+// none of covers, raw and err are set or read until once has been done.  Likewise summary
+// is only set or read after summaryOnce.  mu guards the use of r, which is shared by both.
+type mp4LazyCovers struct {
+	r    io.ReadSeeker
+	refs []mp4AtomRef
+	mu   sync.Mutex
+	once sync.Once
+
+	covers map[string]interface{}
+	raw    map[string]interface{}
+	err    error
+
+	summaryOnce sync.Once
+	summary     []PictureInfo
+}
+
+// mp4AtomRef is the offset and size of the data of an atom.
+type mp4AtomRef struct {
+	offset int64
+	size   int64
+}
+
+// MP4Track is a type which describes a track (trak atom) in an MP4 file.
+type MP4Track struct {
+	ID       uint32   // Track ID (from tkhd).
+	Handler  string   // Handler type (from mdia/hdlr), i.e. "soun", "vide", "text", "subt".
+	Chapters []uint32 // IDs of the chapter tracks referenced by this track (from tref/chap).
+	Codec    string   // Format of the first sample description (from mdia/minf/stbl/stsd), i.e. "mp4a", "alac", "avc1".
+	Invalid  bool     // The trak atom is malformed, so the other fields may be incomplete.
 }
 
 // ReadAtoms reads MP4 metadata atoms from the io.ReadSeeker into a Metadata, returning
 // non-nil error if there was a problem.
 func ReadAtoms(r io.ReadSeeker) (Metadata, error) {
-	m := metadataMP4{
+	m := MetadataMP4{
 		data:     make(map[string]interface{}),
+		freeform: make(map[string][]string),
 		fileType: UnknownFileType,
 	}
 	err := m.readAtoms(r)
+	if m.fileType == UnknownFileType {
+		m.fileType = m.fileTypeFromTracks()
+	}
+	if m.fileType == M4A && m.isALAC() {
+		// Apple Lossless files use the M4A brand, which otherwise implies AAC.
+		m.fileType = ALAC
+	}
 	return m, err
 }
 
-func (m metadataMP4) readAtoms(r io.ReadSeeker) error {
+// ReadAtomsLazy is like ReadAtoms, but the cover art ('covr' atoms, which can be large) is
+// not read until it is first needed by Picture, Pictures or Raw, which is cheaper for
+// callers which only need the text metadata.  PictureSummary reads only the start of each
+// picture.  r must remain valid (i.e. open) until then.  If the cover art cannot be read,
+// it is left out and the error is returned by CoverErr.
+func ReadAtomsLazy(r io.ReadSeeker) (Metadata, error) {
+	m := MetadataMP4{
+		data:     make(map[string]interface{}),
+		freeform: make(map[string][]string),
+		fileType: UnknownFileType,
+		lazy:     &mp4LazyCovers{r: r},
+	}
+	err := m.readAtoms(r)
+	if m.fileType == UnknownFileType {
+		m.fileType = m.fileTypeFromTracks()
+	}
+	if m.fileType == M4A && m.isALAC() {
+		// Apple Lossless files use the M4A brand, which otherwise implies AAC.
+		m.fileType = ALAC
+	}
+	return m, err
+}
+
+// covers returns the map holding the cover art: the data map, unless the cover art was
+// skipped by ReadAtomsLazy, in which case it is read (once) into a separate map.
+func (m MetadataMP4) covers() map[string]interface{} {
+	if m.lazy == nil {
+		return m.data
+	}
+	m.lazy.once.Do(func() {
+		l := m.lazy
+		l.covers = make(map[string]interface{})
+		l.err = l.readCovers()
+		l.raw = make(map[string]interface{}, len(m.data)+len(l.covers))
+		for k, v := range m.data {
+			l.raw[k] = v
+		}
+		for k, v := range l.covers {
+			l.raw[k] = v
+		}
+	})
+	return m.lazy.covers
+}
+
+// readCovers reads the pictures from each of the 'covr' atoms recorded by ReadAtomsLazy,
+// stopping at the first error.
+func (l *mp4LazyCovers) readCovers() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, ref := range l.refs {
+		_, err := l.r.Seek(ref.offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		b, err := readBytes(l.r, uint(ref.size))
+		if err != nil {
+			return err
+		}
+		if err := readCoverAtomData(l.covers, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSummary returns a PictureInfo for each of the pictures in the 'covr' atoms recorded
+// by ReadAtomsLazy, as readCoverAtomData would read them, but reading only the header of
+// each data atom and (if the image type is implicit) the start of the image.  It stops at
+// the first error (which is returned by CoverErr).
+func (l *mp4LazyCovers) readSummary() []PictureInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var infos []PictureInfo
+	for _, ref := range l.refs {
+		for pos, end := ref.offset, ref.offset+ref.size; pos < end; {
+			// size (4 bytes) + "data" + class (4 bytes) + NULL (4 bytes)
+			if end-pos < 16 {
+				return infos
+			}
+			_, err := l.r.Seek(pos, io.SeekStart)
+			if err != nil {
+				return infos
+			}
+			b, err := readBytes(l.r, 16)
+			if err != nil {
+				return infos
+			}
+			size := int64(getInt(b[0:4]))
+			if size < 16 || size > end-pos {
+				return infos
+			}
+			name := string(b[4:8])
+			class := getInt(b[9:12])
+			pos += size
+
+			if name != "data" {
+				continue
+			}
+
+			contentType, ok := atomTypes[class]
+			if !ok {
+				return infos
+			}
+
+			if contentType == "implicit" {
+				// Enough of the data to detect the image type (see sniffImageType).
+				n := int64(len(pngHeader))
+				if size-16 < n {
+					n = size - 16
+				}
+				head, err := readBytes(l.r, uint(n))
+				if err != nil {
+					return infos
+				}
+				mimeType, _ := sniffImageType(head)
+				contentType = strings.TrimPrefix(mimeType, "image/")
+			}
+
+			switch contentType {
+			case "jpeg", "png", "gif", "bmp":
+			default:
+				continue
+			}
+
+			infos = append(infos, PictureInfo{
+				Type:     pictureTypes[0x03],
+				MIMEType: "image/" + contentType,
+				Size:     int(size - 16),
+			})
+		}
+	}
+	return infos
+}
+
+// CoverErr returns the error from reading the cover art skipped by ReadAtomsLazy, if any,
+// reading it first if it has not yet been needed.  It is always nil for Metadata returned
+// by ReadAtoms, for which errors reading the cover art are returned by ReadAtoms.
+func (m MetadataMP4) CoverErr() error {
+	if m.lazy == nil {
+		return nil
+	}
+	m.covers()
+	return m.lazy.err
+}
+
+// mp4Container is an atom which readAtoms has descended into.
+type mp4Container struct {
+	name string
+	end  int64 // position of the end of the atom
+}
+
+// mp4MetadataPath is the path of the containers of the file-level metadata items.  'meta'
+// atoms elsewhere (i.e. in a 'trak', or directly in 'moov' for QuickTime keyed metadata)
+// are skipped.
+var mp4MetadataPath = []string{"moov", "udta", "meta", "ilst"}
+
+// readAtoms reads the atoms from r, descending into the containers on mp4MetadataPath.
+func (m *MetadataMP4) readAtoms(r io.ReadSeeker) error {
+	var path []mp4Container
 	for {
+		start, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		for len(path) > 0 && start >= path[len(path)-1].end {
+			path = path[:len(path)-1]
+		}
+		if len(path) > 0 && path[len(path)-1].end-start < 8 {
+			// Skip any padding at the end of the container which is too short to be an
+			// atom (i.e. the 32-bit terminator QuickTime writes at the end of udta).
+			_, err = r.Seek(path[len(path)-1].end, io.SeekStart)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		name, size, err := readAtomHeader(r)
 		if err != nil {
 			if err == io.EOF {
@@ -92,50 +386,116 @@ func (m metadataMP4) readAtoms(r io.ReadSeeker) error {
 			}
 			return err
 		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
 
-		switch name {
-		case "meta":
-			// next_item_id (int32)
-			_, err := readBytes(r, 4)
+		parent := ""
+		if len(path) > 0 {
+			parent = path[len(path)-1].name
+			// Atoms must be within their container, so that the container end is reached.
+			if pos+size > path[len(path)-1].end {
+				return fmt.Errorf("%w: atom size for %q: %d", ErrInvalidMP4, name, size)
+			}
+		}
+
+		if len(path) < len(mp4MetadataPath) && name == mp4MetadataPath[len(path)] {
+			if name == "meta" {
+				// The hdlr atom (usually "mdir" for iTunes metadata) is not checked, as
+				// some writers omit it or use another handler type for the same ilst.
+				err := skipMetaVersionFlags(r)
+				if err != nil {
+					return err
+				}
+			}
+			path = append(path, mp4Container{name: name, end: pos + size})
+			continue
+		}
+
+		switch {
+		case name == "ftyp" && parent == "":
+			err := m.readFtyp(r, size)
+			if err != nil {
+				return err
+			}
+			continue
+
+		case name == "trak" && parent == "moov":
+			err := m.readTrak(r, size)
+			if err != nil {
+				return err
+			}
+			continue
+
+		case name == "\xa9xyz" && parent == "udta":
+			b, err := readBytes(r, uint(size))
 			if err != nil {
 				return err
 			}
-			fallthrough
+			m.data[name] = readUserDataString(b)
+			continue
 
-		case "moov", "udta", "ilst":
-			return m.readAtoms(r)
+		case parent != "ilst" && (parent != "udta" || !strings.HasPrefix(name, "\xa9")):
+			// Only ilst items, and the QuickTime text atoms in udta (see readAtomData),
+			// are metadata.
+			err = skipAtom(r, size)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 
 		_, ok := atoms[name]
 		var data []string
 		if name == "----" {
-			name, data, err = readCustomAtom(r, size)
+			b, err := readBytes(r, uint(size))
+			if err != nil {
+				return err
+			}
+			name, data, err = readCustomAtom(bytes.NewReader(b), size)
 			if err != nil {
 				return err
 			}
 
-			if name != "----" {
-				ok = true
-				size = 0 // already read data
+			if name == "----" {
+				// Freeform atoms which are not recognised are kept as their raw data
+				// (as "----", "----_0", "----_1", etc.).
+				m.data[numberedName(m.data, name)] = b
+				continue
 			}
+			m.freeform[name] = data
+			ok = true
+			size = 0 // already read data
 		}
 
 		if !ok {
-			_, err := r.Seek(int64(size-8), io.SeekCurrent)
+			// Items in ilst which are not mapped are kept as their raw data, so that
+			// they are not lost when the tags are written back.
+			if parent == "ilst" {
+				b, err := readBytes(r, uint(size))
+				if err != nil {
+					return err
+				}
+				m.data[numberedName(m.data, name)] = b
+				continue
+			}
+
+			err = skipAtom(r, size)
 			if err != nil {
 				return err
 			}
 			continue
 		}
 
-		err = m.readAtomData(r, name, size-8, data)
+		err = m.readAtomData(r, name, size, data)
 		if err != nil {
 			return err
 		}
 	}
 }
 
-func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, processedData []string) error {
+func (m MetadataMP4) readAtomData(r io.ReadSeeker, name string, size int64, processedData []string) error {
 	var b []byte
 	var err error
 	var contentType string
@@ -143,60 +503,104 @@ func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, pro
 		b = []byte(strings.Join(processedData, ";")) // add delimiter if multiple data fields
 		contentType = "text"
 	} else {
+		if name == "covr" && m.lazy != nil {
+			pos, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			m.lazy.refs = append(m.lazy.refs, mp4AtomRef{offset: pos, size: size})
+			return skipAtom(r, size)
+		}
+
 		// read the data
 		b, err = readBytes(r, uint(size))
 		if err != nil {
 			return err
 		}
+		if name == "covr" {
+			return readCoverAtomData(m.data, b)
+		}
+		// Older QuickTime files store text atoms directly in udta rather than in ilst,
+		// without a data atom.  Values from ilst take precedence.
+		if strings.HasPrefix(name, "\xa9") && (len(b) < 8 || string(b[4:8]) != "data") {
+			if _, ok := m.data[name]; !ok {
+				m.data[name] = readUserDataString(b)
+			}
+			return nil
+		}
 		if len(b) < 8 {
-			return fmt.Errorf("invalid encoding: expected at least %d bytes, got %d", 8, len(b))
+			return fmt.Errorf("%w: encoding: expected at least %d bytes, got %d", ErrInvalidMP4, 8, len(b))
+		}
+
+		// An item can contain more than one data atom, so only take the first here.
+		var rest []byte
+		if n := getInt(b[0:4]); n >= 16 && n < len(b) {
+			b, rest = b[:n], b[n:]
 		}
 
 		// "data" + size (4 bytes each)
 		b = b[8:]
 
 		if len(b) < 4 {
-			return fmt.Errorf("invalid encoding: expected at least %d bytes, for class, got %d", 4, len(b))
+			return fmt.Errorf("%w: encoding: expected at least %d bytes, for class, got %d", ErrInvalidMP4, 4, len(b))
 		}
 		class := getInt(b[1:4])
 		var ok bool
 		contentType, ok = atomTypes[class]
 		if !ok {
-			return fmt.Errorf("invalid content type: %v (%x) (%x)", class, b[1:4], b)
+			return fmt.Errorf("%w: content type: %v (%x) (%x)", ErrInvalidMP4, class, b[1:4], b)
 		}
 
 		// 4: atom version (1 byte) + atom flags (3 bytes)
 		// 4: NULL (usually locale indicator)
 		if len(b) < 8 {
-			return fmt.Errorf("invalid encoding: expected at least %d bytes, for atom version and flags, got %d", 8, len(b))
+			return fmt.Errorf("%w: encoding: expected at least %d bytes, for atom version and flags, got %d", ErrInvalidMP4, 8, len(b))
 		}
 		b = b[8:]
+
+		// Further text values (i.e. each line of a notice) are appended using a delimiter.
+		if contentType == "text" {
+			for _, v := range readTextDataAtoms(rest) {
+				b = append(append(b, ';'), v...)
+			}
+		}
 	}
 
 	if name == "trkn" || name == "disk" {
-		if len(b) < 6 {
-			return fmt.Errorf("invalid encoding: expected at least %d bytes, for track and disk numbers, got %d", 6, len(b))
+		// Some taggers write the number as text ("x/y") instead of binary.
+		if contentType == "text" {
+			x, n := parseXofN(strings.TrimSpace(string(b)))
+			if x != 0 || n != 0 {
+				m.data[name] = x
+				m.data[name+"_count"] = n
+			}
+			return nil
 		}
 
-		m.data[name] = int(b[3])
-		m.data[name+"_count"] = int(b[5])
+		// reserved <16>, number <16>, total <16>, reserved <16> (trkn only)
+		// Payloads too short to contain the number are ignored, and the total
+		// is optional.
+		if len(b) < 4 {
+			return nil
+		}
+		m.data[name] = int(binary.BigEndian.Uint16(b[2:4]))
+		if len(b) >= 6 {
+			m.data[name+"_count"] = int(binary.BigEndian.Uint16(b[4:6]))
+		}
 		return nil
 	}
 
-	if contentType == "implicit" {
-		if name == "covr" {
-			if bytes.HasPrefix(b, pngHeader) {
-				contentType = "png"
-			}
-			// TODO(dhowden): Detect JPEG formats too (harder).
-		}
+	// gnre, geID and tmpo are always integers, but gnre (and tmpo, by some taggers) is
+	// usually written with the implicit class.
+	if (name == "gnre" || name == "geID" || name == "tmpo") && contentType == "implicit" {
+		contentType = "uint8"
 	}
 
 	var data interface{}
 	switch contentType {
 	case "implicit":
 		if _, ok := atoms[name]; ok {
-			return fmt.Errorf("unhandled implicit content type for required atom: %q", name)
+			return fmt.Errorf("%w: unhandled implicit content type for required atom: %q", ErrInvalidMP4, name)
 		}
 		return nil
 
@@ -205,29 +609,463 @@ func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, pro
 
 	case "uint8":
 		if len(b) < 1 {
-			return fmt.Errorf("invalid encoding: expected at least %d bytes, for integer tag data, got %d", 1, len(b))
+			return fmt.Errorf("%w: encoding: expected at least %d bytes, for integer tag data, got %d", ErrInvalidMP4, 1, len(b))
+		}
+		// Integer data is big-endian, and can be 1, 2, 3, 4 or 8 bytes wide.
+		if len(b) > 8 {
+			b = b[:8]
+		}
+		data = getInt(b)
+	}
+	m.data[name] = data
+
+	return nil
+}
+
+// readTextDataAtoms returns the values of the text (class 1) data atoms in b, stopping
+// at the first atom which is not a text data atom.
+func readTextDataAtoms(b []byte) [][]byte {
+	var values [][]byte
+	for len(b) >= 16 {
+		n := getInt(b[0:4])
+		if string(b[4:8]) != "data" || n < 16 || n > len(b) || getInt(b[9:12]) != 1 {
+			break
+		}
+		values = append(values, b[16:n])
+		b = b[n:]
+	}
+	return values
+}
+
+// readCoverAtomData reads the pictures from each of the 'data' atoms in b (the content of
+// a 'covr' atom) into dst.  The first picture is stored as "covr", and any further
+// pictures (from this or any sibling 'covr' atoms) as "covr_0", "covr_1", etc.
+func readCoverAtomData(dst map[string]interface{}, b []byte) error {
+	for len(b) > 0 {
+		// size (4 bytes) + "data" + class (4 bytes) + NULL (4 bytes)
+		if len(b) < 16 {
+			return fmt.Errorf("%w: encoding: expected at least %d bytes, for cover data atom, got %d", ErrInvalidMP4, 16, len(b))
+		}
+		size := getInt(b[0:4])
+		if size < 16 || size > len(b) {
+			return fmt.Errorf("%w: encoding: invalid cover data atom size: %d", ErrInvalidMP4, size)
+		}
+		name := string(b[4:8])
+		class := getInt(b[9:12])
+		data := b[16:size]
+		b = b[size:]
+
+		if name != "data" {
+			continue
+		}
+
+		contentType, ok := atomTypes[class]
+		if !ok {
+			return fmt.Errorf("%w: content type: %v for cover data atom", ErrInvalidMP4, class)
+		}
+
+		if contentType == "implicit" {
+			// MP4 does not encode the image type, so detect it from the data.
+			mimeType, _ := sniffImageType(data)
+			contentType = strings.TrimPrefix(mimeType, "image/")
 		}
-		data = getInt(b[:1])
 
-	case "jpeg", "png":
-		data = &Picture{
+		switch contentType {
+		case "jpeg", "png", "gif", "bmp":
+		default:
+			continue
+		}
+
+		// MP4 does not encode the picture type, so assume the front cover.
+		dst[numberedName(dst, "covr")] = &Picture{
 			Ext:      contentType,
 			MIMEType: "image/" + contentType,
-			Data:     b,
+			Type:     pictureTypes[0x03],
+			Data:     data,
 		}
 	}
-	m.data[name] = data
-
 	return nil
 }
 
-func readAtomHeader(r io.ReadSeeker) (name string, size uint32, err error) {
-	err = binary.Read(r, binary.BigEndian, &size)
+// skipMetaVersionFlags skips the version/flags preamble of a 'meta' atom.  In iTunes files
+// 'meta' is a full box (with a 4 byte version/flags preamble), but in some QuickTime files
+// it is a plain container, in which case the first child atom header follows immediately.
+func skipMetaVersionFlags(r io.ReadSeeker) error {
+	b, err := readBytes(r, 8)
+	if err != nil {
+		return err
+	}
+
+	if isAtomName(b[4:8]) {
+		// No preamble: b is the header (size + name) of the first child atom.
+		_, err = r.Seek(-8, io.SeekCurrent)
+		return err
+	}
+	_, err = r.Seek(-4, io.SeekCurrent)
+	return err
+}
+
+// isAtomName returns true if b looks like an atom name (printable ASCII, or the
+// \xa9 (©) prefix used by iTunes).
+func isAtomName(b []byte) bool {
+	for _, c := range b {
+		if c != 0xa9 && (c < 0x20 || c > 0x7e) {
+			return false
+		}
+	}
+	return true
+}
+
+// numberedName returns name if it is not a key in data, otherwise the first of name_0,
+// name_1, etc. which is not.
+func numberedName(data map[string]interface{}, name string) string {
+	k := name
+	for i := 0; ; i++ {
+		if _, ok := data[k]; !ok {
+			return k
+		}
+		k = name + "_" + strconv.Itoa(i)
+	}
+}
+
+// readAtomHeader reads the header of an atom, returning its name and the size of its data
+// (excluding the header).  The size field can be 1, in which case a 64-bit size follows
+// the name, or 0 if the atom extends to the end of the file.
+// Size          <32>
+// Name          <4 bytes>
+// Extended size <64> (if size is 1)
+func readAtomHeader(r io.ReadSeeker) (name string, size int64, err error) {
+	var size32 uint32
+	err = binary.Read(r, binary.BigEndian, &size32)
 	if err != nil {
 		return
 	}
 	name, err = readString(r, 4)
-	return
+	if err != nil {
+		return
+	}
+
+	switch {
+	case size32 == 0:
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", 0, err
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return "", 0, err
+		}
+		_, err = r.Seek(pos, io.SeekStart)
+		return name, end - pos, err
+
+	case size32 == 1:
+		var largeSize uint64
+		err = binary.Read(r, binary.BigEndian, &largeSize)
+		if err != nil {
+			return
+		}
+		if largeSize < 16 || largeSize-16 > math.MaxInt64 {
+			return "", 0, fmt.Errorf("%w: 64-bit atom size for %q: %d", ErrInvalidMP4, name, largeSize)
+		}
+		return name, int64(largeSize - 16), nil
+
+	case size32 < 8:
+		return "", 0, fmt.Errorf("%w: atom size for %q: %d", ErrInvalidMP4, name, size32)
+	}
+	return name, int64(size32) - 8, nil
+}
+
+// ftyp atom
+// Major brand    <4 bytes>
+// Minor version  <32>
+// Compatible brands
+func (m *MetadataMP4) readFtyp(r io.ReadSeeker, size int64) error {
+	if size < 4 {
+		return fmt.Errorf("%w: ftyp atom size: %d", ErrInvalidMP4, size+8)
+	}
+	b, err := readBytes(r, uint(size))
+	if err != nil {
+		return err
+	}
+
+	m.fileType = mp4BrandFileType(b[0:3])
+	return nil
+}
+
+// mp4BrandFileType returns the FileType for the (first 3 bytes of the) major brand of the
+// ftyp atom, or UnknownFileType if the brand does not identify the file type.
+func mp4BrandFileType(b []byte) FileType {
+	switch string(b) {
+	case "M4A":
+		return M4A
+	case "M4B":
+		return M4B
+	case "M4P":
+		return M4P
+	case "M4V":
+		return M4V
+	}
+	return UnknownFileType
+}
+
+// readTrak reads the track ID, handler type and chapter references from the trak atom
+// (with data of the given size, the header already read).  Other child atoms are skipped.
+// A malformed trak atom is recorded as an Invalid track and skipped, as the metadata does
+// not depend on it.
+func (m *MetadataMP4) readTrak(r io.ReadSeeker, size int64) error {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	var t MP4Track
+	err = walkAtoms(r, size, func(name string, size int64) error {
+		switch name {
+		case "tkhd":
+			// version <8>, flags <24>, creation time, modification time, track ID <32>
+			// (the times are 32-bit for version 0 and 64-bit for version 1)
+			b, err := readBytes(r, uint(size))
+			if err != nil {
+				return err
+			}
+			offset := 12
+			if len(b) > 0 && b[0] == 1 {
+				offset = 20
+			}
+			if len(b) >= offset+4 {
+				t.ID = binary.BigEndian.Uint32(b[offset:])
+			}
+
+		case "tref":
+			// Each child atom is a list of track IDs, chap refers to chapter tracks.
+			return walkAtoms(r, size, func(name string, size int64) error {
+				if name != "chap" {
+					return nil
+				}
+				b, err := readBytes(r, uint(size))
+				if err != nil {
+					return err
+				}
+				for ; len(b) >= 4; b = b[4:] {
+					t.Chapters = append(t.Chapters, binary.BigEndian.Uint32(b))
+				}
+				return nil
+			})
+
+		case "mdia":
+			return walkAtoms(r, size, func(name string, size int64) error {
+				switch name {
+				case "hdlr":
+					// version <8>, flags <24>, pre-defined <32>, handler type <4 bytes>
+					b, err := readBytes(r, uint(size))
+					if err != nil {
+						return err
+					}
+					if len(b) >= 12 {
+						t.Handler = string(b[8:12])
+					}
+
+				case "minf":
+					return walkAtoms(r, size, func(name string, size int64) error {
+						if name != "stbl" {
+							return nil
+						}
+						return walkAtoms(r, size, func(name string, size int64) error {
+							if name != "stsd" {
+								return nil
+							}
+							codec, err := readStsdCodec(r, size)
+							t.Codec = codec
+							return err
+						})
+					})
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Invalid = true
+		_, err = r.Seek(start+size, io.SeekStart)
+	}
+	m.tracks = append(m.tracks, t)
+	return err
+}
+
+// readStsdCodec returns the format of the first sample description in the stsd atom (with
+// data of the given size, the header already read).
+// Version <8>, flags <24>, number of entries <32>
+// Sample description size <32>, data format <4 bytes>, ...
+func readStsdCodec(r io.ReadSeeker, size int64) (string, error) {
+	if size < 16 {
+		return "", nil
+	}
+	b, err := readBytes(r, 16)
+	if err != nil {
+		return "", err
+	}
+	if binary.BigEndian.Uint32(b[4:8]) == 0 {
+		return "", nil
+	}
+	return string(b[12:16]), nil
+}
+
+// walkAtoms calls fn with the name and data size of each of the atoms in the next size
+// bytes of r.  fn can read (but not beyond) the atom data, and r is then moved to the
+// next atom.  r is left at the end of the atoms.
+func walkAtoms(r io.ReadSeeker, size int64, fn func(name string, size int64) error) error {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end := start + size
+
+	for offset := start; offset+8 <= end; {
+		name, n, err := readAtomHeader(r)
+		if err != nil {
+			return err
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if pos+n > end {
+			return fmt.Errorf("%w: atom size for %q: %d", ErrInvalidMP4, name, n)
+		}
+
+		err = fn(name, n)
+		if err != nil {
+			return err
+		}
+
+		offset = pos + n
+		_, err = r.Seek(offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = r.Seek(end, io.SeekStart)
+	return err
+}
+
+// fileTypeFromTracks returns the FileType implied by the track handlers, for files whose
+// ftyp brand does not identify the file type: M4V if there is a video track, M4A if there
+// is an audio track.
+func (m *MetadataMP4) fileTypeFromTracks() FileType {
+	fileType := UnknownFileType
+	for _, t := range m.tracks {
+		switch t.Handler {
+		case "vide":
+			return M4V
+		case "soun":
+			fileType = M4A
+		}
+	}
+	return fileType
+}
+
+// isALAC returns true if there is an Apple Lossless (alac) audio track.
+func (m *MetadataMP4) isALAC() bool {
+	for _, t := range m.tracks {
+		if t.Handler == "soun" && t.Codec == "alac" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtected returns true if there is a track with a FairPlay protected sample description
+// (drms for audio, drmi for video), as used by iTunes Store M4P and M4V files.  The tags can
+// be read as normal, but the audio (or video) data is encrypted.
+func (m MetadataMP4) IsProtected() bool {
+	for _, t := range m.tracks {
+		if t.Codec == "drms" || t.Codec == "drmi" {
+			return true
+		}
+	}
+	return false
+}
+
+// Tracks returns the tracks in the file, in the order they appear.
+func (m MetadataMP4) Tracks() []MP4Track {
+	return m.tracks
+}
+
+// ChapterTrack returns the text track which contains the chapter titles: the first
+// text track referenced as a chapter track (tref/chap) by another track.
+func (m MetadataMP4) ChapterTrack() (MP4Track, bool) {
+	for _, t := range m.tracks {
+		for _, id := range t.Chapters {
+			for _, c := range m.tracks {
+				if c.ID == id && c.Handler == "text" {
+					return c, true
+				}
+			}
+		}
+	}
+	return MP4Track{}, false
+}
+
+// readUserDataString returns the string in the atom data b, which is either a 'data'
+// atom (as used in ilst) or a QuickTime user data string:
+// Size      <16>
+// Language  <16>
+// Text      <size bytes>
+func readUserDataString(b []byte) string {
+	if len(b) >= 16 && string(b[4:8]) == "data" {
+		return string(b[16:])
+	}
+	if len(b) < 4 {
+		return ""
+	}
+	n := int(binary.BigEndian.Uint16(b[0:2]))
+	if n > len(b)-4 {
+		n = len(b) - 4
+	}
+	return string(b[4 : 4+n])
+}
+
+// parseISO6709 parses the latitude and longitude (in decimal degrees) from an ISO 6709
+// location string, i.e. "+37.7749-122.4194/" or "+37.7749-122.4194+010.000/" (with
+// altitude).
+func parseISO6709(s string) (lat, lon float64, ok bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "/")
+
+	var values []float64
+	for len(s) > 0 && len(values) < 2 {
+		if s[0] != '+' && s[0] != '-' {
+			return 0, 0, false
+		}
+		end := strings.IndexAny(s[1:], "+-/")
+		if end < 0 {
+			end = len(s) - 1
+		}
+		v, err := strconv.ParseFloat(s[:end+1], 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		values = append(values, v)
+		s = s[end+1:]
+	}
+	if len(values) != 2 || values[0] < -90 || values[0] > 90 || values[1] < -180 || values[1] > 180 {
+		return 0, 0, false
+	}
+	return values[0], values[1], true
+}
+
+// Location returns the latitude and longitude (in decimal degrees) from the ©xyz atom,
+// as written by phones and field recorders.  ok is false if there is no valid location.
+func (m MetadataMP4) Location() (lat, lon float64, ok bool) {
+	s, _ := m.data["\xa9xyz"].(string)
+	return parseISO6709(s)
+}
+
+// skipAtom seeks (rather than reads) past the data (of the given size) of an atom whose
+// header has just been read, so that large atoms (i.e. 'mdat') are skipped cheaply.
+func skipAtom(r io.ReadSeeker, size int64) error {
+	_, err := r.Seek(size, io.SeekCurrent)
+	return err
 }
 
 // Generic atom.
@@ -236,44 +1074,51 @@ func readAtomHeader(r io.ReadSeeker) (name string, size uint32, err error) {
 // the name, and move to the data atom.
 // Data atom could have multiple data values, each with a header.
 // If anything goes wrong, we jump at the end of the "----" atom.
-func readCustomAtom(r io.ReadSeeker, size uint32) (_ string, data []string, _ error) {
+func readCustomAtom(r io.ReadSeeker, size int64) (_ string, data []string, _ error) {
 	subNames := make(map[string]string)
 
-	for size > 8 {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", nil, err
+	}
+	end := start + size
+
+	for offset := start; offset < end; {
 		subName, subSize, err := readAtomHeader(r)
 		if err != nil {
 			return "", nil, err
 		}
-
-		// Remove the size of the atom from the size counter
-		if size >= subSize {
-			size -= subSize
-		} else {
-			return "", nil, errors.New("--- invalid size")
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", nil, err
 		}
+		if pos+subSize > end {
+			return "", nil, fmt.Errorf("%w: '----' atom size", ErrInvalidMP4)
+		}
+		offset = pos + subSize
 
-		b, err := readBytes(r, uint(subSize-8))
+		b, err := readBytes(r, uint(subSize))
 		if err != nil {
 			return "", nil, err
 		}
 
 		if len(b) < 4 {
-			return "", nil, fmt.Errorf("invalid encoding: expected at least %d bytes, got %d", 4, len(b))
+			return "", nil, fmt.Errorf("%w: encoding: expected at least %d bytes, got %d", ErrInvalidMP4, 4, len(b))
 		}
 		switch subName {
 		case "mean", "name":
 			subNames[subName] = string(b[4:])
 		case "data":
-			data = append(data, string(b[4:]))
+			// 4: atom version (1 byte) + atom flags (3 bytes)
+			// 4: NULL (usually locale indicator)
+			// NB: the locale indicator is not part of the value, so is not kept in Raw().
+			if len(b) < 8 {
+				return "", nil, fmt.Errorf("%w: encoding: expected at least %d bytes, got %d", ErrInvalidMP4, 8, len(b))
+			}
+			data = append(data, string(b[8:]))
 		}
 	}
 
-	// there should remain only the header size
-	if size != 8 {
-		err := errors.New("---- atom out of bounds")
-		return "", nil, err
-	}
-
 	if !means[subNames["mean"]] || subNames["name"] == "" || len(data) == 0 {
 		return "----", nil, nil
 	}
@@ -281,54 +1126,79 @@ func readCustomAtom(r io.ReadSeeker, size uint32) (_ string, data []string, _ er
 	return subNames["name"], data, nil
 }
 
-func (metadataMP4) Format() Format       { return MP4 }
-func (m metadataMP4) FileType() FileType { return m.fileType }
+func (MetadataMP4) Format() Format       { return MP4 }
+func (m MetadataMP4) FileType() FileType { return m.fileType }
 
-func (m metadataMP4) Raw() map[string]interface{} { return m.data }
+func (m MetadataMP4) Raw() map[string]interface{} {
+	if m.lazy != nil {
+		m.covers()
+		return m.lazy.raw
+	}
+	return m.data
+}
 
-func (m metadataMP4) getString(n []string) string {
+func (m MetadataMP4) getString(n []string) string {
 	for _, k := range n {
-		if x, ok := m.data[k]; ok {
-			return x.(string)
+		if x, ok := m.data[k].(string); ok {
+			return x
 		}
 	}
 	return ""
 }
 
-func (m metadataMP4) getInt(n []string) int {
+func (m MetadataMP4) getInt(n []string) int {
 	for _, k := range n {
-		if x, ok := m.data[k]; ok {
-			return x.(int)
+		if x, ok := m.data[k].(int); ok {
+			return x
 		}
 	}
 	return 0
 }
 
-func (m metadataMP4) Title() string {
+func (m MetadataMP4) Title() string {
 	return m.getString(atoms.Name("title"))
 }
 
-func (m metadataMP4) Artist() string {
+func (m MetadataMP4) Artist() string {
 	return m.getString(atoms.Name("artist"))
 }
 
-func (m metadataMP4) Album() string {
+func (m MetadataMP4) Album() string {
 	return m.getString(atoms.Name("album"))
 }
 
-func (m metadataMP4) AlbumArtist() string {
+func (m MetadataMP4) AlbumArtist() string {
 	return m.getString(atoms.Name("album_artist"))
 }
 
-func (m metadataMP4) Composer() string {
+func (m MetadataMP4) Composer() string {
 	return m.getString(atoms.Name("composer"))
 }
 
-func (m metadataMP4) Genre() string {
-	return m.getString(atoms.Name("genre"))
+// Genre returns the genre (©gen).  If there is no ©gen, the genre is looked up from the
+// ID3v1 genre number (gnre, which is the ID3v1 genre index + 1), or the Apple genre ID
+// (geID, see AppleGenreByID).  Returns "" if the genre is unavailable or unknown.
+func (m MetadataMP4) Genre() string {
+	if g := m.getString(atoms.Name("genre")); g != "" {
+		return g
+	}
+	if n := m.getInt(atoms.Name("id3v1_genre")); n > 0 && n <= len(id3v1Genres) {
+		return id3v1Genres[n-1]
+	}
+	if id, ok := m.AppleGenreID(); ok {
+		return AppleGenreByID[id]
+	}
+	return ""
+}
+
+// AppleGenreID returns the iTunes Store genre ID (geID), and true if there is one.  IDs
+// which are not in AppleGenreByID can be mapped by the caller.
+func (m MetadataMP4) AppleGenreID() (int, bool) {
+	id := m.getInt(atoms.Name("apple_genre_id"))
+	return id, id > 0
 }
 
-func (m metadataMP4) Year() int {
+func (m MetadataMP4) Year() int {
 	date := m.getString(atoms.Name("year"))
 	if len(date) >= 4 {
 		year, _ := strconv.Atoi(date[:4])
@@ -337,43 +1207,313 @@ func (m metadataMP4) Year() int {
 	return 0
 }
 
-func (m metadataMP4) Track() (int, int) {
-	x := m.getInt([]string{"trkn"})
-	if n, ok := m.data["trkn_count"]; ok {
-		return x, n.(int)
+// getXofN returns the number and total from the (binary or text) name atom, falling back
+// to parsing the freeform atom if the name atom is absent or malformed.
+func (m MetadataMP4) getXofN(name, freeform string) (int, int) {
+	p := m.getPosition(name, freeform)
+	return p.Number, p.Total
+}
+
+// getPosition returns the position from the trkn or disk atom name, falling back to the
+// freeform atom.  A zero total in the atom means that it is not set (iTunes writes the
+// total as zero if it is unknown).
+func (m MetadataMP4) getPosition(name, freeform string) Position {
+	x, ok := m.data[name]
+	if !ok {
+		return parsePosition(strings.TrimSpace(m.getString([]string{freeform})))
 	}
-	return x, 0
+	n, _ := m.data[name+"_count"].(int)
+	return Position{Number: x.(int), Total: n, HasTotal: n != 0}
+}
+
+func (m MetadataMP4) Track() (int, int) {
+	return m.getXofN("trkn", "TRACKNUMBER")
+}
+
+func (m MetadataMP4) Disc() (int, int) {
+	return m.getXofN("disk", "DISCNUMBER")
+}
+
+func (m MetadataMP4) TrackPosition() Position {
+	return m.getPosition("trkn", "TRACKNUMBER")
 }
 
-func (m metadataMP4) Disc() (int, int) {
-	x := m.getInt([]string{"disk"})
-	if n, ok := m.data["disk_count"]; ok {
-		return x, n.(int)
+func (m MetadataMP4) DiscPosition() Position {
+	return m.getPosition("disk", "DISCNUMBER")
+}
+
+// Lyrics returns the lyrics (©lyr), with Windows (\r\n) and old Mac (\r) line endings
+// replaced by \n, or an empty string if unavailable.
+func (m MetadataMP4) Lyrics() string {
+	t, _ := m.data["\xa9lyr"].(string)
+	return strings.ReplaceAll(strings.ReplaceAll(t, "\r\n", "\n"), "\r", "\n")
+}
+
+// Comment returns the comment (©cmt), falling back to the older QuickTime information
+// atom (©inf), or an empty string if unavailable.
+func (m MetadataMP4) Comment() string {
+	if t, ok := m.data["\xa9cmt"].(string); ok {
+		return t
 	}
-	return x, 0
+	return m.getString(atoms.Name("information"))
 }
 
-func (m metadataMP4) Lyrics() string {
-	t, ok := m.data["\xa9lyr"]
-	if !ok {
-		return ""
+func (m MetadataMP4) ISRC() string {
+	// iTunes stores the ISRC as a freeform atom (----:com.apple.iTunes:ISRC).
+	return m.getString([]string{"ISRC"})
+}
+
+// CatalogNumber returns the release catalog number (----:com.apple.iTunes:CATALOGNUMBER),
+// or an empty string if unavailable.
+func (m MetadataMP4) CatalogNumber() string {
+	return m.getString([]string{"CATALOGNUMBER"})
+}
+
+// Barcode returns the release barcode, i.e. the UPC or EAN (----:com.apple.iTunes:BARCODE),
+// or an empty string if unavailable.
+func (m MetadataMP4) Barcode() string {
+	return m.getString([]string{"BARCODE"})
+}
+
+// ReplayGain returns the ReplayGain values from the freeform replaygain_* atoms
+// (----:com.apple.iTunes:replaygain_track_gain, etc., as written by MusicBrainz Picard
+// and foobar2000).  The names can also be in upper case.
+//
+// If there is no track gain atom, the track gain (and the track peak, if there is no track
+// peak atom) is taken from the iTunes Sound Check values (see SoundCheck).
+func (m MetadataMP4) ReplayGain() ReplayGainInfo {
+	get := func(name string) string {
+		return m.getString([]string{name, strings.ToUpper(name)})
 	}
-	return t.(string)
+	rg := ReplayGainInfo{
+		TrackGain: parseReplayGain(get("replaygain_track_gain")),
+		TrackPeak: parseReplayGain(get("replaygain_track_peak")),
+		AlbumGain: parseReplayGain(get("replaygain_album_gain")),
+		AlbumPeak: parseReplayGain(get("replaygain_album_peak")),
+	}
+	if get("replaygain_track_gain") == "" {
+		if sc := m.SoundCheck(); sc != nil {
+			rg.TrackGain = sc.Gain()
+			if get("replaygain_track_peak") == "" {
+				rg.TrackPeak = sc.Peak()
+			}
+		}
+	}
+	return rg
 }
 
-func (m metadataMP4) Comment() string {
-	t, ok := m.data["\xa9cmt"]
-	if !ok {
-		return ""
+// SoundCheck returns the iTunes Sound Check normalization values from the freeform
+// ----:com.apple.iTunes:iTunNORM atom, or nil if unavailable.
+func (m MetadataMP4) SoundCheck() *SoundCheck {
+	s := m.getString([]string{"iTunNORM"})
+	if s == "" {
+		return nil
+	}
+	sc, err := ParseSoundCheck(s)
+	if err != nil {
+		return nil
 	}
-	return t.(string)
+	return &sc
+}
+
+func (m MetadataMP4) BPM() int {
+	return m.getInt(atoms.Name("tempo"))
 }
 
-func (m metadataMP4) Picture() *Picture {
-	v, ok := m.data["covr"]
+func (m MetadataMP4) Compilation() bool {
+	return m.getInt(atoms.Name("compilation")) == 1
+}
+
+func (m MetadataMP4) Picture() *Picture {
+	v, ok := m.covers()["covr"]
 	if !ok {
 		return nil
 	}
 	p, _ := v.(*Picture)
 	return p
 }
+
+// Pictures returns the pictures from each of the data atoms of the covr atoms.
+func (m MetadataMP4) Pictures() []*Picture {
+	return numberedPictures(m.covers(), "covr")
+}
+
+// Copyright returns the copyright notice (cprt, or the older QuickTime ©cpy), or an empty
+// string if unavailable.  The notice is returned in full, including any line breaks.
+func (m MetadataMP4) Copyright() string {
+	if s := m.getString(atoms.Name("copyright")); s != "" {
+		return s
+	}
+	return m.getString(atoms.Name("qt_copyright"))
+}
+
+// PhonogramRights returns the phonogram rights (℗) notice (©phg), or an empty string
+// if unavailable.
+func (m MetadataMP4) PhonogramRights() string {
+	return m.getString(atoms.Name("phonogram_rights"))
+}
+
+// Director returns the director (©dir) of the video or audiobook, or an empty string if unavailable.
+func (m MetadataMP4) Director() string {
+	return m.getString(atoms.Name("director"))
+}
+
+// Producer returns the producer (©prd), or an empty string if unavailable.
+func (m MetadataMP4) Producer() string {
+	return m.getString(atoms.Name("producer"))
+}
+
+// Work returns the name of the work (©wrk, i.e. a symphony) that the track is part of,
+// or an empty string if unavailable.
+func (m MetadataMP4) Work() string {
+	return m.getString(atoms.Name("work"))
+}
+
+// Movement returns the movement name (©mvn), number (©mvi) and count (©mvc), or zero
+// values if unavailable.
+func (m MetadataMP4) Movement() (name string, index, count int) {
+	return m.getString(atoms.Name("movement")), m.getInt(atoms.Name("movement_index")), m.getInt(atoms.Name("movement_count"))
+}
+
+// Artists returns each of the track artists, from the data atoms of the freeform ARTISTS
+// atom (----:com.apple.iTunes:ARTISTS, as written by MusicBrainz Picard).  If there is no
+// ARTISTS atom the result is the ©ART artist, as a joined artist string cannot be reliably
+// split.  Returns nil if there are no artists.
+func (m MetadataMP4) Artists() []string {
+	var artists []string
+	for _, a := range m.freeform["ARTISTS"] {
+		if a != "" {
+			artists = append(artists, a)
+		}
+	}
+	if len(artists) > 0 {
+		return artists
+	}
+	if a := m.Artist(); a != "" {
+		return []string{a}
+	}
+	return nil
+}
+
+// Conductor returns the conductor (----:com.apple.iTunes:CONDUCTOR), or an empty string if unavailable.
+func (m MetadataMP4) Conductor() string {
+	return m.getString([]string{"CONDUCTOR"})
+}
+
+// DiscSubtitle returns the disc subtitle (©st3), falling back to the freeform DISCSUBTITLE
+// atom (----:com.apple.iTunes:DISCSUBTITLE), or an empty string if unavailable.
+func (m MetadataMP4) DiscSubtitle() string {
+	if s := m.getString(atoms.Name("disc_subtitle")); s != "" {
+		return s
+	}
+	return m.getString([]string{"DISCSUBTITLE"})
+}
+
+// Label returns the record label (----:com.apple.iTunes:LABEL), or an empty string if unavailable.
+func (m MetadataMP4) Label() string {
+	return m.getString([]string{"LABEL"})
+}
+
+// Media returns the release medium, i.e. "CD" or "Digital Media" (----:com.apple.iTunes:MEDIA),
+// or an empty string if unavailable.
+func (m MetadataMP4) Media() string {
+	return m.getString([]string{"MEDIA"})
+}
+
+// Script returns the ISO 15924 code of the script of the release text, i.e. "Latn"
+// (----:com.apple.iTunes:SCRIPT), or an empty string if unavailable.
+func (m MetadataMP4) Script() string {
+	return m.getString([]string{"SCRIPT"})
+}
+
+// Language returns the ISO 639-3 code of the language of the lyrics, i.e. "eng"
+// (----:com.apple.iTunes:LANGUAGE), or an empty string if unavailable.
+func (m MetadataMP4) Language() string {
+	return m.getString([]string{"LANGUAGE"})
+}
+
+// Mood returns the mood of the track (----:com.apple.iTunes:MOOD), or an empty string if
+// unavailable.
+func (m MetadataMP4) Mood() string {
+	return m.getString([]string{"MOOD"})
+}
+
+// ComposerSort returns the composer sort name (----:com.apple.iTunes:COMPOSERSORT), or an
+// empty string if unavailable.
+func (m MetadataMP4) ComposerSort() string {
+	return m.getString([]string{"COMPOSERSORT"})
+}
+
+// Rating returns the rating (rate, as written by MediaMonkey and others) on a scale of 1
+// to 5 stars, and true if there is a rating.  MP4 ratings are stored as a percentage
+// (0-100, with 0 meaning unrated) and are rounded to the nearest star, so that 20 is 1
+// star, 80 is 4 stars and 100 is 5 stars.
+func (m MetadataMP4) Rating() (int, bool) {
+	var r int
+	switch v := m.data["rate"].(type) {
+	case string:
+		r, _ = strconv.Atoi(strings.TrimSpace(v))
+	case int:
+		r = v
+	}
+	if r <= 0 {
+		return 0, false
+	}
+	stars := (r + 10) / 20
+	if stars < 1 {
+		stars = 1
+	}
+	if stars > 5 {
+		stars = 5
+	}
+	return stars, true
+}
+
+// Provenance is a type which contains the purchase information embedded in iTunes Store
+// files.  NB: these identify the purchaser, not the artist.
+type Provenance struct {
+	Owner   string // Owner name (ownr).
+	AppleID string // Purchaser Apple ID (apID).
+}
+
+// Provenance returns the iTunes Store purchase information, or zero values if unavailable.
+func (m MetadataMP4) Provenance() Provenance {
+	return Provenance{
+		Owner:   m.getString(atoms.Name("owner")),
+		AppleID: m.getString(atoms.Name("apple_id")),
+	}
+}
+
+// PictureSummary returns the type, MIME type and size of each cover (covr data atom)
+// in the order they appear, or nil if there are none.  For Metadata returned by
+// ReadAtomsLazy, the cover art is not loaded: only the start of each picture is read.
+func (m MetadataMP4) PictureSummary() []PictureInfo {
+	if m.lazy == nil {
+		return summarizePictures(m.Pictures())
+	}
+	m.lazy.summaryOnce.Do(func() {
+		m.lazy.summary = m.lazy.readSummary()
+	})
+	return m.lazy.summary
+}
+
+// TVInfo is a type which contains TV episode metadata, as used by iTunes and media
+// center software to classify TV episodes.
+type TVInfo struct {
+	Show      string // Show name (tvsh).
+	Network   string // Network name (tvnn).
+	EpisodeID string // Episode identifier (tven).
+	Season    int    // Season number (tvsn).
+	Episode   int    // Episode number (tves).
+}
+
+// TVInfo returns the TV episode metadata, or zero values if unavailable.
+func (m MetadataMP4) TVInfo() TVInfo {
+	return TVInfo{
+		Show:      m.getString(atoms.Name("tv_show")),
+		Network:   m.getString(atoms.Name("tv_network")),
+		EpisodeID: m.getString(atoms.Name("tv_episode_id")),
+		Season:    m.getInt(atoms.Name("tv_season")),
+		Episode:   m.getInt(atoms.Name("tv_episode")),
+	}
+}
@@ -0,0 +1,843 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMP4TVInfo(t *testing.T) {
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.tv.m4v")
+
+	want := TVInfo{
+		Show:      "Test Show",
+		Network:   "Test Network",
+		EpisodeID: "S02E05",
+		Season:    2,
+		Episode:   5,
+	}
+	if got := m.TVInfo(); got != want {
+		t.Errorf("TVInfo() = %#v, expected: %#v", got, want)
+	}
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.m4a")
+	if got := m.TVInfo(); got != (TVInfo{}) {
+		t.Errorf("TVInfo() = %#v, expected zero value", got)
+	}
+}
+
+func TestMP4QuickTimeMeta(t *testing.T) {
+	// sample.quicktime.m4a is sample.m4a with a 'meta' atom without version/flags.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.quicktime.m4a")
+	compareMetadata(t, m, fullMetadata)
+
+	sum := func(path string) string {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		s, err := Sum(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	if got, want := sum("with_tags/sample.quicktime.m4a"), sum("with_tags/sample.m4a"); got != want {
+		t.Errorf("Sum() = %v, expected: %v", got, want)
+	}
+}
+
+func TestMP4CoverArt(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.covr.m4a",          // explicit jpeg (13) and png (14) classes
+		"with_tags/sample.covr-implicit.m4a", // implicit (0) class
+		"with_tags/sample.covr-hdlr.m4a",     // meta handler "zzzz" rather than "mdir"
+		"with_tags/sample.covr-nohdlr.m4a",   // meta without a hdlr atom
+		"with_tags/sample.covr-siblings.m4a", // separate covr atoms with a data atom each
+	} {
+		m := readTestFile[MetadataMP4](t, path)
+
+		tests := []struct {
+			name     string
+			ext      string
+			mimeType string
+		}{
+			{"covr", "jpeg", "image/jpeg"},
+			{"covr_0", "png", "image/png"},
+		}
+
+		for _, tt := range tests {
+			p, ok := m.Raw()[tt.name].(*Picture)
+			if !ok {
+				t.Errorf("%v: Raw()[%q] = %#v, expected *Picture", path, tt.name, m.Raw()[tt.name])
+				continue
+			}
+			if p.Ext != tt.ext || p.MIMEType != tt.mimeType || p.Type != "Cover (front)" {
+				t.Errorf("%v: %v: got %v, expected Ext: %v, MIMEType: %v, Type: Cover (front)", path, tt.name, p, tt.ext, tt.mimeType)
+			}
+		}
+
+		if p := m.Picture(); p == nil || p.MIMEType != "image/jpeg" {
+			t.Errorf("%v: Picture() = %v, expected image/jpeg picture", path, p)
+		}
+		if n := len(m.PictureSummary()); n != 2 {
+			t.Errorf("%v: len(PictureSummary()) = %d, expected: 2", path, n)
+		}
+	}
+}
+
+func TestMP4DirectorProducer(t *testing.T) {
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.credits.m4b")
+	testValue(t, "Test Director", m.Director())
+	testValue(t, "Test Producer", m.Producer())
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.m4a")
+	testValue(t, "", m.Director())
+	testValue(t, "", m.Producer())
+}
+
+func TestMP4FreeformCredits(t *testing.T) {
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.freeform.m4a")
+	testValue(t, "Test Conductor", m.Conductor())
+	testValue(t, "Test Disc Subtitle", m.DiscSubtitle())
+	testValue(t, "Test Label", m.Label())
+	testValue(t, "Digital Media", m.Media())
+	testValue(t, "Latn", m.Script())
+	testValue(t, "eng", m.Language())
+	testValue(t, "Happy", m.Mood())
+	testValue(t, "Composer, Test", m.ComposerSort())
+
+	// The values in Raw() do not include the 4 byte locale indicator of the data atom
+	// (which was kept as a NUL prefix before the accessors were added).
+	testValue(t, "Test Conductor", m.Raw()["CONDUCTOR"])
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.m4a")
+	testValue(t, "", m.Conductor())
+	testValue(t, "", m.DiscSubtitle())
+	testValue(t, "", m.Label())
+	testValue(t, "", m.Media())
+	testValue(t, "", m.Script())
+	testValue(t, "", m.Language())
+	testValue(t, "", m.Mood())
+	testValue(t, "", m.ComposerSort())
+}
+
+func TestMP4ReplayGain(t *testing.T) {
+	tests := map[string]ReplayGainInfo{
+		"with_tags/sample.replaygain.m4a":       {TrackGain: -6.54, TrackPeak: 0.988547, AlbumGain: -7.03, AlbumPeak: 1},
+		"with_tags/sample.replaygain-upper.m4a": {TrackGain: 2.1, TrackPeak: 0.5},
+		"with_tags/sample.m4a":                  {},
+	}
+	for path, want := range tests {
+		m := readTestFile[MetadataMP4](t, path)
+		if got := m.ReplayGain(); got != want {
+			t.Errorf("%v: ReplayGain() = %+v, expected: %+v", path, got, want)
+		}
+	}
+}
+
+func TestMP4Provenance(t *testing.T) {
+	// sample.purchased.m4a has ownr and apID atoms, but no artist or album artist.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.purchased.m4a")
+
+	want := Provenance{
+		Owner:   "Test Owner",
+		AppleID: "test@example.com",
+	}
+	if got := m.Provenance(); got != want {
+		t.Errorf("Provenance() = %#v, expected: %#v", got, want)
+	}
+	testValue(t, "", m.Artist())
+	testValue(t, "", m.AlbumArtist())
+	testValue(t, "Test Title", m.Title())
+}
+
+func TestMP4Rating(t *testing.T) {
+	tests := []struct {
+		path  string
+		stars int
+		ok    bool
+	}{
+		{"with_tags/sample.rate.m4a", 4, true}, // rate 80
+		{"with_tags/sample.m4a", 0, false},
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[MetadataMP4](t, tt.path)
+		stars, ok := m.Rating()
+		if stars != tt.stars || ok != tt.ok {
+			t.Errorf("%v: Rating() = %d, %v, expected: %d, %v", tt.path, stars, ok, tt.stars, tt.ok)
+		}
+	}
+}
+
+func TestMP4Genre(t *testing.T) {
+	// Each of these is sample.m4a without ©gen.
+	tests := []struct {
+		path  string
+		genre string
+		id    int
+		ok    bool
+	}{
+		{"with_tags/sample.m4a", "Jazz", 0, false},
+		{"with_tags/sample.gnre.m4a", "Jazz", 0, false},        // gnre 9 (implicit class)
+		{"with_tags/sample.geid.m4a", "Rock", 21, true},        // geID 21
+		{"with_tags/sample.geid-unknown.m4a", "", 99999, true}, // geID not in AppleGenreByID
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[MetadataMP4](t, tt.path)
+		if got := m.Genre(); got != tt.genre {
+			t.Errorf("%v: Genre() = %q, expected: %q", tt.path, got, tt.genre)
+		}
+		id, ok := m.AppleGenreID()
+		if id != tt.id || ok != tt.ok {
+			t.Errorf("%v: AppleGenreID() = %d, %v, expected: %d, %v", tt.path, id, ok, tt.id, tt.ok)
+		}
+	}
+}
+
+func TestMP4Rights(t *testing.T) {
+	// sample.rights.m4a has a multi-line cprt, and a ©phg with two data atoms.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.rights.m4a")
+	testValue(t, "℗ 2000 Test Label\n© 2000 Test Label", m.Copyright())
+	testValue(t, "℗ 2000 Test Label;℗ 2001 Test Label", m.PhonogramRights())
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.m4a")
+	testValue(t, "", m.PhonogramRights())
+}
+
+// gapReaderAt is an io.ReaderAt which reads head, followed by gap zero bytes (which
+// are never stored), followed by tail.
+type gapReaderAt struct {
+	head, tail []byte
+	gap        int64
+}
+
+func (g *gapReaderAt) Size() int64 {
+	return int64(len(g.head)) + g.gap + int64(len(g.tail))
+}
+
+func (g *gapReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	h := int64(len(g.head))
+	n := 0
+	for n < len(p) && off < g.Size() {
+		var m int
+		switch {
+		case off < h:
+			m = copy(p[n:], g.head[off:])
+		case off < h+g.gap:
+			m = len(p) - n
+			if int64(m) > h+g.gap-off {
+				m = int(h + g.gap - off)
+			}
+			for i := n; i < n+m; i++ {
+				p[i] = 0
+			}
+		default:
+			m = copy(p[n:], g.tail[off-h-g.gap:])
+		}
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// newLargeMdatReader returns an io.ReadSeeker over sample.m4a with its 'mdat' atom
+// replaced by one with size bytes of data (followed by the 'moov' atom).
+func newLargeMdatReader(t testing.TB, size int64, largeSize bool) io.ReadSeeker {
+	b, err := os.ReadFile("testdata/with_tags/sample.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sample.m4a: ftyp (24 bytes), free (8 bytes), mdat, moov
+	head := append([]byte{}, b[:32]...)
+	mdatSize := binary.BigEndian.Uint32(b[32:36])
+	tail := b[32+mdatSize:]
+
+	if largeSize {
+		head = binary.BigEndian.AppendUint32(head, 1)
+		head = append(head, "mdat"...)
+		head = binary.BigEndian.AppendUint64(head, uint64(16+size))
+	} else {
+		head = binary.BigEndian.AppendUint32(head, uint32(8+size))
+		head = append(head, "mdat"...)
+	}
+
+	g := &gapReaderAt{head: head, tail: tail, gap: size}
+	return io.NewSectionReader(g, 0, g.Size())
+}
+
+func TestMP4LargeMdat(t *testing.T) {
+	tests := []struct {
+		size      int64
+		largeSize bool
+	}{
+		{3 << 30, false}, // 3GiB, 32-bit atom size
+		{8 << 30, true},  // 8GiB, 64-bit atom size
+	}
+
+	for _, tt := range tests {
+		m, err := ReadFrom(newLargeMdatReader(t, tt.size, tt.largeSize))
+		if err != nil {
+			t.Errorf("%d byte mdat: ReadFrom() returned unexpected error: %v", tt.size, err)
+			continue
+		}
+		compareMetadata(t, m, fullMetadata)
+
+		_, length, err := AudioRegion(newLargeMdatReader(t, tt.size, tt.largeSize))
+		if err != nil || length != tt.size {
+			t.Errorf("%d byte mdat: AudioRegion() length = %d, %v, expected: %d, <nil>", tt.size, length, err, tt.size)
+		}
+	}
+}
+
+func TestReadAtomHeader(t *testing.T) {
+	tests := []struct {
+		in   string
+		name string
+		size int64
+		err  bool
+	}{
+		{"\x00\x00\x00\x10free12345678", "free", 8, false},
+		{"\x00\x00\x00\x01mdat\x00\x00\x00\x02\x00\x00\x00\x00", "mdat", 1<<33 - 16, false},
+		{"\x00\x00\x00\x00mdat1234", "mdat", 4, false}, // to the end of the data
+		{"\x00\x00\x00\x04free", "", 0, true},
+		{"\x00\x00\x00\x01mdat\x00\x00\x00\x00\x00\x00\x00\x08", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		name, size, err := readAtomHeader(strings.NewReader(tt.in))
+		if name != tt.name || size != tt.size || (err != nil) != tt.err {
+			t.Errorf("readAtomHeader(%q) = %q, %d, %v, expected: %q, %d, error: %v", tt.in, name, size, err, tt.name, tt.size, tt.err)
+		}
+	}
+}
+
+func TestMP4MalformedAtomSizes(t *testing.T) {
+	ftyp := mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	withSize := func(b []byte, size uint32) []byte {
+		b = append([]byte(nil), b...)
+		binary.BigEndian.PutUint32(b, size)
+		return b
+	}
+	file := func(items ...[]byte) []byte {
+		return append(ftyp, mp4Atom("moov", mp4Atom("udta", mp4Atom("meta", make([]byte, 4), mp4Atom("ilst", items...))))...)
+	}
+	title := mp4Atom("\xa9nam", mp4Atom("data", []byte{0, 0, 0, 1}, make([]byte, 4), []byte("Test Title")))
+
+	tests := []struct {
+		name string
+		in   []byte
+		err  bool
+	}{
+		{"atom size less than header", append(ftyp, withSize(mp4Atom("free"), 4)...), true},
+		{"item size less than header", file(withSize(title, 7)), true},
+		{"item beyond ilst", file(withSize(title, 1000), title), true},
+		{"64-bit size less than header", append(ftyp, "\x00\x00\x00\x01mdat\x00\x00\x00\x00\x00\x00\x00\x08"...), true},
+		{"freeform child size less than header", file(mp4Atom("----", withSize(mp4Atom("mean", make([]byte, 8)), 3))), true},
+		{"freeform header-only children", file(mp4Atom("----", mp4Atom("mean"), mp4Atom("mean"), mp4Atom("mean"))), true},
+		{"trak child size less than header", append(ftyp, mp4Atom("moov", mp4Atom("trak", withSize(mp4Atom("tkhd", make([]byte, 8)), 5)))...), false},
+		{"header-only atoms", append(ftyp, bytes.Repeat(mp4Atom("free"), 10000)...), false},
+		{"ilst padding", file(title, make([]byte, 4)), false},
+	}
+
+	for _, tt := range tests {
+		done := make(chan error)
+		go func() {
+			_, err := ReadAtoms(bytes.NewReader(tt.in))
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if (err != nil) != tt.err {
+				t.Errorf("%v: ReadAtoms() = %v, expected error: %v", tt.name, err, tt.err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v: ReadAtoms() did not return", tt.name)
+		}
+	}
+}
+
+func TestMP4ExtendedAtomSize(t *testing.T) {
+	// sample.mdat64.m4a is sample.m4a with a 64-bit 'mdat' atom size.
+	sum := func(path string) string {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		s, err := Sum(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	if got, want := sum("with_tags/sample.mdat64.m4a"), sum("with_tags/sample.m4a"); got != want {
+		t.Errorf("Sum() = %v, expected: %v", got, want)
+	}
+}
+
+func BenchmarkReadFromLargeMdat(b *testing.B) {
+	r := newLargeMdatReader(b, 8<<30, true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Seek(0, io.SeekStart)
+		if _, err := ReadFrom(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMP4TrackDisc(t *testing.T) {
+	tests := []struct {
+		path                               string
+		track, trackTotal, disc, discTotal int
+	}{
+		{"with_tags/sample.m4a", 3, 6, 2, 0},
+		{"with_tags/sample.trkn-0of1.m4a", 0, 1, 0, 1},
+		{"with_tags/sample.trkn-1of0.m4a", 1, 0, 1, 0},
+		{"with_tags/sample.trkn-300.m4a", 300, 312, 2, 0},  // numbers wider than a byte
+		{"with_tags/sample.trkn-text.m4a", 3, 6, 2, 0},     // text "3/6" and "2"
+		{"with_tags/sample.trkn-freeform.m4a", 3, 6, 2, 0}, // short binary, freeform TRACKNUMBER/DISCNUMBER
+		{"with_tags/sample.trkn-short.m4a", 0, 0, 0, 0},    // short binary only
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[MetadataMP4](t, tt.path)
+		if n, total := m.Track(); n != tt.track || total != tt.trackTotal {
+			t.Errorf("%v: Track() = %d, %d, expected: %d, %d", tt.path, n, total, tt.track, tt.trackTotal)
+		}
+		if n, total := m.Disc(); n != tt.disc || total != tt.discTotal {
+			t.Errorf("%v: Disc() = %d, %d, expected: %d, %d", tt.path, n, total, tt.disc, tt.discTotal)
+		}
+	}
+}
+
+func TestMP4FileTypeCodec(t *testing.T) {
+	// sample.alac.m4a is sample.m4a with the audio sample description format changed
+	// to alac.  Both have the M4A brand.  sample.drms.m4p is sample.m4a with the format
+	// changed to drms (FairPlay protected AAC) and the M4P brand.
+	tests := []struct {
+		path      string
+		codec     string
+		fileType  FileType
+		protected bool
+	}{
+		{"with_tags/sample.m4a", "mp4a", M4A, false},
+		{"with_tags/sample.alac.m4a", "alac", ALAC, false},
+		{"with_tags/sample.drms.m4p", "drms", M4P, true},
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[MetadataMP4](t, tt.path)
+		if tracks := m.Tracks(); len(tracks) != 1 || tracks[0].Codec != tt.codec {
+			t.Errorf("%v: Tracks() = %+v, expected a single %v track", tt.path, tracks, tt.codec)
+		}
+		if got := m.FileType(); got != tt.fileType {
+			t.Errorf("%v: FileType() = %v, expected: %v", tt.path, got, tt.fileType)
+		}
+		if got := m.IsProtected(); got != tt.protected {
+			t.Errorf("%v: IsProtected() = %v, expected: %v", tt.path, got, tt.protected)
+		}
+		testValue(t, "Test Title", m.Title())
+	}
+}
+
+func TestMP4Tracks(t *testing.T) {
+	// sample.chapters.m4a has an audio track which references a chapter text track.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.chapters.m4a")
+
+	want := []MP4Track{
+		{ID: 1, Handler: "soun", Chapters: []uint32{2}, Codec: "mp4a"},
+		{ID: 2, Handler: "text"},
+	}
+	if got := m.Tracks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tracks() = %+v, expected: %+v", got, want)
+	}
+
+	if c, ok := m.ChapterTrack(); !ok || c.ID != 2 {
+		t.Errorf("ChapterTrack() = %+v, %v, expected track 2", c, ok)
+	}
+	if m.FileType() != M4A {
+		t.Errorf("FileType() = %v, expected: %v", m.FileType(), M4A)
+	}
+
+	// Without an iTunes brand the file type comes from the track handlers.
+	b, err := os.ReadFile("testdata/with_tags/sample.chapters.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(b[8:12], "mp42")
+	mm, err := ReadAtoms(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadAtoms() returned unexpected error: %v", err)
+	}
+	if mm.FileType() != M4A {
+		t.Errorf("mp42: FileType() = %v, expected: %v", mm.FileType(), M4A)
+	}
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.m4a")
+	if _, ok := m.ChapterTrack(); ok {
+		t.Error("ChapterTrack() returned ok for file without chapters")
+	}
+
+	// A malformed trak is recorded as an invalid track, and the rest of the file is read.
+	hdlr := mp4Atom("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 12))
+	bad := mp4Atom("trak", mp4Atom("tkhd", make([]byte, 8)))
+	binary.BigEndian.PutUint32(bad[8:], 5)
+	title := mp4Atom("\xa9nam", mp4Atom("data", []byte{0, 0, 0, 1}, make([]byte, 4), []byte("Test Title")))
+	b = append(mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4Atom("moov",
+		bad,
+		mp4Atom("trak", mp4Atom("mdia", hdlr)),
+		mp4Atom("udta", mp4Atom("meta", make([]byte, 4), mp4Atom("ilst", title))),
+	)...)
+	mm, err = ReadAtoms(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadAtoms() returned unexpected error for malformed trak: %v", err)
+	}
+	want = []MP4Track{{Invalid: true}, {Handler: "soun"}}
+	if got := mm.(MetadataMP4).Tracks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("malformed trak: Tracks() = %+v, expected: %+v", got, want)
+	}
+	testValue(t, "Test Title", mm.Title())
+}
+
+func TestParseISO6709(t *testing.T) {
+	tests := []struct {
+		in       string
+		lat, lon float64
+		ok       bool
+	}{
+		{"+37.7749-122.4194/", 37.7749, -122.4194, true},
+		{"+37.7749-122.4194+010.000/", 37.7749, -122.4194, true},
+		{"-33.8688+151.2093", -33.8688, 151.2093, true},
+		{"", 0, 0, false},
+		{"+37.7749/", 0, 0, false},
+		{"+97.0000+000.0000/", 0, 0, false},
+		{"37.7749,-122.4194", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		lat, lon, ok := parseISO6709(tt.in)
+		if lat != tt.lat || lon != tt.lon || ok != tt.ok {
+			t.Errorf("parseISO6709(%q) = %v, %v, %v, expected: %v, %v, %v", tt.in, lat, lon, ok, tt.lat, tt.lon, tt.ok)
+		}
+	}
+}
+
+func TestMP4Location(t *testing.T) {
+	// sample.location.m4a has a QuickTime style ©xyz atom in moov/udta.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.location.m4a")
+	lat, lon, ok := m.Location()
+	if !ok || lat != 37.7749 || lon != -122.4194 {
+		t.Errorf("Location() = %v, %v, %v, expected: 37.7749, -122.4194, true", lat, lon, ok)
+	}
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.m4a")
+	if _, _, ok := m.Location(); ok {
+		t.Error("Location() returned ok for file without ©xyz")
+	}
+}
+
+func TestMP4UserDataText(t *testing.T) {
+	// sample.udta.mov has no ilst items, only QuickTime style ©nam and ©ART atoms in
+	// moov/udta.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.udta.mov")
+	if got, want := m.Title(), "Test Title"; got != want {
+		t.Errorf("Title() = %q, expected: %q", got, want)
+	}
+	if got, want := m.Artist(), "Test Artist"; got != want {
+		t.Errorf("Artist() = %q, expected: %q", got, want)
+	}
+	if got := m.Album(); got != "" {
+		t.Errorf("Album() = %q, expected: %q", got, "")
+	}
+
+	// sample.udta-ilst.m4a has both, and the ilst title takes precedence.
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.udta-ilst.m4a")
+	if got, want := m.Title(), "Test Title"; got != want {
+		t.Errorf("Title() = %q, expected: %q", got, want)
+	}
+}
+
+func TestMP4UserDataCopyrightInformation(t *testing.T) {
+	// sample.udta-info.mov is sample.udta.mov with QuickTime style ©cpy and ©inf atoms in
+	// moov/udta, which are used when there is no cprt or ©cmt.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.udta-info.mov")
+	testValue(t, "2000 Test Label", m.Copyright())
+	testValue(t, "Test Information", m.Comment())
+
+	m = readTestFile[MetadataMP4](t, "with_tags/sample.udta.mov")
+	testValue(t, "", m.Copyright())
+	testValue(t, "", m.Comment())
+}
+
+func TestMP4LyricsLineEndings(t *testing.T) {
+	// sample.lyrics-crlf.m4a has ©lyr with \r\n and \r line endings.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.lyrics-crlf.m4a")
+	testValue(t, "Line one\nLine two\n\nLine three\nLine four", m.Lyrics())
+	if got := m.Raw()["\xa9lyr"]; got != "Line one\r\nLine two\r\n\r\nLine three\rLine four" {
+		t.Errorf("Raw()[\"\\xa9lyr\"] = %q, expected the lyrics unchanged", got)
+	}
+}
+
+func TestMP4NestedMeta(t *testing.T) {
+	// sample.meta-nested.m4a is sample.m4a with 'meta' atoms in the trak (in trak/udta),
+	// directly in moov (before moov/udta) and at the top level (after moov), each with a
+	// different ©nam.  Only the moov/udta/meta items are file-level metadata.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.meta-nested.m4a")
+	compareMetadata(t, m, fullMetadata)
+	for k, v := range m.Raw() {
+		if s, ok := v.(string); ok && strings.Contains(s, "Meta") {
+			t.Errorf("Raw()[%q] = %q, expected only file-level metadata", k, s)
+		}
+	}
+	if got := len(m.Tracks()); got != 1 {
+		t.Errorf("len(Tracks()) = %d, expected: 1", got)
+	}
+}
+
+func TestMP4UnknownAtoms(t *testing.T) {
+	// sample.unknown-atoms.m4a has an unmapped soal item and a freeform item with an
+	// unrecognised mean, followed by ©nam.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.unknown-atoms.m4a")
+
+	soal, ok := m.Raw()["soal"].([]byte)
+	if !ok || !bytes.HasSuffix(soal, []byte("Album, Test")) {
+		t.Errorf("Raw()[%q] = %#v, expected data atom containing %q", "soal", m.Raw()["soal"], "Album, Test")
+	}
+	freeform, ok := m.Raw()["----"].([]byte)
+	if !ok || !bytes.Contains(freeform, []byte("com.example.app")) {
+		t.Errorf("Raw()[%q] = %#v, expected freeform atom data", "----", m.Raw()["----"])
+	}
+	testValue(t, "Test Title", m.Title())
+
+	// Atoms outside ilst (i.e. mdat) are not kept.
+	if _, ok := m.Raw()["mdat"]; ok {
+		t.Errorf("Raw() contains %q", "mdat")
+	}
+}
+
+func TestMP4Artists(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		// Picard style ARTISTS atom with a data atom per artist.
+		{"with_tags/sample.artists.m4a", []string{"Test Artist", "Other; Artist"}},
+		{"with_tags/sample.m4a", []string{"Test Artist"}},
+		{"without_tags/sample.m4a", nil},
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[MetadataMP4](t, tt.path)
+		if got := m.Artists(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: Artists() = %q, expected: %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReadAtomsLazy(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.covr.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadAtomsLazy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareMetadata(t, m, fullMetadata)
+	if _, ok := m.(MetadataMP4).data["covr"]; ok {
+		t.Errorf("ReadAtomsLazy() read 'covr' before it was needed")
+	}
+
+	eager := readTestFile[MetadataMP4](t, "with_tags/sample.covr.m4a")
+	if got, want := m.Picture(), eager.Picture(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Picture() = %v, expected: %v", got, want)
+	}
+	if got, want := m.(MetadataMP4).PictureSummary(), eager.PictureSummary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PictureSummary() = %v, expected: %v", got, want)
+	}
+	if got, want := m.Raw()["covr"], eager.Raw()["covr"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Raw()[\"covr\"] = %v, expected: %v", got, want)
+	}
+	if _, ok := m.(MetadataMP4).data["covr"]; ok {
+		t.Errorf("ReadAtomsLazy() added 'covr' to the shared data")
+	}
+	if err := m.(MetadataMP4).CoverErr(); err != nil {
+		t.Errorf("CoverErr() = %v, expected: nil", err)
+	}
+}
+
+func TestReadAtomsLazyPictureSummary(t *testing.T) {
+	data := func(class uint32, b []byte) []byte {
+		return mp4Atom("data", binary.BigEndian.AppendUint32(nil, class), make([]byte, 4), b)
+	}
+	m4a := func(covr ...[]byte) []byte {
+		ilst := mp4Atom("ilst", mp4Atom("covr", covr...))
+		return append(
+			mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")),
+			mp4Atom("moov", mp4Atom("udta", mp4Atom("meta", make([]byte, 4), ilst)))...,
+		)
+	}
+	fixture, err := os.ReadFile("testdata/with_tags/sample.covr.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"sample.covr.m4a": fixture,
+		"implicit": m4a(
+			data(0, append(append([]byte(nil), pngHeader...), 1, 2, 3)),
+			data(0, []byte("GIF89a")),
+			data(0, []byte{0xFF, 0xD8}), // too short to detect, so skipped
+			data(1, []byte("text")),
+			data(14, nil),
+		),
+	}
+	for name, b := range tests {
+		eager, err := ReadAtoms(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%v: ReadAtoms() returned unexpected error: %v", name, err)
+		}
+		m, err := ReadAtomsLazy(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%v: ReadAtomsLazy() returned unexpected error: %v", name, err)
+		}
+
+		want := eager.(MetadataMP4).PictureSummary()
+		if len(want) == 0 {
+			t.Fatalf("%v: PictureSummary() = nil, expected pictures", name)
+		}
+		if got := m.(MetadataMP4).PictureSummary(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: PictureSummary() = %+v, expected: %+v", name, got, want)
+		}
+		if m.(MetadataMP4).lazy.covers != nil {
+			t.Errorf("%v: PictureSummary() loaded the cover art", name)
+		}
+	}
+}
+
+func TestReadAtomsLazyConcurrent(t *testing.T) {
+	m, err := ReadAtomsLazy(bytes.NewReader(newCoverHeavyM4A(1 << 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if m.Picture() == nil {
+				t.Errorf("Picture() = nil, expected a picture")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if s := m.(MetadataMP4).PictureSummary(); len(s) != 1 {
+				t.Errorf("PictureSummary() = %+v, expected one picture", s)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if m.Title() != "Test Title" {
+				t.Errorf("Title() = %q, expected: %q", m.Title(), "Test Title")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReadAtomsLazyCoverErr(t *testing.T) {
+	b := newCoverHeavyM4A(1 << 10)
+	m, err := ReadAtomsLazy(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replace the reader with one which ends before the cover art.
+	m.(MetadataMP4).lazy.r = bytes.NewReader(b[:len(b)/2])
+
+	if p := m.Picture(); p != nil {
+		t.Errorf("Picture() = %v, expected: nil", p)
+	}
+	if err := m.(MetadataMP4).CoverErr(); err == nil {
+		t.Errorf("CoverErr() = nil, expected an error")
+	}
+	if got := m.Title(); got != "Test Title" {
+		t.Errorf("Title() = %q, expected: %q", got, "Test Title")
+	}
+}
+
+// mp4Atom returns an atom with the given name and data.
+func mp4Atom(name string, data ...[]byte) []byte {
+	b := bytes.Join(data, nil)
+	return append(binary.BigEndian.AppendUint32(nil, uint32(8+len(b))), append([]byte(name), b...)...)
+}
+
+// newCoverHeavyM4A returns an M4A file (without audio) with a title and a size byte cover.
+func newCoverHeavyM4A(size int) []byte {
+	data := func(class uint32, b []byte) []byte {
+		return mp4Atom("data", binary.BigEndian.AppendUint32(nil, class), make([]byte, 4), b)
+	}
+	cover := append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, size-3)...)
+	ilst := mp4Atom("ilst",
+		mp4Atom("\xa9nam", data(1, []byte("Test Title"))),
+		mp4Atom("covr", data(13, cover)),
+	)
+	return append(
+		mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")),
+		mp4Atom("moov", mp4Atom("udta", mp4Atom("meta", make([]byte, 4), ilst)))...,
+	)
+}
+
+func BenchmarkReadAtomsCover(b *testing.B) {
+	r := bytes.NewReader(newCoverHeavyM4A(4 << 20))
+
+	for _, bb := range []struct {
+		name string
+		read func(io.ReadSeeker) (Metadata, error)
+	}{
+		{"Eager", ReadAtoms},
+		{"Lazy", ReadAtomsLazy},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Seek(0, io.SeekStart)
+				m, err := bb.read(r)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if m.Title() != "Test Title" {
+					b.Fatalf("Title() = %q, expected: %q", m.Title(), "Test Title")
+				}
+			}
+		})
+	}
+}
@@ -10,13 +10,38 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
 var (
-	vorbisCommentPrefix = []byte("\x03vorbis")
-	opusTagsPrefix      = []byte("OpusTags")
+	vorbisIdentificationPrefix = []byte("\x01vorbis")
+	vorbisCommentPrefix        = []byte("\x03vorbis")
+	opusHeadPrefix             = []byte("OpusHead")
+	opusTagsPrefix             = []byte("OpusTags")
+	oggFLACPrefix              = []byte("\x7fFLAC")
+	speexHeaderPrefix          = []byte("Speex   ")
 )
 
+// ErrUnsupportedOGGCodec is the error returned (wrapped) by ReadOGGTags when the OGG
+// stream does not contain a supported codec (Vorbis, Opus, FLAC or Speex).
+// errors.Is(ErrUnsupportedOGGCodec, ErrUnsupportedFormat) is true.
+var ErrUnsupportedOGGCodec error = unsupportedOGGCodecError{}
+
+type unsupportedOGGCodecError struct{}
+
+func (unsupportedOGGCodecError) Error() string { return "unsupported OGG codec" }
+
+// Is reports whether target is ErrUnsupportedFormat.
+func (unsupportedOGGCodecError) Is(target error) bool { return target == ErrUnsupportedFormat }
+
+// oggNoGranulePosition is the granule position of pages on which no packet completes
+// (i.e. header pages, or pages containing only part of a packet).
+const oggNoGranulePosition = ^uint64(0)
+
+// opusSampleRate is the rate at which Opus granule positions are counted, regardless
+// of the input sample rate (see https://tools.ietf.org/html/rfc7845#section-4).
+const opusSampleRate = 48000
+
 var oggCRC32Poly04c11db7 = oggCRCTable(0x04c11db7)
 
 type crc32Table [256]uint32
@@ -57,16 +82,21 @@ type oggPageHeader struct {
 	Segments        uint8
 }
 
-type oggDemuxer struct {
-	packetBufs map[uint32]*bytes.Buffer
+// oggPage is a page of an OGG stream.
+type oggPage struct {
+	oggPageHeader
+	segmentTable []byte // lacing values
+	data         []byte // segment data
 }
 
-// Read ogg packets, can return empty slice of packets and nil err
-// if more data is needed
-func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
+// readOGGPage reads an OGG page from r, and checks its CRC.
+func readOGGPage(r io.Reader) (*oggPage, error) {
 	headerBuf := &bytes.Buffer{}
 	var oh oggPageHeader
 	if err := binary.Read(io.TeeReader(r, headerBuf), binary.LittleEndian, &oh); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("reading page header: %w", ErrTruncated)
+		}
 		return nil, err
 	}
 
@@ -77,6 +107,9 @@ func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
 
 	segmentTable := make([]byte, oh.Segments)
 	if _, err := io.ReadFull(r, segmentTable); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("reading segment table of page %d: %w", oh.SequenceNumber, ErrTruncated)
+		}
 		return nil, err
 	}
 	var segmentsSize int64
@@ -85,6 +118,9 @@ func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
 	}
 	segmentsData := make([]byte, segmentsSize)
 	if _, err := io.ReadFull(r, segmentsData); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("reading %d bytes of segment data of page %d: %w", segmentsSize, oh.SequenceNumber, ErrTruncated)
+		}
 		return nil, err
 	}
 
@@ -101,6 +137,26 @@ func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
 		return nil, fmt.Errorf("expected crc %x != %x", oh.CRC, crc)
 	}
 
+	return &oggPage{
+		oggPageHeader: oh,
+		segmentTable:  segmentTable,
+		data:          segmentsData,
+	}, nil
+}
+
+type oggDemuxer struct {
+	packetBufs map[uint32]*bytes.Buffer
+}
+
+// Read ogg packets, can return empty slice of packets and nil err
+// if more data is needed
+func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
+	page, err := readOGGPage(r)
+	if err != nil {
+		return nil, err
+	}
+	oh := page.oggPageHeader
+
 	if o.packetBufs == nil {
 		o.packetBufs = map[uint32]*bytes.Buffer{}
 	}
@@ -119,8 +175,8 @@ func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
 
 	var packets [][]byte
 	var p int
-	for _, s := range segmentTable {
-		packetBuf.Write(segmentsData[p : p+int(s)])
+	for _, s := range page.segmentTable {
+		packetBuf.Write(page.data[p : p+int(s)])
 		if s < 255 {
 			packets = append(packets, packetBuf.Bytes())
 			packetBuf = &bytes.Buffer{}
@@ -138,37 +194,431 @@ func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
 // See http://www.xiph.org/vorbis/doc/Vorbis_I_spec.html
 // and http://www.xiph.org/ogg/doc/framing.html for details.
 // For Opus see https://tools.ietf.org/html/rfc7845
+// Ogg FLAC streams are read with the FLAC metadata block reader and returned as
+// *MetadataFLAC, and Speex streams are read from their comment header.  Other codecs
+// return an error wrapping ErrUnsupportedOGGCodec.
 func ReadOGGTags(r io.Reader) (Metadata, error) {
 	od := &oggDemuxer{}
+	m := &MetadataOGG{
+		metadataVorbis: newMetadataVorbis(),
+		fileType:       OGG,
+	}
+
+	// Keep the source (if it can be seeked back to) for WriteTo.
+	if rs, ok := r.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		m.src, m.start = rs, start
+	}
+
+	// The first packet is the identification header, which determines the codec.
+	var first []byte
 	for {
 		bs, err := od.Read(r)
 		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("%w: identification header %q", ErrUnsupportedOGGCodec, oggPacketMagic(first))
+			}
 			return nil, err
 		}
 
-		for _, b := range bs {
+		for i, b := range bs {
+			if first == nil {
+				first = b
+				switch {
+				case bytes.HasPrefix(b, oggFLACPrefix):
+					return readOGGFLACTags(od, r, bs[i:])
+
+				case bytes.HasPrefix(b, speexHeaderPrefix):
+					// The Speex comment header is the second packet, and is a Vorbis
+					// comment block without a packet type or framing bit.
+					b, _, err := readOGGPacket(od, r, bs[i+1:])
+					if err != nil {
+						return nil, err
+					}
+					m.commentPrefix = []byte{}
+					err = m.readVorbisComment(bytes.NewReader(b))
+					return m, err
+				}
+			}
+
 			switch {
-			case bytes.HasPrefix(b, vorbisCommentPrefix):
-				m := &metadataOGG{
-					newMetadataVorbis(),
+			case bytes.HasPrefix(b, vorbisIdentificationPrefix):
+				err = m.readVorbisIdentification(b[len(vorbisIdentificationPrefix):])
+				if err != nil {
+					return nil, err
 				}
+			case bytes.HasPrefix(b, opusHeadPrefix):
+				m.fileType = OPUS
+				err = m.readOpusHead(b[len(opusHeadPrefix):])
+				if err != nil {
+					return nil, err
+				}
+			case bytes.HasPrefix(b, vorbisCommentPrefix):
+				m.commentPrefix = vorbisCommentPrefix
 				err = m.readVorbisComment(bytes.NewReader(b[len(vorbisCommentPrefix):]))
 				return m, err
 			case bytes.HasPrefix(b, opusTagsPrefix):
-				m := &metadataOGG{
-					newMetadataVorbis(),
+				// OpusTags is a Vorbis comment block, without the framing bit.
+				m.fileType = OPUS
+				m.commentPrefix = opusTagsPrefix
+				br := bytes.NewReader(b[len(opusTagsPrefix):])
+				err = m.readVorbisComment(br)
+				if err == nil && br.Len() > 0 {
+					m.commentSuffix = b[len(b)-br.Len():]
 				}
-				err = m.readVorbisComment(bytes.NewReader(b[len(opusTagsPrefix):]))
 				return m, err
 			}
 		}
 	}
 }
 
-type metadataOGG struct {
+// oggPacketMagic returns the first 8 bytes of the packet b, to identify the codec in errors.
+func oggPacketMagic(b []byte) []byte {
+	if len(b) > 8 {
+		return b[:8]
+	}
+	return b
+}
+
+// readOGGPacket returns the first of the packets bs (reading the following pages if there
+// are none) and the remaining packets.
+func readOGGPacket(od *oggDemuxer, r io.Reader, bs [][]byte) ([]byte, [][]byte, error) {
+	for len(bs) == 0 {
+		var err error
+		bs, err = od.Read(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil, nil, fmt.Errorf("reading packet: %w", ErrTruncated)
+			}
+			return nil, nil, err
+		}
+	}
+	return bs[0], bs[1:], nil
+}
+
+// readOGGFLACTags reads the FLAC metadata blocks of an Ogg FLAC stream, where bs are the
+// packets starting with the identification header.  Each further header packet is a single
+// metadata block.
+// See https://xiph.org/flac/ogg_mapping.html
+// Packet type              <8> (0x7F)
+// Signature                <4 bytes> ("FLAC")
+// Version                  <16>
+// Number of header packets <16>
+// fLaC signature           <4 bytes>
+// STREAMINFO block
+func readOGGFLACTags(od *oggDemuxer, r io.Reader, bs [][]byte) (Metadata, error) {
+	b := bs[0]
+	if len(b) < 13 || string(b[9:13]) != "fLaC" {
+		return nil, errors.New("invalid Ogg FLAC identification header")
+	}
+
+	m := &MetadataFLAC{
+		metadataVorbis: newMetadataVorbis(),
+	}
+	last, err := m.readFLACMetadataBlock(bytes.NewReader(b[13:]))
+	if err != nil {
+		return nil, err
+	}
+
+	bs = bs[1:]
+	for !last {
+		b, bs, err = readOGGPacket(od, r, bs)
+		if err != nil {
+			return nil, err
+		}
+		last, err = m.readFLACMetadataBlock(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// MetadataOGG is the implementation of Metadata for OGG files.  The Metadata returned
+// by ReadOGGTags can be asserted to *MetadataOGG to access OGG-specific metadata.
+type MetadataOGG struct {
 	*metadataVorbis
+
+	fileType   FileType // OGG (Vorbis) or OPUS
+	streamInfo VorbisStreamInfo
+	opusInfo   OpusStreamInfo
+
+	src           io.ReadSeeker // the stream the metadata was read from, if it can be seeked
+	start         int64         // offset of the stream in src
+	commentPrefix []byte        // prefix of the comment header packet (empty for Speex)
+	commentSuffix []byte        // data after the comments in an OpusTags packet
 }
 
-func (m *metadataOGG) FileType() FileType {
-	return OGG
+// VorbisStreamInfo is a type which contains the information from the Vorbis identification
+// header.  Bitrates are in bits per second, and are 0 if unset.
+type VorbisStreamInfo struct {
+	Channels       int // Number of channels.
+	SampleRate     int // Sample rate in Hz.
+	MaximumBitrate int // Maximum bitrate, if the stream is limited.
+	NominalBitrate int // Nominal (average) bitrate.
+	MinimumBitrate int // Minimum bitrate, if the stream is limited.
+}
+
+// Vorbis identification header (after the packet type and "vorbis")
+// Version           <32>
+// Channels          <8>
+// Sample rate       <32>
+// Bitrate maximum   <32> (signed)
+// Bitrate nominal   <32> (signed)
+// Bitrate minimum   <32> (signed)
+// Block sizes       <8>
+// Framing flag      <8>
+func (m *MetadataOGG) readVorbisIdentification(b []byte) error {
+	if len(b) < 21 {
+		return fmt.Errorf("invalid Vorbis identification header: expected at least %d bytes, got %d", 21, len(b))
+	}
+
+	// Negative (and zero) bitrates mean the value is unset.
+	bitrate := func(b []byte) int {
+		n := int32(binary.LittleEndian.Uint32(b))
+		if n < 0 {
+			return 0
+		}
+		return int(n)
+	}
+
+	m.streamInfo = VorbisStreamInfo{
+		Channels:       int(b[4]),
+		SampleRate:     int(binary.LittleEndian.Uint32(b[5:9])),
+		MaximumBitrate: bitrate(b[9:13]),
+		NominalBitrate: bitrate(b[13:17]),
+		MinimumBitrate: bitrate(b[17:21]),
+	}
+	return nil
+}
+
+// OpusStreamInfo is a type which contains the information from the Opus identification
+// header (OpusHead).  Opus is always decoded at 48kHz, whatever the input sample rate.
+// Decoders apply the output gain, and the R128 gains (see ReplayGain) are relative to
+// the output with it applied.
+type OpusStreamInfo struct {
+	Version         int     // Version of the header (1).
+	Channels        int     // Number of output channels.
+	PreSkip         int     // Number of samples (at 48kHz) to discard from the start.
+	InputSampleRate int     // Sample rate of the original input in Hz, or 0 if unspecified.
+	OutputGain      float64 // Gain to apply to the decoded output in dB.
+
+	// ChannelMappingFamily is 0 for mono or stereo, 1 for surround (up to 8 channels, in
+	// the Vorbis channel order) and 255 for unspecified channels.
+	ChannelMappingFamily int
+	StreamCount          int    // Number of Opus streams.
+	CoupledCount         int    // Number of the streams which are stereo (coupled).
+	ChannelMapping       []byte // Decoded channel for each output channel (nil for family 0).
+}
+
+// OpusHead (after "OpusHead")
+// Version                <8>
+// Output channel count   <8>
+// Pre-skip               <16>
+// Input sample rate      <32>
+// Output gain            <16> (signed, Q7.8 in dB)
+// Channel mapping family <8>
+// Channel mapping table (for channel mapping family other than 0):
+//
+//	Stream count    <8>
+//	Coupled count   <8>
+//	Channel mapping <8> * output channel count
+func (m *MetadataOGG) readOpusHead(b []byte) error {
+	if len(b) < 11 {
+		return fmt.Errorf("invalid Opus identification header: expected at least %d bytes, got %d", 11, len(b))
+	}
+
+	info := OpusStreamInfo{
+		Version:              int(b[0]),
+		Channels:             int(b[1]),
+		PreSkip:              int(binary.LittleEndian.Uint16(b[2:4])),
+		InputSampleRate:      int(binary.LittleEndian.Uint32(b[4:8])),
+		OutputGain:           float64(int16(binary.LittleEndian.Uint16(b[8:10]))) / 256,
+		ChannelMappingFamily: int(b[10]),
+		StreamCount:          1,
+	}
+	if info.Channels == 2 {
+		info.CoupledCount = 1
+	}
+
+	if info.ChannelMappingFamily != 0 {
+		b = b[11:]
+		if len(b) < 2+info.Channels {
+			return fmt.Errorf("invalid Opus channel mapping table: expected %d bytes, got %d", 2+info.Channels, len(b))
+		}
+		info.StreamCount = int(b[0])
+		info.CoupledCount = int(b[1])
+		info.ChannelMapping = b[2 : 2+info.Channels]
+	}
+	m.opusInfo = info
+	return nil
+}
+
+// OpusStreamInfo returns the information from the Opus identification header, which is
+// zero for other codecs (i.e. Vorbis).
+func (m *MetadataOGG) OpusStreamInfo() OpusStreamInfo {
+	return m.opusInfo
+}
+
+// Channels returns the number of channels from the Vorbis or Opus identification header,
+// or 0 if unavailable.
+func (m *MetadataOGG) Channels() int {
+	if m.fileType == OPUS {
+		return m.opusInfo.Channels
+	}
+	return m.streamInfo.Channels
+}
+
+// StreamInfo returns the information from the Vorbis identification header, which is
+// zero for other codecs (i.e. Opus).
+func (m *MetadataOGG) StreamInfo() VorbisStreamInfo {
+	return m.streamInfo
+}
+
+// SampleRate returns the sample rate in Hz, or 0 if unavailable.
+func (m *MetadataOGG) SampleRate() int {
+	return m.streamInfo.SampleRate
+}
+
+// FileType returns OPUS for Opus streams, and OGG otherwise.
+func (m *MetadataOGG) FileType() FileType {
+	return m.fileType
+}
+
+// identifyOGG returns OPUS if the first packet in the OGG stream is an Opus identification
+// header, FLAC if it is an Ogg FLAC identification header (see ReadOGGTags), and OGG
+// otherwise (including if the first page cannot be read).  r is returned to its original
+// position.
+func identifyOGG(r io.ReadSeeker) (FileType, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return UnknownFileType, err
+	}
+
+	od := &oggDemuxer{}
+	bs, readErr := od.Read(r)
+
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return UnknownFileType, err
+	}
+	if readErr == nil && len(bs) > 0 {
+		switch {
+		case bytes.HasPrefix(bs[0], opusHeadPrefix):
+			return OPUS, nil
+		case bytes.HasPrefix(bs[0], oggFLACPrefix):
+			return FLAC, nil
+		}
+	}
+	return OGG, nil
+}
+
+// ReadOGGDuration reads the OGG stream from the io.ReadSeeker and returns the duration of the
+// audio, computed from the last valid granule position in the stream.  Pages which carry no
+// granule position (-1) are ignored, as is a final page which has been truncated.
+func ReadOGGDuration(r io.ReadSeeker) (time.Duration, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	od := &oggDemuxer{}
+	bs, err := od.Read(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(bs) == 0 {
+		return 0, errors.New("expected identification header in first OGG page")
+	}
+
+	// The identification header is always the first packet of the first page.
+	var rate, preSkip uint64
+	b := bs[0]
+	switch {
+	case bytes.HasPrefix(b, vorbisIdentificationPrefix):
+		// version <32>, channels <8>, sample rate <32>
+		if len(b) < len(vorbisIdentificationPrefix)+9 {
+			return 0, errors.New("invalid Vorbis identification header")
+		}
+		rate = uint64(binary.LittleEndian.Uint32(b[len(vorbisIdentificationPrefix)+5:]))
+
+	case bytes.HasPrefix(b, opusHeadPrefix):
+		// version <8>, channels <8>, pre-skip <16>
+		if len(b) < len(opusHeadPrefix)+4 {
+			return 0, errors.New("invalid Opus identification header")
+		}
+		rate = opusSampleRate
+		preSkip = uint64(binary.LittleEndian.Uint16(b[len(opusHeadPrefix)+2:]))
+
+	default:
+		return 0, errors.New("unsupported OGG stream: expected Vorbis or Opus identification header")
+	}
+	if rate == 0 {
+		return 0, errors.New("invalid sample rate: 0")
+	}
+
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the stream is truncated, the granule position of the last complete
+	// page is used.
+	var serial uint32
+	var granule uint64
+	for first := true; ; first = false {
+		var oh oggPageHeader
+		if err := binary.Read(r, binary.LittleEndian, &oh); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return 0, err
+		}
+		if !bytes.Equal(oh.Magic[:], []byte("OggS")) {
+			return 0, errors.New("expected 'OggS'")
+		}
+
+		segmentTable, err := readBytes(r, uint(oh.Segments))
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return 0, err
+		}
+		var segmentsSize int64
+		for _, s := range segmentTable {
+			segmentsSize += int64(s)
+		}
+		pos, err := r.Seek(segmentsSize, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		if pos > end {
+			break
+		}
+
+		if first {
+			serial = oh.SerialNumber
+		}
+		if oh.SerialNumber == serial && oh.GranulePosition != oggNoGranulePosition {
+			granule = oh.GranulePosition
+		}
+	}
+
+	if granule < preSkip {
+		return 0, nil
+	}
+	granule -= preSkip
+
+	secs := granule / rate
+	rem := granule % rate
+	return time.Duration(secs)*time.Second + time.Duration(rem)*time.Second/time.Duration(rate), nil
 }
@@ -0,0 +1,305 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadOGGDuration(t *testing.T) {
+	// 149880 samples at 44.1kHz.
+	want := 3398639455 * time.Nanosecond
+
+	for _, path := range []string{
+		"with_tags/sample.ogg",
+		"with_tags/sample.multipage.ogg", // header page with granule position -1
+		"with_tags/sample.granule.ogg",   // last page with granule position -1
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ReadOGGDuration(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadOGGDuration() returned unexpected error: %v", path, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%v: ReadOGGDuration() = %v, expected: %v", path, got, want)
+		}
+	}
+}
+
+func TestOGGTruncated(t *testing.T) {
+	// sample.truncated.ogg is sample.ogg truncated part way through the last page.
+	f, err := os.Open("testdata/with_tags/sample.truncated.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// 140352 samples (the last complete page) at 44.1kHz.
+	want := 3182585034 * time.Nanosecond
+	got, err := ReadOGGDuration(f)
+	if err != nil {
+		t.Fatalf("ReadOGGDuration() returned unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("ReadOGGDuration() = %v, expected: %v", got, want)
+	}
+
+	// Truncate part way through the comment page.
+	b, err := os.ReadFile("testdata/with_tags/sample.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadOGGTags(bytes.NewReader(b[:2000]))
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("ReadOGGTags() returned error %v, expected: %v", err, ErrTruncated)
+	}
+}
+
+func TestOpusR128ReplayGain(t *testing.T) {
+	// R128_TRACK_GAIN=-1536 (-6dB) and R128_ALBUM_GAIN=512 (+2dB), relative to -23 LUFS.
+	f, err := os.Open("testdata/with_tags/sample.r128.opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+
+	r, ok := m.(ReplayGainMetadata)
+	if !ok {
+		t.Fatalf("%T does not implement ReplayGainMetadata", m)
+	}
+	want := ReplayGainInfo{TrackGain: -1, AlbumGain: 7}
+	if got := r.ReplayGain(); got != want {
+		t.Errorf("ReplayGain() = %+v, expected: %+v", got, want)
+	}
+}
+
+func TestVorbisMixedCaseKeys(t *testing.T) {
+	// sample.mixedcase.opus uses "Title", "ARTIST", "album", "TrackNumber", ...
+	f, err := os.Open("testdata/with_tags/sample.mixedcase.opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() returned unexpected error: %v", err)
+	}
+	compareMetadata(t, m, fullMetadata)
+
+	raw := m.Raw()
+	if raw["artist"] != "Test Artist" {
+		t.Errorf("Raw()[\"artist\"] = %v, expected: %q", raw["artist"], "Test Artist")
+	}
+	if _, ok := raw["ARTIST"]; ok {
+		t.Error("Raw() contains \"ARTIST\", expected lower case keys only")
+	}
+}
+
+func TestOGGStreamInfo(t *testing.T) {
+	tests := []struct {
+		path string
+		want VorbisStreamInfo
+	}{
+		{"with_tags/sample.ogg", VorbisStreamInfo{Channels: 2, SampleRate: 44100, NominalBitrate: 64000}},
+		// Maximum 128000, nominal 96000 and minimum -1 (unset).
+		{"with_tags/sample.bitrate.ogg", VorbisStreamInfo{Channels: 2, SampleRate: 44100, MaximumBitrate: 128000, NominalBitrate: 96000}},
+		{"with_tags/sample.r128.opus", VorbisStreamInfo{}},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+
+		ogg, ok := m.(*MetadataOGG)
+		if !ok {
+			t.Errorf("%v: ReadFrom() returned %T, expected *MetadataOGG", tt.path, m)
+			continue
+		}
+		if got := ogg.StreamInfo(); got != tt.want {
+			t.Errorf("%v: StreamInfo() = %#v, expected: %#v", tt.path, got, tt.want)
+		}
+		if got := ogg.SampleRate(); got != tt.want.SampleRate {
+			t.Errorf("%v: SampleRate() = %d, expected: %d", tt.path, got, tt.want.SampleRate)
+		}
+	}
+}
+
+func TestOpusFileType(t *testing.T) {
+	tests := []struct {
+		path     string
+		fileType FileType
+	}{
+		{"with_tags/sample.ogg", OGG},
+		{"with_tags/sample.r128.opus", OPUS},
+		{"with_tags/sample.mixedcase.opus", OPUS},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+		} else if got := m.FileType(); got != tt.fileType {
+			t.Errorf("%v: FileType() = %v, expected: %v", tt.path, got, tt.fileType)
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		format, fileType, err := Identify(f)
+		if err != nil || format != VORBIS || fileType != tt.fileType {
+			t.Errorf("%v: Identify() = %v, %v, %v, expected: %v, %v, <nil>", tt.path, format, fileType, err, VORBIS, tt.fileType)
+		}
+		f.Close()
+	}
+
+	// 48000 samples after a pre-skip of 312, counted at 48kHz regardless of the 44.1kHz
+	// input sample rate in OpusHead.
+	f, err := os.Open("testdata/with_tags/sample.r128.opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got, err := ReadOGGDuration(f); err != nil || got != time.Second {
+		t.Errorf("ReadOGGDuration() = %v, %v, expected: %v, <nil>", got, err, time.Second)
+	}
+}
+
+func TestReadOGGTagsCodecs(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.oga")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadOGGTags(f)
+	if err != nil {
+		t.Fatalf("ReadOGGTags() returned unexpected error: %v", err)
+	}
+	mf, ok := m.(*MetadataFLAC)
+	if !ok {
+		t.Fatalf("ReadOGGTags() returned %T, expected: *MetadataFLAC", m)
+	}
+	if got := mf.StreamInfo().SampleRate; got != 11025 {
+		t.Errorf("StreamInfo().SampleRate = %v, expected: %v", got, 11025)
+	}
+
+	f, err = os.Open("testdata/with_tags/sample.theora.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = ReadOGGTags(f)
+	if !errors.Is(err, ErrUnsupportedOGGCodec) {
+		t.Errorf("ReadOGGTags() returned error: %v, expected: %v", err, ErrUnsupportedOGGCodec)
+	}
+}
+
+func TestOpusStreamInfo(t *testing.T) {
+	tests := []struct {
+		path string
+		want OpusStreamInfo
+	}{
+		{"with_tags/sample.r128.opus", OpusStreamInfo{
+			Version:         1,
+			Channels:        2,
+			PreSkip:         312,
+			InputSampleRate: 44100,
+			StreamCount:     1,
+			CoupledCount:    1,
+		}},
+		// 5.1 surround, with an output gain of -1 dB.
+		{"with_tags/sample.surround.opus", OpusStreamInfo{
+			Version:              1,
+			Channels:             6,
+			PreSkip:              3840,
+			InputSampleRate:      48000,
+			OutputGain:           -1,
+			ChannelMappingFamily: 1,
+			StreamCount:          4,
+			CoupledCount:         2,
+			ChannelMapping:       []byte{0, 4, 1, 2, 3, 5},
+		}},
+		{"with_tags/sample.ogg", OpusStreamInfo{}},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+
+		ogg := m.(*MetadataOGG)
+		if got := ogg.OpusStreamInfo(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: OpusStreamInfo() = %#v, expected: %#v", tt.path, got, tt.want)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"with_tags/sample.r128.opus":     2,
+		"with_tags/sample.surround.opus": 6,
+		"with_tags/sample.ogg":           2,
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.(*MetadataOGG).Channels(); got != want {
+			t.Errorf("%v: Channels() = %d, expected: %d", path, got, want)
+		}
+	}
+
+	// 48000 samples after a pre-skip of 3840.
+	f, err := os.Open("testdata/with_tags/sample.surround.opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got, err := ReadOGGDuration(f); err != nil || got != time.Second {
+		t.Errorf("ReadOGGDuration() = %v, %v, expected: %v, <nil>", got, err, time.Second)
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MetadataRegion returns the offset (from the start of the data) and length in bytes of the
+// metadata region in the io.ReadSeeker, along with the Format of the metadata it contains, without
+// parsing the metadata itself.  Callers can use this to fetch (or strip/replace) exactly the bytes
+// which contain the metadata.
+//
+// The region is:
+//   - ID3v2: the tag header and frames,
+//   - ID3v1: the trailing 128 byte tag,
+//   - MP4: the 'moov' atom,
+//   - FLAC: the "fLaC" marker and all metadata blocks,
+//   - OGG: the pages up to and including the end of the comment header,
+//   - DSF: the ID3v2 chunk,
+//   - APEv2: the tag header (if present), items and footer.
+//
+// Returns ErrNoTagsFound if the metadata format could not be identified.
+func MetadataRegion(r io.ReadSeeker) (start, length int64, format Format, err error) {
+	start, err = r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	b, err := readBytes(r, 11)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	_, err = r.Seek(-11, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, UnknownFormat, fmt.Errorf("could not seek back to original position: %w", err)
+	}
+
+	switch {
+	case string(b[0:4]) == "fLaC":
+		length, err = regionFLAC(r)
+		return start, length, VORBIS, err
+
+	case string(b[0:4]) == "OggS":
+		length, err = regionOGG(r)
+		return start, length, VORBIS, err
+
+	case string(b[4:8]) == "ftyp":
+		start, length, err = regionAtoms(r)
+		return start, length, MP4, err
+
+	case string(b[0:3]) == "ID3":
+		h, _, err := readID3v2Header(r)
+		if err != nil {
+			return 0, 0, UnknownFormat, err
+		}
+		return start, h.tagSize(), h.Version, nil
+
+	case string(b[0:4]) == "DSD ":
+		return regionDSF(r)
+	}
+
+	return regionTrailer(r)
+}
+
+// regionFLAC returns the length of the FLAC marker and metadata blocks.
+func regionFLAC(r io.ReadSeeker) (int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = r.Seek(4, io.SeekCurrent) // "fLaC"
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		last, err := skipFLACMetadataBlock(r)
+		if err != nil {
+			return 0, err
+		}
+		if last {
+			break
+		}
+	}
+
+	end, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return end - start, nil
+}
+
+// regionOGG returns the length of the OGG pages up to and including the page which
+// completes the comment header packet.
+func regionOGG(r io.ReadSeeker) (int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	od := &oggDemuxer{}
+	for {
+		bs, err := od.Read(r)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, b := range bs {
+			if bytes.HasPrefix(b, vorbisCommentPrefix) || bytes.HasPrefix(b, opusTagsPrefix) {
+				end, err := r.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return 0, err
+				}
+				return end - start, nil
+			}
+		}
+	}
+}
+
+// regionAtoms returns the offset and length of the top-level 'moov' atom.
+func regionAtoms(r io.ReadSeeker) (int64, int64, error) {
+	for {
+		start, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		name, size, err := readAtomHeader(r)
+		if err != nil {
+			if err == io.EOF {
+				return 0, 0, errors.New("could not find 'moov' atom")
+			}
+			return 0, 0, err
+		}
+
+		if name == "moov" {
+			end, err := r.Seek(size, io.SeekCurrent)
+			if err != nil {
+				return 0, 0, err
+			}
+			return start, end - start, nil
+		}
+
+		err = skipAtom(r, size)
+		if err != nil {
+			return 0, 0, fmt.Errorf("could not skip %q atom: %w", name, err)
+		}
+	}
+}
+
+// regionDSF returns the offset and length of the ID3v2 chunk referenced by the DSF header.
+func regionDSF(r io.ReadSeeker) (int64, int64, Format, error) {
+	_, err := r.Seek(12, io.SeekCurrent) // "DSD " + chunk size
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	fileSize, err := readUint64LittleEndian(r)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	id3Pointer, err := readUint64LittleEndian(r)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	if id3Pointer == 0 || id3Pointer >= fileSize {
+		return 0, 0, UnknownFormat, ErrNoTagsFound
+	}
+
+	_, err = r.Seek(int64(id3Pointer), io.SeekStart)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	h, _, err := readID3v2Header(r)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+	return int64(id3Pointer), h.tagSize(), h.Version, nil
+}
+
+// regionTrailer returns the offset and length of the metadata at the end of the
+// data: an APEv2 tag, or failing that an ID3v1 tag.
+func regionTrailer(r io.ReadSeeker) (int64, int64, Format, error) {
+	if h, err := findAPEFooter(r); err == nil {
+		end, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, 0, UnknownFormat, err
+		}
+
+		length := int64(h.Size)
+		if getBit(byte(h.Flags>>24), 7) { // contains header
+			length += apeHeaderSize
+		}
+		return end - length, length, APEv2, nil
+	}
+
+	start, err := r.Seek(-128, io.SeekEnd)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	tag, err := readString(r, 3)
+	if err != nil {
+		return 0, 0, UnknownFormat, err
+	}
+
+	if tag != "TAG" {
+		return 0, 0, UnknownFormat, ErrNoTagsFound
+	}
+	return start, 128, ID3v1, nil
+}
+
+// AudioRegion returns the offset (from the start of the data) and length in bytes of the
+// audio data in the io.ReadSeeker, excluding all metadata, so that decoders can seek straight
+// to the audio.
+//
+// The region is:
+//   - FLAC: the frames after the metadata blocks,
+//   - MP4: the contents of the 'mdat' atom,
+//   - OGG: the pages after the header packets (Vorbis, Opus, FLAC or Speex),
+//   - otherwise (i.e. MP3): the data after any ID3v2 tag and before any trailing APEv2
+//     or ID3v1 tag.
+func AudioRegion(r io.ReadSeeker) (start, length int64, err error) {
+	b, err := readBytes(r, 11)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = r.Seek(-11, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not seek back to original position: %w", err)
+	}
+
+	switch {
+	case string(b[0:4]) == "fLaC":
+		_, err := regionFLAC(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		start, err = r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, 0, err
+		}
+		return regionToEnd(r, start)
+
+	case string(b[0:4]) == "OggS":
+		return audioRegionOGG(r)
+
+	case string(b[4:8]) == "ftyp":
+		return audioRegionAtoms(r)
+
+	case string(b[0:3]) == "ID3":
+		h, _, err := readID3v2Header(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		// Skip the rest of the tag (the 10 byte header has been read).
+		_, err = r.Seek(h.tagSize()-10, io.SeekCurrent)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	start, err = r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, _, _, err := regionTrailer(r)
+	if err == ErrNoTagsFound {
+		return regionToEnd(r, start)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("trailing tag at offset %d overlaps audio starting at %d", end, start)
+	}
+	return start, end - start, nil
+}
+
+// regionToEnd returns start and the length of the data from start to the end.
+func regionToEnd(r io.ReadSeeker, start int64) (int64, int64, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end - start, nil
+}
+
+// audioRegionOGG returns the offset and length of the OGG pages which follow the page
+// completing the last header packet.
+func audioRegionOGG(r io.ReadSeeker) (int64, int64, error) {
+	od := &oggDemuxer{}
+	headers := 0 // number of header packets, known after the identification header
+	n := 0
+	for {
+		bs, err := od.Read(r)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, b := range bs {
+			if n == 0 {
+				headers, err = oggHeaderPackets(b)
+				if err != nil {
+					return 0, 0, err
+				}
+			}
+			n++
+		}
+
+		if n > 0 && n >= headers {
+			start, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return 0, 0, err
+			}
+			return regionToEnd(r, start)
+		}
+	}
+}
+
+// oggHeaderPackets returns the number of header packets (including the identification
+// header b) in the OGG stream.
+func oggHeaderPackets(b []byte) (int, error) {
+	switch {
+	case bytes.HasPrefix(b, vorbisIdentificationPrefix):
+		return 3, nil // identification, comment and setup
+
+	case bytes.HasPrefix(b, opusHeadPrefix):
+		return 2, nil // OpusHead and OpusTags
+
+	case bytes.HasPrefix(b, oggFLACPrefix) && len(b) >= 9:
+		// Identification header followed by the number of (metadata block) header packets.
+		return 1 + int(binary.BigEndian.Uint16(b[7:9])), nil
+
+	case bytes.HasPrefix(b, speexHeaderPrefix) && len(b) >= 80:
+		// Speex header and comment, followed by extra headers.
+		return 2 + int(binary.LittleEndian.Uint32(b[76:80])), nil
+	}
+	return 0, fmt.Errorf("%w: identification header %q", ErrUnsupportedOGGCodec, oggPacketMagic(b))
+}
+
+// audioRegionAtoms returns the offset and length of the contents of the top-level 'mdat' atom.
+func audioRegionAtoms(r io.ReadSeeker) (int64, int64, error) {
+	for {
+		name, size, err := readAtomHeader(r)
+		if err != nil {
+			if err == io.EOF {
+				return 0, 0, errors.New("could not find 'mdat' atom")
+			}
+			return 0, 0, err
+		}
+
+		if name == "mdat" {
+			start, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return 0, 0, err
+			}
+			return start, size, nil
+		}
+
+		err = skipAtom(r, size)
+		if err != nil {
+			return 0, 0, fmt.Errorf("could not skip %q atom: %w", name, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"crypto/sha1"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestMetadataRegion(t *testing.T) {
+	tests := []struct {
+		path   string
+		start  int64
+		length int64
+		format Format
+	}{
+		// ID3v2 header (10 bytes) + tag size.
+		{"with_tags/sample.id3v23.mp3", 0, 1034, ID3v2_3},
+		{"with_tags/sample.id3v24.mp3", 0, 1034, ID3v2_4},
+		// ID3v2 header + tag size + footer (10 bytes).
+		{"with_tags/sample.footer.id3v24.mp3", 0, 1044, ID3v2_4},
+		// Trailing 128 byte tag.
+		{"with_tags/sample.id3v11.mp3", 55170, 128, ID3v1},
+		// 'moov' atom.
+		{"with_tags/sample.m4a", 32715, 3237, MP4},
+		// "fLaC" + STREAMINFO (4+34) + VORBIS_COMMENT (4+246) + PADDING (4+7988).
+		{"with_tags/sample.flac", 0, 8284, VORBIS},
+		// Identification page and comment page.
+		{"with_tags/sample.ogg", 0, 4147, VORBIS},
+		// Comment header continued onto the third page.
+		{"with_tags/sample.multipage.ogg", 0, 108396, VORBIS},
+		// ID3v2 chunk at the end of the file.
+		{"with_tags/sample.dsf", 1040476, 1328, ID3v2_4},
+		// APEv2 header, items and footer.
+		{"with_tags/sample.ape", 76, 404, APEv2},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start, length, format, err := MetadataRegion(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: MetadataRegion() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+		if start != tt.start || length != tt.length || format != tt.format {
+			t.Errorf("%v: MetadataRegion() = %d, %d, %v, expected: %d, %d, %v", tt.path, start, length, format, tt.start, tt.length, tt.format)
+		}
+	}
+}
+
+func TestMetadataRegionNoTags(t *testing.T) {
+	f, err := os.Open("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, _, _, err := MetadataRegion(f); err != ErrNoTagsFound {
+		t.Errorf("MetadataRegion() returned error %v, expected: %v", err, ErrNoTagsFound)
+	}
+}
+
+func TestAudioRegion(t *testing.T) {
+	tests := []struct {
+		path   string
+		start  int64
+		length int64
+	}{
+		// After the ID3v2 tag (and before the ID3v1 tag, if any).
+		{"with_tags/sample.id3v23.mp3", 1034, 55170},
+		{"with_tags/sample.footer.id3v24.mp3", 1044, 55170},
+		{"with_tags/sample.id3v11.mp3", 0, 55170},
+		{"with_tags/sample.apev2.mp3", 0, 55170},
+		{"without_tags/sample.mp3", 0, 55170},
+		// Contents of the 'mdat' atom.
+		{"with_tags/sample.m4a", 40, 32675},
+		// After the metadata blocks.
+		{"with_tags/sample.flac", 8284, 60159},
+		// After the page completing the setup header.
+		{"with_tags/sample.ogg", 4147, 18131},
+		{"with_tags/sample.multipage.ogg", 108396, 18131},
+		{"with_tags/sample.r128.opus", 179, 69},
+		{"with_tags/sample.oga", 357, 50},
+		{"with_tags/sample.spx", 411, 48},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start, length, err := AudioRegion(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: AudioRegion() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+		if start != tt.start || length != tt.length {
+			t.Errorf("%v: AudioRegion() = %d, %d, expected: %d, %d", tt.path, start, length, tt.start, tt.length)
+		}
+	}
+}
+
+func TestAudioRegionSum(t *testing.T) {
+	// Files for which Sum hashes exactly the audio data.
+	for _, path := range []string{
+		"with_tags/sample.flac",
+		"with_tags/sample.m4a",
+		"with_tags/sample.id3v11.mp3",
+		"with_tags/sample.id3v23.mp3",
+		"with_tags/sample.footer.id3v24.mp3",
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start, length, err := AudioRegion(f)
+		if err != nil {
+			t.Errorf("%v: AudioRegion() returned unexpected error: %v", path, err)
+			f.Close()
+			continue
+		}
+		h := sha1.New()
+		_, err = io.Copy(h, io.NewSectionReader(f, start, length))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := Sum(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: Sum() returned unexpected error: %v", path, err)
+			continue
+		}
+		if got := hashSum(h); got != sum {
+			t.Errorf("%v: hash of AudioRegion() = %v, expected Sum(): %v", path, got, sum)
+		}
+	}
+}
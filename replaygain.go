@@ -0,0 +1,46 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ReplayGainInfo is a type which contains ReplayGain loudness normalization values.  Gains
+// are in dB relative to the ReplayGain reference level (-18 LUFS), peaks are relative to
+// full scale (1.0).  Values which are not present in the metadata are zero.
+type ReplayGainInfo struct {
+	TrackGain float64
+	TrackPeak float64
+	AlbumGain float64
+	AlbumPeak float64
+}
+
+// r128ReplayGainOffset is the difference (in dB) between the EBU R128 reference level
+// (-23 LUFS) and the ReplayGain reference level (-18 LUFS).
+const r128ReplayGainOffset = 5
+
+// parseReplayGain parses a ReplayGain gain or peak value, with an optional "dB" suffix
+// (i.e. "-6.54 dB").  Returns 0 if the value could not be parsed.
+func parseReplayGain(s string) float64 {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.EqualFold(s[len(s)-2:], "db") {
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// parseR128Gain parses an EBU R128 gain (as written to Opus files in R128_TRACK_GAIN and
+// R128_ALBUM_GAIN), a signed Q7.8 fixed point integer in dB relative to -23 LUFS, and
+// returns the equivalent ReplayGain gain in dB.
+func parseR128Gain(s string) (float64, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 16)
+	if err != nil {
+		return 0, false
+	}
+	return float64(n)/256 + r128ReplayGainOffset, true
+}
@@ -0,0 +1,48 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "testing"
+
+func TestParseReplayGain(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"", 0},
+		{"-6.54 dB", -6.54},
+		{"+2.10 dB", 2.1},
+		{"-6.54dB", -6.54},
+		{" 0.988251 ", 0.988251},
+		{"junk", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseReplayGain(tt.in); got != tt.want {
+			t.Errorf("parseReplayGain(%q) = %v, expected: %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseR128Gain(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"0", 5, true},
+		{"-1536", -1, true},
+		{"384", 6.5, true},
+		{"", 0, false},
+		{"40000", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseR128Gain(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseR128Gain(%q) = %v, %v, expected: %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SoundCheck is a type which contains the iTunes Sound Check normalization values
+// (stored by iTunes as "iTunNORM").
+type SoundCheck struct {
+	// Values are the 10 values as stored in the tag:
+	//  0-1: volume adjustment (left, right), relative to 1/1000 W.
+	//  2-3: volume adjustment (left, right), relative to 1/2500 W.
+	//  4-5: unknown (possibly statistical data).
+	//  6-7: peak sample value (left, right).
+	//  8-9: unknown (possibly statistical data).
+	Values [10]uint32
+}
+
+// ParseSoundCheck parses the iTunNORM string s, which contains 10 space-separated
+// hexadecimal values.
+func ParseSoundCheck(s string) (SoundCheck, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 10 {
+		return SoundCheck{}, fmt.Errorf("invalid iTunNORM: expected 10 values, got %d", len(fields))
+	}
+
+	var sc SoundCheck
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 16, 32)
+		if err != nil {
+			return SoundCheck{}, fmt.Errorf("invalid iTunNORM value %q: %w", f, err)
+		}
+		sc.Values[i] = uint32(v)
+	}
+	return sc, nil
+}
+
+// Gain returns the gain adjustment (in dB) to apply for playback, computed from the
+// louder of the two channels.
+func (s SoundCheck) Gain() float64 {
+	v := s.Values[0]
+	if s.Values[1] > v {
+		v = s.Values[1]
+	}
+	if v == 0 {
+		return 0
+	}
+	return -10 * math.Log10(float64(v)/1000)
+}
+
+// Peak returns the peak sample value (of the louder of the two channels), relative
+// to full scale (1.0 = 0x7FFF).
+func (s SoundCheck) Peak() float64 {
+	v := s.Values[6]
+	if s.Values[7] > v {
+		v = s.Values[7]
+	}
+	return float64(v) / 0x7FFF
+}
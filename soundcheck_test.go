@@ -0,0 +1,89 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func TestParseSoundCheck(t *testing.T) {
+	sc, err := ParseSoundCheck(" 00000A2C 00000B2C 00004A6F 00004A6F 00024CA8 00024CA8 00007FFF 00006FFF 00024CA8 00024CA8")
+	if err != nil {
+		t.Fatalf("ParseSoundCheck() returned unexpected error: %v", err)
+	}
+
+	want := [10]uint32{0xA2C, 0xB2C, 0x4A6F, 0x4A6F, 0x24CA8, 0x24CA8, 0x7FFF, 0x6FFF, 0x24CA8, 0x24CA8}
+	if sc.Values != want {
+		t.Errorf("Values = %x, expected: %x", sc.Values, want)
+	}
+
+	// -10 * log10(0xB2C / 1000)
+	if got, want := sc.Gain(), -4.5637; math.Abs(got-want) > 0.0001 {
+		t.Errorf("Gain() = %v, expected: %v", got, want)
+	}
+	if got, want := sc.Peak(), 1.0; got != want {
+		t.Errorf("Peak() = %v, expected: %v", got, want)
+	}
+
+	for _, s := range []string{"", "00000A2C 00000B2C", "00000A2C 00000B2C 00004A6F 00004A6F 00024CA8 00024CA8 00007FFF 00006FFF 00024CA8 0002XCA8"} {
+		if _, err := ParseSoundCheck(s); err == nil {
+			t.Errorf("ParseSoundCheck(%q) expected error", s)
+		}
+	}
+}
+
+func TestID3v2SoundCheck(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.itunnorm.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The iTunNORM COMM frame precedes the comment, and must not be returned by Comment().
+	compareMetadata(t, m, fullMetadata)
+
+	sc := m.(MetadataID3v2).SoundCheck()
+	if sc == nil {
+		t.Fatal("SoundCheck() = nil, expected iTunNORM values")
+	}
+	if sc.Values[1] != 0xB2C {
+		t.Errorf("SoundCheck().Values[1] = %x, expected: %x", sc.Values[1], 0xB2C)
+	}
+
+	// There are no replaygain TXXX frames or RVAD frame, so ReplayGain falls back to iTunNORM.
+	testSoundCheckReplayGain(t, m.(MetadataID3v2).ReplayGain())
+}
+
+func TestMP4SoundCheck(t *testing.T) {
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.itunnorm.m4a")
+
+	sc := m.SoundCheck()
+	if sc == nil {
+		t.Fatal("SoundCheck() = nil, expected iTunNORM values")
+	}
+	if sc.Values[1] != 0xB2C {
+		t.Errorf("SoundCheck().Values[1] = %x, expected: %x", sc.Values[1], 0xB2C)
+	}
+	testSoundCheckReplayGain(t, m.ReplayGain())
+
+	if sc := readTestFile[MetadataMP4](t, "with_tags/sample.m4a").SoundCheck(); sc != nil {
+		t.Errorf("SoundCheck() = %v, expected: nil", sc)
+	}
+}
+
+// testSoundCheckReplayGain checks rg was taken from the iTunNORM values used in the
+// sample.itunnorm.* files.
+func testSoundCheckReplayGain(t *testing.T, rg ReplayGainInfo) {
+	t.Helper()
+	if math.Abs(rg.TrackGain - -4.5637) > 0.0001 || rg.TrackPeak != 1 || rg.AlbumGain != 0 || rg.AlbumPeak != 0 {
+		t.Errorf("ReplayGain() = %+v, expected: TrackGain -4.5637, TrackPeak 1", rg)
+	}
+}
@@ -2,7 +2,6 @@ package tag
 
 import (
 	"crypto/sha1"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"hash"
@@ -10,7 +9,7 @@ import (
 )
 
 // Sum creates a checksum of the audio file data provided by the io.ReadSeeker which is metadata
-// (ID3, MP4) invariant.
+// (ID3, MP4, FLAC and OGG Vorbis comment) invariant.
 func Sum(r io.ReadSeeker) (string, error) {
 	b, err := readBytes(r, 11)
 	if err != nil {
@@ -19,13 +18,16 @@ func Sum(r io.ReadSeeker) (string, error) {
 
 	_, err = r.Seek(-11, io.SeekCurrent)
 	if err != nil {
-		return "", fmt.Errorf("could not seek back to original position: %v", err)
+		return "", fmt.Errorf("could not seek back to original position: %w", err)
 	}
 
 	switch {
 	case string(b[0:4]) == "fLaC":
 		return SumFLAC(r)
 
+	case string(b[0:4]) == "OggS":
+		return SumOGG(r)
+
 	case string(b[4:11]) == "ftypM4A":
 		return SumAtoms(r)
 
@@ -35,7 +37,7 @@ func Sum(r io.ReadSeeker) (string, error) {
 
 	h, err := SumID3v1(r)
 	if err != nil {
-		if err == ErrNotID3v1 {
+		if errors.Is(err, ErrNotID3v1) {
 			return SumAll(r)
 		}
 		return "", err
@@ -57,8 +59,7 @@ func SumAll(r io.ReadSeeker) (string, error) {
 // metadata invariant.
 func SumAtoms(r io.ReadSeeker) (string, error) {
 	for {
-		var size uint32
-		err := binary.Read(r, binary.BigEndian, &size)
+		name, size, err := readAtomHeader(r)
 		if err != nil {
 			if err == io.EOF {
 				return "", fmt.Errorf("reached EOF before audio data")
@@ -66,15 +67,9 @@ func SumAtoms(r io.ReadSeeker) (string, error) {
 			return "", err
 		}
 
-		name, err := readString(r, 4)
-		if err != nil {
-			return "", err
-		}
-
 		switch name {
 		case "meta":
-			// next_item_id (int32)
-			_, err := r.Seek(4, io.SeekCurrent)
+			err := skipMetaVersionFlags(r)
 			if err != nil {
 				return "", err
 			}
@@ -85,31 +80,62 @@ func SumAtoms(r io.ReadSeeker) (string, error) {
 
 		case "mdat": // stop when we get to the data
 			h := sha1.New()
-			_, err := io.CopyN(h, r, int64(size-8))
+			_, err := io.CopyN(h, r, size)
 			if err != nil {
-				return "", fmt.Errorf("error reading audio data: %v", err)
+				return "", fmt.Errorf("error reading audio data: %w", err)
 			}
 			return hashSum(h), nil
 		}
 
-		_, err = r.Seek(int64(size-8), io.SeekCurrent)
+		_, err = r.Seek(size, io.SeekCurrent)
 		if err != nil {
-			return "", fmt.Errorf("error reading '%v' tag: %v", name, err)
+			return "", fmt.Errorf("error reading '%v' tag: %w", name, err)
 		}
 	}
 }
 
+// sizeToEndOffset returns the number of bytes from the current position of r to offset
+// bytes before the end, leaving r at the current position.
 func sizeToEndOffset(r io.ReadSeeker, offset int64) (int64, error) {
-	n, err := r.Seek(-128, io.SeekEnd)
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("error getting current position: %w", err)
+	}
+
+	end, err := r.Seek(-offset, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("error seeking end offset (%d bytes): %w", offset, err)
+	}
+
+	_, err = r.Seek(pos, io.SeekStart)
+	if err != nil {
+		return 0, fmt.Errorf("error seeking back to original position: %w", err)
+	}
+	return end - pos, nil
+}
+
+// sizeToTrailer returns the number of bytes from the current position of r to the start
+// of any trailing APEv2 or ID3v1 tag (or the end, if there is none), leaving r at the
+// current position.
+func sizeToTrailer(r io.ReadSeeker) (int64, error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("error getting current position: %w", err)
+	}
+
+	end, _, _, err := regionTrailer(r)
+	if err == ErrNoTagsFound {
+		end, err = r.Seek(0, io.SeekEnd)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("error seeking end offset (%d bytes): %v", offset, err)
+		return 0, err
 	}
 
-	_, err = r.Seek(-n, io.SeekCurrent)
+	_, err = r.Seek(pos, io.SeekStart)
 	if err != nil {
-		return 0, fmt.Errorf("error seeking back to original position: %v", err)
+		return 0, fmt.Errorf("error seeking back to original position: %w", err)
 	}
-	return n, nil
+	return end - pos, nil
 }
 
 // SumID3v1 constructs a checksum of MP3 audio file data (assumed to have ID3v1 tags) provided
@@ -117,7 +143,7 @@ func sizeToEndOffset(r io.ReadSeeker, offset int64) (int64, error) {
 func SumID3v1(r io.ReadSeeker) (string, error) {
 	n, err := sizeToEndOffset(r, 128)
 	if err != nil {
-		return "", fmt.Errorf("error determining read size to ID3v1 header: %v", err)
+		return "", fmt.Errorf("error determining read size to ID3v1 header: %w", err)
 	}
 
 	// TODO: improve this check???
@@ -128,43 +154,44 @@ func SumID3v1(r io.ReadSeeker) (string, error) {
 	h := sha1.New()
 	_, err = io.CopyN(h, r, n)
 	if err != nil {
-		return "", fmt.Errorf("error reading %v bytes: %v", n, err)
+		return "", fmt.Errorf("error reading %v bytes: %w", n, err)
 	}
 	return hashSum(h), nil
 }
 
 // SumID3v2 constructs a checksum of MP3 audio file data (assumed to have ID3v2 tags) provided by the
-// io.ReadSeeker which is metadata invariant.
+// io.ReadSeeker which is metadata invariant.  Any trailing APEv2 or ID3v1 tag is excluded (so the
+// checksum is of the AudioRegion).
 func SumID3v2(r io.ReadSeeker) (string, error) {
 	header, _, err := readID3v2Header(r)
 	if err != nil {
-		return "", fmt.Errorf("error reading ID3v2 header: %v", err)
+		return "", fmt.Errorf("error reading ID3v2 header: %w", err)
 	}
 
-	_, err = r.Seek(int64(header.Size), io.SeekCurrent)
+	// Skip the rest of the tag (the 10 byte header has been read).
+	_, err = r.Seek(header.tagSize()-10, io.SeekCurrent)
 	if err != nil {
-		return "", fmt.Errorf("error seeking to end of ID3V2 header: %v", err)
+		return "", fmt.Errorf("error seeking to end of ID3V2 header: %w", err)
 	}
 
-	n, err := sizeToEndOffset(r, 128)
+	n, err := sizeToTrailer(r)
 	if err != nil {
-		return "", fmt.Errorf("error determining read size to ID3v1 header: %v", err)
+		return "", fmt.Errorf("error determining read size to trailing tag: %w", err)
 	}
 
-	// TODO: remove this check?????
 	if n < 0 {
-		return "", fmt.Errorf("file size must be greater than 128 bytes for MP3: %v bytes", n)
+		return "", fmt.Errorf("trailing tag overlaps ID3v2 tag: %v bytes", n)
 	}
 
 	h := sha1.New()
 	_, err = io.CopyN(h, r, n)
 	if err != nil {
-		return "", fmt.Errorf("error reading %v bytes: %v", n, err)
+		return "", fmt.Errorf("error reading %v bytes: %w", n, err)
 	}
 	return hashSum(h), nil
 }
 
-// SumFLAC costructs a checksum of the FLAC audio file data provided by the io.ReadSeeker (ignores
+// SumFLAC constructs a checksum of the FLAC audio file data provided by the io.ReadSeeker (ignores
 // metadata fields).
 func SumFLAC(r io.ReadSeeker) (string, error) {
 	flac, err := readString(r, 4)
@@ -189,11 +216,59 @@ func SumFLAC(r io.ReadSeeker) (string, error) {
 	h := sha1.New()
 	_, err = io.Copy(h, r)
 	if err != nil {
-		return "", fmt.Errorf("error reading data bytes from FLAC: %v", err)
+		return "", fmt.Errorf("error reading data bytes from FLAC: %w", err)
 	}
 	return hashSum(h), nil
 }
 
+// SumOGG constructs a checksum of the OGG audio file data provided by the io.ReadSeeker (ignores
+// the header packets, including the comment header).  Only the packets of the first logical
+// stream are used, and the page framing is ignored, as the pages are renumbered when the comment
+// header changes size.
+func SumOGG(r io.ReadSeeker) (string, error) {
+	h := sha1.New()
+	var serial uint32
+	var packet []byte
+	headers := 0 // number of header packets, known after the identification header
+	n := 0
+	for first := true; ; first = false {
+		p, err := readOGGPage(r)
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				return hashSum(h), nil
+			}
+			return "", err
+		}
+		if first {
+			serial = p.SerialNumber
+		}
+		if p.SerialNumber != serial {
+			continue
+		}
+
+		var pos int
+		for _, s := range p.segmentTable {
+			packet = append(packet, p.data[pos:pos+int(s)]...)
+			pos += int(s)
+			if s == 255 {
+				continue
+			}
+
+			if n == 0 {
+				headers, err = oggHeaderPackets(packet)
+				if err != nil {
+					return "", err
+				}
+			}
+			if n >= headers {
+				h.Write(packet)
+			}
+			packet = packet[:0]
+			n++
+		}
+	}
+}
+
 func skipFLACMetadataBlock(r io.ReadSeeker) (last bool, err error) {
 	blockHeader, err := readBytes(r, 1)
 	if err != nil {
@@ -2,22 +2,123 @@ package tag
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
+// TestSumVorbisComment checks that the FLAC and OGG checksums do not change when the tags are
+// rewritten.
+func TestSumVorbisComment(t *testing.T) {
+	for _, path := range []string{
+		"testdata/with_tags/sample.flac",
+		"testdata/with_tags/sample.pictures.flac",
+		"testdata/with_tags/sample.ogg",
+		"testdata/with_tags/sample.multipage.ogg",
+		"testdata/with_tags/sample.r128.opus",
+		"testdata/with_tags/sample.spx",
+	} {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Sum(bytes.NewReader(original))
+		if err != nil {
+			t.Errorf("%v: Sum() returned unexpected error: %v", path, err)
+			continue
+		}
+		all, err := SumAll(bytes.NewReader(original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == all {
+			t.Errorf("%v: Sum() = SumAll() = %v, expected the metadata to be ignored", path, want)
+		}
+
+		m, err := ReadFrom(bytes.NewReader(original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm := m.(interface {
+			SetVorbisComment(key, value string)
+			io.WriterTo
+		})
+		vm.SetVorbisComment("TITLE", strings.Repeat("x", 100000))
+		var buf bytes.Buffer
+		_, err = vm.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Sum(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%v: Sum() after WriteTo() returned unexpected error: %v", path, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%v: Sum() after WriteTo() = %v, expected: %v", path, got, want)
+		}
+	}
+}
+
+// TestSumID3v2 checks that the MP3 checksum does not change when the ID3v2 tag is rewritten.
+func TestSumID3v2(t *testing.T) {
+	for _, path := range []string{
+		"testdata/with_tags/sample.id3v23.mp3",
+		"testdata/with_tags/sample.id3v24.mp3",
+	} {
+		original, saved := saveID3v2Tag(t, path, func(tag *ID3v2Tag) {
+			tag.Title = strings.Repeat("x", 100000)
+		})
+		want, err := Sum(bytes.NewReader(original))
+		if err != nil {
+			t.Errorf("%v: Sum() returned unexpected error: %v", path, err)
+			continue
+		}
+		got, err := Sum(bytes.NewReader(saved))
+		if err != nil {
+			t.Errorf("%v: Sum() after Save() returned unexpected error: %v", path, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%v: Sum() after Save() = %v, expected: %v", path, got, want)
+		}
+	}
+}
+
 func TestFuzz(t *testing.T) {
-	fuzzIssue73(dataIssue73)
+	fuzz(dataIssue73)
+}
+
+// FuzzReadFromBytes checks that reading arbitrary data does not panic.  The seed corpus is
+// each of the test files.
+func FuzzReadFromBytes(f *testing.F) {
+	f.Add(dataIssue73)
+	paths, err := filepath.Glob(filepath.Join("testdata", "with_tags", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, in []byte) {
+		fuzz(in)
+	})
 }
 
 var dataIssue73 = []byte{0x49, 0x44, 0x33, 0x03, 0x00, 0x40, 0x00, 0x00, 0x00, 0x0E, 0xDB, 0xDB, 0xDB, 0xDB, 0xDB, 0xDB,
 	0xDB, 0x06, 0xFF, 0x54, 0x58, 0x58, 0x00}
 
-func fuzzIssue73(in []byte) {
-	r := bytes.NewReader(in)
+func fuzz(in []byte) {
+	Identify(bytes.NewReader(in))
 
-	Identify(r)
-
-	m, err := ReadFrom(r)
+	m, err := ReadFromBytes(in)
 	if err != nil {
 		return
 	}
@@ -33,8 +134,29 @@ func fuzzIssue73(in []byte) {
 	m.Genre()
 	m.Track()
 	m.Disc()
+	if p, ok := m.(PositionMetadata); ok {
+		p.TrackPosition()
+		p.DiscPosition()
+	}
 	m.Picture()
+	if p, ok := m.(PicturesMetadata); ok {
+		p.Pictures()
+	}
 	m.Lyrics()
+	m.Comment()
+	if i, ok := m.(ISRCMetadata); ok {
+		i.ISRC()
+	}
+	if r, ok := m.(ReplayGainMetadata); ok {
+		r.ReplayGain()
+	}
+	if c, ok := m.(CompilationMetadata); ok {
+		c.Compilation()
+	}
+	if b, ok := m.(BPMMetadata); ok {
+		b.BPM()
+	}
+	m.Raw()
 
-	Sum(r)
+	Sum(bytes.NewReader(in))
 }
@@ -15,19 +15,58 @@
 package tag
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 )
 
 // ErrNoTagsFound is the error returned by ReadFrom when the metadata format
-// cannot be identified.
+// cannot be identified.  Errors from this package which mean that no tags were
+// found (such as ErrNotID3v1) match it with errors.Is, including when wrapped.
 var ErrNoTagsFound = errors.New("no tags found")
 
-// ReadFrom detects and parses audio file metadata tags (currently supports ID3v1,2.{2,3,4}, MP4, FLAC/OGG).
+// ErrUnsupportedFormat is the error returned (wrapped) when the data is in a recognised
+// format which cannot be read, i.e. an OGG stream of an unsupported codec (see
+// ErrUnsupportedOGGCodec).  Errors for recognised data which is invalid wrap a sentinel
+// for the format instead, such as ErrInvalidID3v2Header or ErrInvalidMP4.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// ErrTruncated is the error returned (wrapped) when the data ends part way through
+// a structure which declares a larger size, i.e. a truncated file.
+var ErrTruncated = errors.New("truncated data")
+
+// ReadFrom detects and parses audio file metadata tags (currently supports ID3v1,2.{2,3,4}, MP4, FLAC/OGG, AIFF, WAV).
 // Returns non-nil error if the format of the given data could not be determined, or if there was a problem
 // parsing the data.
 func ReadFrom(r io.ReadSeeker) (Metadata, error) {
+	return ReadFromOptions(r, Options{})
+}
+
+// Options are the options for reading metadata with ReadFromOptions.  The zero value reads
+// metadata in the same way as ReadFrom.
+type Options struct {
+	// Charset, if not nil, is called to decode ID3v2 text which has encoding byte 0
+	// (ISO-8859-1), including ID3v2 tags in DSF, WAV and AIFF files.  Some taggers wrote
+	// text in a legacy character set (i.e. Windows-1251 or Shift JIS) with this encoding,
+	// which can be decoded with a package such as golang.org/x/text/encoding.  enc is the
+	// encoding byte and b the encoded text.  If Charset returns false, b is decoded as
+	// ISO-8859-1.
+	Charset func(enc byte, b []byte) (string, bool)
+
+	// SearchLimit is the number of bytes searched for an ID3v2 header, or the start of a
+	// FLAC, OGG or MP4 file, when the data does not start with a recognised format (i.e.
+	// when there is a UTF-8 BOM or other junk before it).  If zero, the first 4096 bytes
+	// are searched.  If negative, the data is not searched.
+	SearchLimit int
+
+	validator *validator // if not nil, problems with the tags are reported to it (see Validate)
+}
+
+// ReadFromOptions is like ReadFrom, with the options opts.
+func ReadFromOptions(r io.ReadSeeker, opts Options) (Metadata, error) {
 	b, err := readBytes(r, 11)
 	if err != nil {
 		return nil, err
@@ -35,7 +74,7 @@ func ReadFrom(r io.ReadSeeker) (Metadata, error) {
 
 	_, err = r.Seek(-11, io.SeekCurrent)
 	if err != nil {
-		return nil, fmt.Errorf("could not seek back to original position: %v", err)
+		return nil, fmt.Errorf("could not seek back to original position: %w", err)
 	}
 
 	switch {
@@ -49,22 +88,178 @@ func ReadFrom(r io.ReadSeeker) (Metadata, error) {
 		return ReadAtoms(r)
 
 	case string(b[0:3]) == "ID3":
-		return ReadID3v2Tags(r)
+		return readID3v2Tags(r, opts)
 
 	case string(b[0:4]) == "DSD ":
-		return ReadDSFTags(r)
+		return readDSFTags(r, opts)
+
+	case string(b[0:4]) == "FORM" && string(b[8:11]) == "AIF":
+		return readAIFFTags(r, opts)
+
+	case (string(b[0:4]) == "RIFF" || string(b[0:4]) == "RIFX") && string(b[8:11]) == "WAV":
+		return readWAVTags(r, opts)
+
+	case apeFileType(b) != UnknownFileType:
+		return ReadAPETags(r)
+
+	case bytes.Equal(b, asfHeaderGUID[:11]):
+		return ReadASFTags(r)
 	}
 
+	// Some files have a UTF-8 BOM or other junk before the ID3v2 header or the start of
+	// the file.
+	limit := opts.SearchLimit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	magic, err := seekHeader(r, limit, "ID3", "fLaC", "OggS", "ftyp")
+	if err != nil {
+		return nil, err
+	}
+	switch magic {
+	case "fLaC", "OggS", "ftyp":
+		// r is now at the start of the file.
+		return ReadFromOptions(r, opts)
+
+	case "ID3":
+		m, err := readID3v2Tags(r, opts)
+		if err == nil {
+			return m, nil
+		}
+
+		// The header may have been a false match in the audio data, in which case
+		// there can still be an ID3v1 tag at the end.
+		if m, err := ReadID3v1Tags(r); err == nil {
+			return m, nil
+		}
+		return nil, err
+	}
+
+	// The leading bytes are not recognised, so the only remaining possibilities are an
+	// ID3v1 or APEv2 tag at the end of the data.
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
 	m, err := ReadID3v1Tags(r)
+	if err == nil {
+		return m, nil
+	}
+	if !errors.Is(err, ErrNotID3v1) {
+		return nil, err
+	}
+
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	if m, err := ReadAPETags(r); err == nil {
+		return m, nil
+	}
+	return nil, ErrNoTagsFound
+}
+
+// ErrSeekRequired is the error returned (wrapped) by ReadFromReader when the metadata
+// format can only be read from an io.ReadSeeker (i.e. MP4, where the tags can follow the
+// audio data).
+var ErrSeekRequired = errors.New("metadata format requires an io.ReadSeeker")
+
+// ReadFromReader detects and parses audio file metadata tags from a forward-only stream (i.e.
+// an HTTP response body), without reading the audio data.  Only ID3v2 and OGG can be read
+// this way, as other formats require seeking (and tags at the end of the data cannot be
+// found): these return an error which matches ErrSeekRequired with errors.Is.
+func ReadFromReader(r io.Reader) (Metadata, error) {
+	b, err := readBytes(r, 11)
 	if err != nil {
-		if err == ErrNotID3v1 {
-			err = ErrNoTagsFound
+		return nil, err
+	}
+	mr := io.MultiReader(bytes.NewReader(b), r)
+
+	switch {
+	case string(b[0:3]) == "ID3":
+		return ReadID3v2TagsReader(mr)
+
+	case string(b[0:4]) == "OggS":
+		return ReadOGGTags(mr)
+	}
+	return nil, fmt.Errorf("%w: leading bytes %q", ErrSeekRequired, b[:4])
+}
+
+// ReadFromContext is like ReadFrom, but stops parsing and returns ctx.Err() if ctx is
+// cancelled (or its deadline passes) before the metadata has been read, which bounds the
+// time spent on a malformed or very large file.  The context is checked before each read
+// and seek on r, and is not used after ReadFromContext returns (i.e. by WriteTo).
+func ReadFromContext(ctx context.Context, r io.ReadSeeker) (Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr := &contextReadSeeker{ReadSeeker: r, ctx: ctx}
+	m, err := ReadFrom(cr)
+	if err != nil {
+		// The parsers do not all wrap the errors from r.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
 		}
 		return nil, err
 	}
+	cr.ctx = context.Background()
 	return m, nil
 }
 
+// readFromFileBufferSize is the size of the buffer used by ReadFromFile.
+const readFromFileBufferSize = 64 << 10
+
+// ReadFromFile opens the file at path and reads its metadata with ReadFrom, buffering
+// reads from the file.  The file is closed before ReadFromFile returns.
+func ReadFromFile(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := newBufferedReadSeeker(f, readFromFileBufferSize)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFrom(r)
+}
+
+// ReadFromBytes reads the metadata of the audio file data b (i.e. a file which has already
+// been read into memory) with ReadFrom.
+func ReadFromBytes(b []byte) (Metadata, error) {
+	return ReadFrom(bytes.NewReader(b))
+}
+
+// VariousArtists is the album artist returned by EffectiveAlbumArtist for compilations
+// which do not have an album artist.
+const VariousArtists = "Various Artists"
+
+// EffectiveAlbumArtist returns the album artist to use for grouping the track, which is:
+//   - the album artist, if set, otherwise
+//   - VariousArtists, if the track is part of a compilation (see CompilationMetadata),
+//     otherwise
+//   - the track artist.
+func EffectiveAlbumArtist(m Metadata) string {
+	if aa := m.AlbumArtist(); aa != "" {
+		return aa
+	}
+	if compilation(m) {
+		return VariousArtists
+	}
+	return m.Artist()
+}
+
+// Position is a track or disc number and the total number of tracks or discs.  HasTotal
+// is false if the metadata does not give the total (i.e. "3" rather than "3/6"), in which
+// case Total is zero.
+type Position struct {
+	Number   int
+	Total    int
+	HasTotal bool
+}
+
 // Format is an enumeration of metadata types supported by this package.
 type Format string
 
@@ -77,6 +272,9 @@ const (
 	ID3v2_4       Format = "ID3v2.4" // ID3v2.4 tag format.
 	MP4           Format = "MP4"     // MP4 tag (atom) format (see http://www.ftyps.com/ for a full file type list)
 	VORBIS        Format = "VORBIS"  // Vorbis Comment tag format.
+	APEv2         Format = "APEv2"   // APEv2 tag format.
+	RIFFINFO      Format = "INFO"    // RIFF LIST/INFO chunk tag format (WAV).
+	ASF           Format = "ASF"     // ASF attribute format (WMA).
 )
 
 // FileType is an enumeration of the audio file types supported by this package, in particular
@@ -91,10 +289,20 @@ const (
 	M4A             FileType = "M4A"  // M4A file Apple iTunes (ACC) Audio
 	M4B             FileType = "M4B"  // M4A file Apple iTunes (ACC) Audio Book
 	M4P             FileType = "M4P"  // M4A file Apple iTunes (ACC) AES Protected Audio
-	ALAC            FileType = "ALAC" // Apple Lossless file FIXME: actually detect this
+	M4V             FileType = "M4V"  // M4V file Apple iTunes Video
+	ALAC            FileType = "ALAC" // Apple Lossless file (M4A with an alac audio track)
 	FLAC            FileType = "FLAC" // FLAC file
 	OGG             FileType = "OGG"  // OGG file
+	OPUS            FileType = "OPUS" // Opus file (in an OGG container)
 	DSF             FileType = "DSF"  // DSF file DSD Sony format see https://dsd-guide.com/sites/default/files/white-papers/DSFFileFormatSpec_E.pdf
+	WAV             FileType = "WAV"  // WAV file (RIFF WAVE)
+	AIFF            FileType = "AIFF" // AIFF file
+	AIFC            FileType = "AIFC" // AIFC file (AIFF with compression type)
+	APE             FileType = "APE"  // Monkey's Audio file
+	WV              FileType = "WV"   // WavPack file
+	MPC             FileType = "MPC"  // Musepack file
+	OFR             FileType = "OFR"  // OptimFROG file
+	WMA             FileType = "WMA"  // WMA file (ASF container)
 )
 
 // Metadata is an interface which is used to describe metadata retrieved by this package.
@@ -114,7 +322,8 @@ type Metadata interface {
 	// Artist returns the artist name of the track.
 	Artist() string
 
-	// AlbumArtist returns the album artist name of the track.
+	// AlbumArtist returns the album artist name of the track, as stored in the metadata
+	// (see EffectiveAlbumArtist for a fallback to the artist).
 	AlbumArtist() string
 
 	// Composer returns the composer of the track.
@@ -145,3 +354,98 @@ type Metadata interface {
 	// NB: tag/atom names are not standardised between formats.
 	Raw() map[string]interface{}
 }
+
+// PositionMetadata is implemented by the Metadata of all of the formats read by this
+// package, to give the track and disc positions along with whether the totals are set.
+// It is not part of Metadata so that other implementations of Metadata are not broken,
+// so the Metadata must be asserted to it:
+//
+//	if p, ok := m.(tag.PositionMetadata); ok {
+//		log.Print(p.TrackPosition())
+//	}
+type PositionMetadata interface {
+	// TrackPosition returns the track number and total tracks, as for Track, with
+	// HasTotal set if the metadata gives the total.
+	TrackPosition() Position
+
+	// DiscPosition returns the disc number and total discs, as for Disc, with HasTotal
+	// set if the metadata gives the total.
+	DiscPosition() Position
+}
+
+// BPMMetadata is implemented by the Metadata of all of the formats read by this package, to
+// give the tempo of the track.  As for PositionMetadata, the Metadata must be asserted to it.
+type BPMMetadata interface {
+	// BPM returns the tempo of the track in beats per minute, or 0 if unavailable.
+	BPM() int
+}
+
+// ReplayGainMetadata is implemented by the Metadata of all of the formats read by this
+// package, to give the loudness normalization values.  As for PositionMetadata, the
+// Metadata must be asserted to it.
+type ReplayGainMetadata interface {
+	// ReplayGain returns the ReplayGain loudness normalization values.  Values which are
+	// unavailable are zero.
+	ReplayGain() ReplayGainInfo
+}
+
+// PicturesMetadata is implemented by the Metadata of all of the formats read by this
+// package, to give all of the pictures rather than one.  As for PositionMetadata, the
+// Metadata must be asserted to it.
+type PicturesMetadata interface {
+	// Pictures returns all of the pictures, in the order they appear in the metadata, or
+	// nil if there are none.  Use the Type of each picture to find a particular one (i.e.
+	// the back cover).
+	Pictures() []*Picture
+}
+
+// DiscSubtitleMetadata is implemented by the Metadata of all of the formats read by this
+// package, to give the subtitle of the disc.  As for PositionMetadata, the Metadata must
+// be asserted to it.
+type DiscSubtitleMetadata interface {
+	// DiscSubtitle returns the subtitle of the disc (i.e. "The Remixes" for one disc of a
+	// box set), or an empty string if unavailable.
+	DiscSubtitle() string
+}
+
+// ISRCMetadata is implemented by the Metadata of all of the formats read by this package,
+// to give the recording code of the track.  As for PositionMetadata, the Metadata must be
+// asserted to it.
+type ISRCMetadata interface {
+	// ISRC returns the International Standard Recording Code, or an empty string if
+	// unavailable.  Use ParseISRC to decompose the code into its component parts.
+	ISRC() string
+}
+
+// CompilationMetadata is implemented by the Metadata of all of the formats read by this
+// package, to give whether the track is part of a compilation.  As for PositionMetadata,
+// the Metadata must be asserted to it.
+type CompilationMetadata interface {
+	// Compilation returns true if the track is part of a compilation (i.e. the iTunes
+	// compilation flag is set).
+	Compilation() bool
+}
+
+// compilation returns true if m implements CompilationMetadata and the track is part of
+// a compilation.
+func compilation(m Metadata) bool {
+	c, ok := m.(CompilationMetadata)
+	return ok && c.Compilation()
+}
+
+// discSubtitle returns the disc subtitle of m, or an empty string if m does not implement
+// DiscSubtitleMetadata.
+func discSubtitle(m Metadata) string {
+	if d, ok := m.(DiscSubtitleMetadata); ok {
+		return d.DiscSubtitle()
+	}
+	return ""
+}
+
+// isrc returns the ISRC of m, or an empty string if m does not implement ISRCMetadata.
+func isrc(m Metadata) string {
+	if i, ok := m.(ISRCMetadata); ok {
+		return i.ISRC()
+	}
+	return ""
+}
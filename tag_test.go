@@ -5,7 +5,16 @@
 package tag
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -41,6 +50,18 @@ var fullMetadata = testMetadata{
 	Year:        2000,
 	Comment:     "Test Comment",
 }
+var asfMetadata = testMetadata{
+	Album:       "Test Album",
+	AlbumArtist: "Test AlbumArtist",
+	Artist:      "Test Artist",
+	Composer:    "Test Composer",
+	Disc:        2,
+	Genre:       "Jazz",
+	Title:       "Test Title",
+	Track:       3,
+	Year:        2000,
+	Comment:     "Test Comment",
+}
 var mp3id3v11Metadata = testMetadata{
 	Album:   "Test Album",
 	Artist:  "Test Artist",
@@ -55,30 +76,86 @@ var mp3id3v11Metadata = testMetadata{
 func TestReadFrom(t *testing.T) {
 	testdata := map[string]testMetadata{
 		"with_tags/sample.flac":          fullMetadata,
+		"with_tags/sample.id3.aiff":      fullMetadata,
+		"with_tags/sample.id3.wav":       fullMetadata,
+		"with_tags/sample.info.wav":      wavInfoMetadata,
+		"with_tags/sample.rifx.wav":      wavInfoMetadata,
 		"with_tags/sample.id3v11.mp3":    mp3id3v11Metadata,
 		"with_tags/sample.id3v22.mp3":    fullMetadata,
 		"with_tags/sample.id3v23.mp3":    fullMetadata,
 		"with_tags/sample.id3v24.mp3":    fullMetadata,
+		"with_tags/sample.tkey.mp3":      fullMetadata,
+		"with_tags/sample.radio.mp3":     fullMetadata,
+		"with_tags/sample.tkey-raw.mp3":  fullMetadata,
+		"with_tags/sample.link.mp3":      fullMetadata,
+		"with_tags/sample.bom.mp3":       fullMetadata,
+		"with_tags/sample.boundary.mp3":  fullMetadata, // final frame ends at the tag boundary
+		"with_tags/sample.mbz.mp3":       fullMetadata,
+		"with_tags/sample.mbz.flac":      fullMetadata,
+		"with_tags/sample.mbz.m4a":       fullMetadata,
+		"with_tags/sample.pictures.mp3":  fullMetadata,
+		"with_tags/sample.pictures.flac": fullMetadata,
+		"with_tags/sample.itunsmpb.mp3":  fullMetadata,
 		"with_tags/sample.m4a":           fullMetadata,
+		"with_tags/sample.artists.m4a":   fullMetadata,
 		"with_tags/sample.mp4":           fullMetadata,
+		"with_tags/sample.tv.m4v":        fullMetadata,
+		"with_tags/sample.quicktime.m4a": fullMetadata,
+		"with_tags/sample.covr.m4a":      fullMetadata,
+		"with_tags/sample.mdat64.m4a":    fullMetadata,
+		"with_tags/sample.covr-hdlr.m4a": fullMetadata,
+		"with_tags/sample.credits.m4b":   fullMetadata,
+		"with_tags/sample.freeform.m4a":  fullMetadata,
+		"with_tags/sample.location.m4a":  fullMetadata,
+		"with_tags/sample.itunnorm.m4a":  fullMetadata,
+		"with_tags/sample.alac.m4a":      fullMetadata,
+		"with_tags/sample.chapters.m4a":  fullMetadata,
 		"with_tags/sample.ogg":           fullMetadata,
+		"with_tags/sample.oga":           fullMetadata,
+		"with_tags/sample.spx":           fullMetadata,
+		"with_tags/sample.bitrate.ogg":   fullMetadata,
 		"with_tags/sample.multipage.ogg": fullMetadata,
+		"with_tags/sample.granule.ogg":   fullMetadata,
+		"with_tags/sample.truncated.ogg": fullMetadata,
 		"with_tags/sample.dsf":           fullMetadata,
+		"with_tags/sample.wma":           asfMetadata,
+		"with_tags/sample.ape":           fullMetadata,
+		"with_tags/sample.wv":            fullMetadata,
+		"with_tags/sample.mpc":           fullMetadata,
+		"with_tags/sample.apev2.mp3":     fullMetadata,
 		"without_tags/sample.flac":       emptyMetadata,
 		"without_tags/sample.m4a":        emptyMetadata,
 		"without_tags/sample.mp3":        emptyMetadata,
 		"without_tags/sample.mp4":        emptyMetadata,
 		"without_tags/sample.ogg":        emptyMetadata,
+
+		"with_tags/sample.id3v1-junk.mp3":       mp3id3v11Metadata, // unrecognised front, ID3v1 trailer
+		"with_tags/sample.radio.id3v24.mp3":     fullMetadata,
+		"with_tags/sample.exthdr.id3v23.mp3":    fullMetadata,
+		"with_tags/sample.exthdr.id3v24.mp3":    fullMetadata,
+		"with_tags/sample.tkey-offkey.mp3":      fullMetadata,
+		"with_tags/sample.unknown-frames.mp3":   fullMetadata,
+		"with_tags/sample.id3v24-plainsize.mp3": fullMetadata, // v2.3 frame sizes
+		"with_tags/sample.timestamps.mp3":       fullMetadata,
+		"with_tags/sample.unknown-atoms.m4a":    fullMetadata,
+		"with_tags/sample.covr-nohdlr.m4a":      fullMetadata,
+		"with_tags/sample.replaygain.m4a":       fullMetadata,
+		"with_tags/sample.meta-nested.m4a":      fullMetadata,
+		"with_tags/sample.footer.id3v24.mp3":    fullMetadata,
 	}
 
 	for path, metadata := range testdata {
-		if err := test(t, path, metadata); err != nil {
+		err := test(t, path, metadata)
 
-			// mp3 id3v11 returns an err if it doesn't find any tags
-			if err != ErrNoTagsFound && path != "without_tags/sample.mp3" {
-				t.Error(err)
+		// An MP3 without an ID3v1 or ID3v2 tag has no metadata to return.
+		if path == "without_tags/sample.mp3" {
+			if !errors.Is(err, ErrNoTagsFound) {
+				t.Errorf("%v: ReadFrom() returned error %v, expected: %v", path, err, ErrNoTagsFound)
 			}
-
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", path, err)
 		}
 	}
 }
@@ -124,3 +201,740 @@ func testValue(t *testing.T, expected interface{}, found interface{}) {
 		t.Errorf("expected '%v', found '%v'", expected, found)
 	}
 }
+
+// readTestFile reads the metadata of the file at path (relative to testdata) with ReadFrom,
+// failing the test if there is an error or the Metadata is not a T.
+func readTestFile[T Metadata](t *testing.T, path string) T {
+	t.Helper()
+
+	f, err := os.Open("testdata/" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok := m.(T)
+	if !ok {
+		var want T
+		t.Fatalf("expected %T, got %T", want, m)
+	}
+	return v
+}
+
+func TestReadFromJunkBeforeID3v2(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, junk := range []string{"\xef\xbb\xbf", "junk", "\x00\x00 ID3\xff"} {
+		r := bytes.NewReader(append([]byte(junk), b...))
+		m, err := ReadFrom(r)
+		if err != nil {
+			t.Errorf("%q: ReadFrom() returned unexpected error: %v", junk, err)
+			continue
+		}
+		if m.Format() != ID3v2_4 {
+			t.Errorf("%q: Format() = %v, expected: %v", junk, m.Format(), ID3v2_4)
+		}
+		compareMetadata(t, m, fullMetadata)
+
+		r.Seek(0, io.SeekStart)
+		format, fileType, err := Identify(r)
+		if err != nil || format != ID3v2_4 || fileType != MP3 {
+			t.Errorf("%q: Identify() = %v, %v, %v, expected: %v, %v, <nil>", junk, format, fileType, err, ID3v2_4, MP3)
+		}
+	}
+}
+
+func TestReadFromJunkBeforeFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		format   Format
+		fileType FileType
+	}{
+		{"with_tags/sample.flac", VORBIS, FLAC},
+		{"with_tags/sample.ogg", VORBIS, OGG},
+		{"with_tags/sample.m4a", MP4, M4A},
+	}
+
+	for _, tt := range tests {
+		b, err := os.ReadFile("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := bytes.NewReader(append([]byte("\xef\xbb\xbf"), b...))
+		m, err := ReadFrom(r)
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+		if m.Format() != tt.format || m.FileType() != tt.fileType {
+			t.Errorf("%v: ReadFrom() = %v, %v, expected: %v, %v", tt.path, m.Format(), m.FileType(), tt.format, tt.fileType)
+		}
+		compareMetadata(t, m, fullMetadata)
+
+		r.Seek(0, io.SeekStart)
+		format, fileType, err := Identify(r)
+		if err != nil || format != tt.format || fileType != tt.fileType {
+			t.Errorf("%v: Identify() = %v, %v, %v, expected: %v, %v, <nil>", tt.path, format, fileType, err, tt.format, tt.fileType)
+		}
+	}
+}
+
+func TestReadFromOptionsSearchLimit(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	junk := bytes.Repeat([]byte{0x00}, 5000)
+
+	tests := []struct {
+		junk  []byte
+		limit int
+		ok    bool
+	}{
+		{junk, 0, false},
+		{junk, 8192, true},
+		{junk[:3], 0, true},
+		{junk[:3], -1, false},
+	}
+	for _, tt := range tests {
+		_, err := ReadFromOptions(bytes.NewReader(append(tt.junk, b...)), Options{SearchLimit: tt.limit})
+		if (err == nil) != tt.ok {
+			t.Errorf("%d bytes of junk, SearchLimit %d: ReadFromOptions() returned error %v, expected success: %v", len(tt.junk), tt.limit, err, tt.ok)
+		}
+	}
+}
+
+func BenchmarkReadFromMP3(b *testing.B) {
+	data, err := os.ReadFile("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadFrom(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPictureSummary(t *testing.T) {
+	front := PictureInfo{Type: "Cover (front)", MIMEType: "image/png", Size: 69}
+	back := PictureInfo{Type: "Cover (back)", MIMEType: "image/jpeg", Size: 22}
+	artist := PictureInfo{Type: "Artist/performer", MIMEType: "image/png", Size: 69}
+
+	tests := map[string][]PictureInfo{
+		"with_tags/sample.pictures.mp3":  {front, back, artist},
+		"with_tags/sample.pictures.flac": {front, back, artist},
+		"with_tags/sample.covr.m4a": {
+			{Type: "Cover (front)", MIMEType: "image/jpeg", Size: 22},
+			{Type: "Cover (front)", MIMEType: "image/png", Size: 69},
+		},
+		"with_tags/sample.m4a": nil,
+	}
+
+	for path, want := range tests {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+
+		s, ok := m.(interface{ PictureSummary() []PictureInfo })
+		if !ok {
+			t.Errorf("%v: %T does not implement PictureSummary()", path, m)
+			continue
+		}
+		if got := s.PictureSummary(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: PictureSummary() = %+v, expected: %+v", path, got, want)
+		}
+	}
+}
+
+func TestPictures(t *testing.T) {
+	tests := map[string][]string{
+		"with_tags/sample.pictures.mp3":  {"Cover (front)", "Cover (back)", "Artist/performer"},
+		"with_tags/sample.pictures.flac": {"Cover (front)", "Cover (back)", "Artist/performer"},
+		"with_tags/sample.pictures.ape":  {"Cover (front)", "Cover (back)"},
+		"with_tags/sample.pictures.wma":  {"Cover (front)", "Cover (back)"},
+		"with_tags/sample.covr.m4a":      {"Cover (front)", "Cover (front)"},
+		"with_tags/sample.m4a":           nil,
+		"with_tags/sample.id3v11.mp3":    nil,
+	}
+
+	for path, want := range tests {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+
+		pm, ok := m.(PicturesMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement PicturesMetadata", path, m)
+			continue
+		}
+		var got []string
+		for _, p := range pm.Pictures() {
+			got = append(got, p.Type)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: Pictures() types = %q, expected: %q", path, got, want)
+		}
+		if p := m.Picture(); p != nil && !containsPicture(pm.Pictures(), p) {
+			t.Errorf("%v: Pictures() does not contain Picture() = %v", path, p)
+		}
+	}
+}
+
+func containsPicture(pictures []*Picture, p *Picture) bool {
+	for _, x := range pictures {
+		if x == p {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDiscSubtitle(t *testing.T) {
+	tests := map[string]string{
+		"with_tags/sample.discsubtitle.mp3":          "Disc 2: The Remixes",
+		"with_tags/sample.discsubtitle.id3v24.mp3":   "Disc 2: The Remixes",
+		"with_tags/sample.discsubtitle.m4a":          "Disc 2: The Remixes",
+		"with_tags/sample.discsubtitle-freeform.m4a": "Disc 2: The Remixes",
+		"with_tags/sample.discsubtitle.flac":         "Disc 2: The Remixes",
+		"with_tags/sample.discsubtitle.ape":          "Disc 2: The Remixes",
+		"with_tags/sample.discsubtitle.wma":          "Disc 2: The Remixes",
+		"with_tags/sample.id3v24.mp3":                "",
+		"with_tags/sample.m4a":                       "",
+		"with_tags/sample.flac":                      "",
+	}
+
+	for path, want := range tests {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		d, ok := m.(DiscSubtitleMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement DiscSubtitleMetadata", path, m)
+			continue
+		}
+		if got := d.DiscSubtitle(); got != want {
+			t.Errorf("%v: DiscSubtitle() = %q, expected: %q", path, got, want)
+		}
+	}
+}
+
+func TestBPM(t *testing.T) {
+	tests := map[string]int{
+		"with_tags/sample.bpm.mp3":    128,
+		"with_tags/sample.bpm.m4a":    128,
+		"with_tags/sample.bpm.flac":   128, // BPM=127.6
+		"with_tags/sample.id3v24.mp3": 0,
+		"with_tags/sample.m4a":        0,
+		"with_tags/sample.flac":       0,
+		"with_tags/sample.id3v11.mp3": 0,
+	}
+
+	for path, want := range tests {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		b, ok := m.(BPMMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement BPMMetadata", path, m)
+			continue
+		}
+		if got := b.BPM(); got != want {
+			t.Errorf("%v: BPM() = %d, expected: %d", path, got, want)
+		}
+	}
+}
+
+func TestMovement(t *testing.T) {
+	type movementer interface {
+		Work() string
+		Movement() (name string, index, count int)
+	}
+
+	const work = "Symphony No. 9 in D minor, Op. 125"
+	const movement = "Presto - Allegro assai"
+	for _, path := range []string{
+		"with_tags/sample.movement.mp3",
+		"with_tags/sample.movement.m4a",
+		"with_tags/sample.movement.flac",
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		mm, ok := m.(movementer)
+		if !ok {
+			t.Errorf("%v: %T does not have Work and Movement methods", path, m)
+			continue
+		}
+		if got := mm.Work(); got != work {
+			t.Errorf("%v: Work() = %q, expected: %q", path, got, work)
+		}
+		name, index, count := mm.Movement()
+		if name != movement || index != 4 || count != 4 {
+			t.Errorf("%v: Movement() = %q, %d, %d, expected: %q, 4, 4", path, name, index, count, movement)
+		}
+	}
+
+	for _, path := range []string{"with_tags/sample.id3v24.mp3", "with_tags/sample.m4a", "with_tags/sample.flac"} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		mm := m.(movementer)
+		if name, index, count := mm.Movement(); mm.Work() != "" || name != "" || index != 0 || count != 0 {
+			t.Errorf("%v: Work(), Movement() = %q, %q, %d, %d, expected zero values", path, mm.Work(), name, index, count)
+		}
+	}
+}
+
+func TestReleaseIdentifiers(t *testing.T) {
+	type releaseIdentifiers interface {
+		CatalogNumber() string
+		Barcode() string
+	}
+
+	for _, path := range []string{
+		"with_tags/sample.release.mp3",
+		"with_tags/sample.release.m4a",
+		"with_tags/sample.release.flac",
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", path, err)
+			continue
+		}
+		if got := isrc(m); got != "USRC17607839" {
+			t.Errorf("%v: ISRC() = %q, expected: %q", path, got, "USRC17607839")
+		}
+		r, ok := m.(releaseIdentifiers)
+		if !ok {
+			t.Errorf("%v: %T does not have CatalogNumber and Barcode methods", path, m)
+			continue
+		}
+		if got := r.CatalogNumber(); got != "TEST-001" {
+			t.Errorf("%v: CatalogNumber() = %q, expected: %q", path, got, "TEST-001")
+		}
+		if got := r.Barcode(); got != "0123456789012" {
+			t.Errorf("%v: Barcode() = %q, expected: %q", path, got, "0123456789012")
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		path        string
+		track, disc Position
+	}{
+		{"with_tags/sample.id3v24.mp3", Position{3, 6, true}, Position{2, 0, false}},
+		{"with_tags/sample.id3v11.mp3", Position{3, 0, false}, Position{}},
+		{"with_tags/sample.m4a", Position{3, 6, true}, Position{2, 0, false}},
+		{"with_tags/sample.trkn-1of0.m4a", Position{1, 0, false}, Position{1, 0, false}},
+		{"with_tags/sample.trkn-text.m4a", Position{3, 6, true}, Position{2, 0, false}},
+		{"with_tags/sample.flac", Position{3, 6, true}, Position{2, 0, false}},
+		{"with_tags/sample.ape", Position{3, 6, true}, Position{2, 0, false}},
+		{"with_tags/sample.wma", Position{3, 0, false}, Position{2, 0, false}},
+		{"with_tags/sample.info.wav", Position{3, 6, true}, Position{}},
+		{"with_tags/sample.dsf", Position{3, 6, true}, Position{2, 0, false}},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+		p, ok := m.(PositionMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement PositionMetadata", tt.path, m)
+			continue
+		}
+		if got := p.TrackPosition(); got != tt.track {
+			t.Errorf("%v: TrackPosition() = %+v, expected: %+v", tt.path, got, tt.track)
+		}
+		if got := p.DiscPosition(); got != tt.disc {
+			t.Errorf("%v: DiscPosition() = %+v, expected: %+v", tt.path, got, tt.disc)
+		}
+
+		// The pair methods return the same values.
+		if x, n := m.Track(); x != tt.track.Number || n != tt.track.Total {
+			t.Errorf("%v: Track() = %d, %d, expected: %d, %d", tt.path, x, n, tt.track.Number, tt.track.Total)
+		}
+		if x, n := m.Disc(); x != tt.disc.Number || n != tt.disc.Total {
+			t.Errorf("%v: Disc() = %d, %d, expected: %d, %d", tt.path, x, n, tt.disc.Number, tt.disc.Total)
+		}
+	}
+}
+
+func TestReadFromBytes(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "with_tags", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFromBytes(b)
+
+		f, err2 := os.Open(path)
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+		want, wantErr := ReadFrom(f)
+		f.Close()
+
+		if (err == nil) != (wantErr == nil) {
+			t.Errorf("%v: ReadFromBytes() returned error %v, expected: %v", path, err, wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(m.Raw(), want.Raw()) {
+			t.Errorf("%v: ReadFromBytes().Raw() = %v, expected: %v", path, m.Raw(), want.Raw())
+		}
+	}
+}
+
+// cancelReadSeeker is an io.ReadSeeker which calls cancel on the first read.
+type cancelReadSeeker struct {
+	io.ReadSeeker
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadSeeker) Read(p []byte) (int, error) {
+	c.cancel()
+	return c.ReadSeeker.Read(p)
+}
+
+func TestReadFromContext(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.id3v24.mp3",
+		"with_tags/sample.m4a",
+		"with_tags/sample.flac",
+		"with_tags/sample.ogg",
+	} {
+		b, err := os.ReadFile("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ReadFromContext(context.Background(), bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%v: ReadFromContext() returned unexpected error: %v", path, err)
+			continue
+		}
+		compareMetadata(t, m, fullMetadata)
+
+		// Cancelled before and during parsing.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := ReadFromContext(ctx, bytes.NewReader(b)); err != context.Canceled {
+			t.Errorf("%v: ReadFromContext() with cancelled context returned error: %v, expected: %v", path, err, context.Canceled)
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		if _, err := ReadFromContext(ctx, &cancelReadSeeker{bytes.NewReader(b), cancel}); err != context.Canceled {
+			t.Errorf("%v: ReadFromContext() cancelled while reading returned error: %v, expected: %v", path, err, context.Canceled)
+		}
+	}
+
+	// The context is not used after ReadFromContext returns.
+	b, err := os.ReadFile("testdata/with_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m, err := ReadFromContext(ctx, bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := m.(*MetadataFLAC).WriteTo(io.Discard); err != nil {
+		t.Errorf("WriteTo() after cancel returned unexpected error: %v", err)
+	}
+}
+
+func TestReadFromErrors(t *testing.T) {
+	read := func(path string) []byte {
+		b, err := os.ReadFile("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// An MP4 file with an invalid size for the atom after ftyp.
+	m4a := read("with_tags/sample.m4a")
+	n := binary.BigEndian.Uint32(m4a)
+	binary.BigEndian.PutUint32(m4a[n:], 3)
+
+	tests := []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"no tags", read("without_tags/sample.mp3"), ErrNoTagsFound},
+		{"unsupported OGG codec", read("with_tags/sample.theora.ogg"), ErrUnsupportedFormat},
+		{"invalid ID3v2 version", append([]byte("ID3\x09\x00\x00\x00\x00\x00\x00"), read("without_tags/sample.mp3")...), ErrInvalidID3v2Header},
+		{"invalid MP4 atom size", m4a, ErrInvalidMP4},
+	}
+
+	sentinels := []error{ErrNoTagsFound, ErrUnsupportedFormat, ErrInvalidID3v2Header, ErrInvalidMP4}
+	for _, tt := range tests {
+		_, err := ReadFrom(bytes.NewReader(tt.b))
+		for _, e := range sentinels {
+			if errors.Is(err, e) != (e == tt.want) {
+				t.Errorf("%v: errors.Is(%v, %v) = %v, expected: %v", tt.name, err, e, !(e == tt.want), e == tt.want)
+			}
+		}
+	}
+
+	// ReadID3v2Tags of data without an ID3v2 tag.
+	_, err := ReadID3v2Tags(bytes.NewReader(read("with_tags/sample.flac")))
+	if !errors.Is(err, ErrInvalidID3v2Header) {
+		t.Errorf("ReadID3v2Tags() returned error: %v, expected: %v", err, ErrInvalidID3v2Header)
+	}
+}
+
+func TestReadFromReader(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.id3v23.mp3",
+		"with_tags/sample.id3v24.mp3",
+		"with_tags/sample.ogg",
+		"with_tags/sample.r128.opus",
+	} {
+		b, err := os.ReadFile("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ReadFrom(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Hide the Seek method.
+		got, err := ReadFromReader(struct{ io.Reader }{bytes.NewReader(b)})
+		if err != nil {
+			t.Errorf("%v: ReadFromReader() returned unexpected error: %v", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Raw(), want.Raw()) {
+			t.Errorf("%v: ReadFromReader() Raw() = %v, expected: %v", path, got.Raw(), want.Raw())
+		}
+	}
+
+	for _, path := range []string{
+		"with_tags/sample.m4a",
+		"with_tags/sample.flac",
+		"with_tags/sample.id3v11.mp3",
+	} {
+		b, err := os.ReadFile("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ReadFromReader(struct{ io.Reader }{bytes.NewReader(b)})
+		if !errors.Is(err, ErrSeekRequired) {
+			t.Errorf("%v: ReadFromReader() returned error: %v, expected: %v", path, err, ErrSeekRequired)
+		}
+	}
+}
+
+func TestReadID3v2TagsReader(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(b)
+	m, err := ReadID3v2TagsReader(struct{ io.Reader }{r})
+	if err != nil {
+		t.Fatalf("ReadID3v2TagsReader() returned unexpected error: %v", err)
+	}
+	compareMetadata(t, m, fullMetadata)
+
+	// The reader is left at the start of the audio data.
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, audioAfterID3v2(t, b)) {
+		t.Errorf("ReadID3v2TagsReader() left %d bytes, expected the %d bytes after the tag", len(rest), len(audioAfterID3v2(t, b)))
+	}
+}
+
+func TestEffectiveAlbumArtist(t *testing.T) {
+	tests := []struct {
+		path        string
+		compilation bool
+		want        string
+	}{
+		{"with_tags/sample.id3v23.mp3", false, "Test AlbumArtist"},
+		{"with_tags/sample.compilation.mp3", true, VariousArtists},
+		{"with_tags/sample.compilation-aa.mp3", true, "Test AlbumArtist"},
+		{"with_tags/sample.compilation.m4a", true, VariousArtists},
+		{"with_tags/sample.id3v11.mp3", false, "Test Artist"}, // no album artist
+		{"with_tags/sample.m4a", false, "Test AlbumArtist"},
+		{"with_tags/sample.noaart.m4a", false, "Test Artist"}, // ©ART but no aART
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: ReadFrom() returned unexpected error: %v", tt.path, err)
+			continue
+		}
+
+		c, ok := m.(CompilationMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement CompilationMetadata", tt.path, m)
+			continue
+		}
+		if got := c.Compilation(); got != tt.compilation {
+			t.Errorf("%v: Compilation() = %v, expected: %v", tt.path, got, tt.compilation)
+		}
+		if got := EffectiveAlbumArtist(m); got != tt.want {
+			t.Errorf("%v: EffectiveAlbumArtist() = %q, expected: %q", tt.path, got, tt.want)
+		}
+	}
+
+	// AlbumArtist returns only the stored value.
+	m := readTestFile[MetadataMP4](t, "with_tags/sample.noaart.m4a")
+	if got := m.AlbumArtist(); got != "" {
+		t.Errorf("AlbumArtist() = %q, expected: %q", got, "")
+	}
+}
+
+func TestErrNoTagsFound(t *testing.T) {
+	if !errors.Is(ErrNotID3v1, ErrNoTagsFound) {
+		t.Errorf("errors.Is(ErrNotID3v1, ErrNoTagsFound) = false, expected: true")
+	}
+	if !errors.Is(fmt.Errorf("reading tag: %w", ErrNotID3v1), ErrNoTagsFound) {
+		t.Errorf("errors.Is(wrapped ErrNotID3v1, ErrNoTagsFound) = false, expected: true")
+	}
+	if errors.Is(ErrNoTagsFound, ErrNotID3v1) {
+		t.Errorf("errors.Is(ErrNoTagsFound, ErrNotID3v1) = true, expected: false")
+	}
+
+	b, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadID3v1Tags(bytes.NewReader(b))
+	if !errors.Is(err, ErrNotID3v1) || !errors.Is(err, ErrNoTagsFound) {
+		t.Errorf("ReadID3v1Tags() returned error %v, expected: %v", err, ErrNotID3v1)
+	}
+
+	// Unrecognised leading bytes send ReadFrom to the ID3v1 fallback.
+	for _, junk := range []string{"", "junk", "\xef\xbb\xbf"} {
+		_, err := ReadFrom(bytes.NewReader(append([]byte(junk), b...)))
+		if !errors.Is(err, ErrNoTagsFound) {
+			t.Errorf("%q: ReadFrom() returned error %v, expected: %v", junk, err, ErrNoTagsFound)
+		}
+	}
+}
+
+func TestReadFromFile(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.id3v24.mp3",
+		"with_tags/sample.m4a",
+		"with_tags/sample.flac",
+		"with_tags/sample.ogg",
+		"with_tags/sample.id3v1-junk.mp3",
+	} {
+		m, err := ReadFromFile(filepath.Join("testdata", path))
+		if err != nil {
+			t.Errorf("%v: ReadFromFile() returned unexpected error: %v", path, err)
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join("testdata", path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ReadFrom(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m.Raw(), want.Raw()) {
+			t.Errorf("%v: ReadFromFile().Raw() = %v, expected: %v", path, m.Raw(), want.Raw())
+		}
+	}
+
+	_, err := ReadFromFile(filepath.Join("testdata", "without_tags", "sample.mp3"))
+	if !errors.Is(err, ErrNoTagsFound) {
+		t.Errorf("ReadFromFile() returned error %v, expected: %v", err, ErrNoTagsFound)
+	}
+	_, err = ReadFromFile(filepath.Join("testdata", "missing.mp3"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFromFile() returned error %v, expected: %v", err, fs.ErrNotExist)
+	}
+}
@@ -5,8 +5,11 @@
 package tag
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
 )
 
@@ -93,6 +96,22 @@ func read7BitChunkedUint(r io.Reader, n uint) (uint, error) {
 	return uint(get7BitChunkedInt(b)), nil
 }
 
+// sniffImageType detects the MIME type and file extension of the image data in b
+// from its header bytes, returning empty strings if the type is not recognised.
+func sniffImageType(b []byte) (mimeType, ext string) {
+	switch {
+	case bytes.HasPrefix(b, pngHeader):
+		return "image/png", "png"
+	case bytes.HasPrefix(b, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg", "jpg"
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		return "image/gif", "gif"
+	case bytes.HasPrefix(b, []byte("BM")):
+		return "image/bmp", "bmp"
+	}
+	return "", ""
+}
+
 func readUint32LittleEndian(r io.Reader) (uint32, error) {
 	b, err := readBytes(r, 4)
 	if err != nil {
@@ -100,3 +119,101 @@ func readUint32LittleEndian(r io.Reader) (uint32, error) {
 	}
 	return binary.LittleEndian.Uint32(b), nil
 }
+
+// countWriter is an io.Writer which counts the bytes written to the underlying io.Writer.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// bufferedReadSeeker is an io.ReadSeeker which buffers reads from an underlying
+// io.ReadSeeker (i.e. an *os.File), so that the many small reads made when parsing
+// tags do not each make a system call.  Seeks forward within the buffered data do not
+// touch the underlying io.ReadSeeker.
+type bufferedReadSeeker struct {
+	r   io.ReadSeeker
+	br  *bufio.Reader
+	pos int64 // position of the next byte returned by Read
+}
+
+func newBufferedReadSeeker(r io.ReadSeeker, size int) (*bufferedReadSeeker, error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedReadSeeker{
+		r:   r,
+		br:  bufio.NewReaderSize(r, size),
+		pos: pos,
+	}, nil
+}
+
+func (b *bufferedReadSeeker) Read(p []byte) (int, error) {
+	n, err := b.br.Read(p)
+	b.pos += int64(n)
+	return n, err
+}
+
+func (b *bufferedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = b.pos + offset
+	case io.SeekEnd:
+		n, err := b.r.Seek(offset, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		b.br.Reset(b.r)
+		b.pos = n
+		return n, nil
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if abs >= b.pos && abs-b.pos <= int64(b.br.Buffered()) {
+		_, err := b.br.Discard(int(abs - b.pos))
+		if err != nil {
+			return 0, err
+		}
+		b.pos = abs
+		return abs, nil
+	}
+
+	n, err := b.r.Seek(abs, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	b.br.Reset(b.r)
+	b.pos = n
+	return n, nil
+}
+
+// contextReadSeeker is an io.ReadSeeker which returns the error of ctx (rather than reading
+// or seeking) once ctx is done, so that parsing stops at the next read.
+type contextReadSeeker struct {
+	io.ReadSeeker
+	ctx context.Context
+}
+
+func (c *contextReadSeeker) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.ReadSeeker.Read(p)
+}
+
+func (c *contextReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.ReadSeeker.Seek(offset, whence)
+}
@@ -6,6 +6,7 @@ package tag
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -118,3 +119,68 @@ func TestGetUintLittleEndian(t *testing.T) {
 		}
 	}
 }
+
+func TestSniffImageType(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		mimeType string
+		ext      string
+	}{
+		{[]byte{}, "", ""},
+		{[]byte{0x00, 0x01, 0x02}, "", ""},
+		{[]byte{137, 80, 78, 71, 13, 10, 26, 10, 0x00}, "image/png", "png"},
+		{[]byte{0xFF, 0xD8, 0xFF, 0xE0}, "image/jpeg", "jpg"},
+		{[]byte("GIF89a\x01\x00"), "image/gif", "gif"},
+		{[]byte("BM\x00\x00"), "image/bmp", "bmp"},
+	}
+
+	for ii, tt := range tests {
+		mimeType, ext := sniffImageType(tt.input)
+		if mimeType != tt.mimeType || ext != tt.ext {
+			t.Errorf("[%d] sniffImageType(%v) = %q, %q, expected: %q, %q", ii, tt.input, mimeType, ext, tt.mimeType, tt.ext)
+		}
+	}
+}
+
+func TestBufferedReadSeeker(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	want := bytes.NewReader(data)
+	got, err := newBufferedReadSeeker(bytes.NewReader(data), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := []struct {
+		offset int64
+		whence int
+		read   int
+	}{
+		{0, io.SeekCurrent, 11},
+		{-11, io.SeekCurrent, 4}, // backwards
+		{3, io.SeekCurrent, 2},   // forwards within the buffer
+		{100, io.SeekCurrent, 8}, // forwards past the buffer
+		{-128, io.SeekEnd, 3},
+		{500, io.SeekStart, 40}, // read larger than the buffer
+		{0, io.SeekEnd, 1},      // EOF
+	}
+
+	for i, op := range ops {
+		wantPos, _ := want.Seek(op.offset, op.whence)
+		gotPos, err := got.Seek(op.offset, op.whence)
+		if err != nil || gotPos != wantPos {
+			t.Fatalf("[%d] Seek(%d, %d) = %d, %v, expected: %d", i, op.offset, op.whence, gotPos, err, wantPos)
+		}
+
+		wantBuf := make([]byte, op.read)
+		wantN, wantErr := io.ReadFull(want, wantBuf)
+		gotBuf := make([]byte, op.read)
+		gotN, gotErr := io.ReadFull(got, gotBuf)
+		if gotN != wantN || gotErr != wantErr || !bytes.Equal(gotBuf, wantBuf) {
+			t.Errorf("[%d] ReadFull() = %d, %v, %v, expected: %d, %v, %v", i, gotN, gotErr, gotBuf, wantN, wantErr, wantBuf)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Issue severities.
+const (
+	// SeverityError is used for problems which stop values being read, or cause them to
+	// be read incorrectly.
+	SeverityError = "error"
+
+	// SeverityWarning is used for problems which do not stop the values being read, but
+	// which other software may not handle.
+	SeverityWarning = "warning"
+)
+
+// Issue codes.
+const (
+	IssueReadFailed   = "read-failed"   // the tag structure could not be read, so the rest of the tag is not checked
+	IssueTagSize      = "tag-size"      // the tag extends past the end of the data
+	IssueFrameSize    = "frame-size"    // an ID3v2 frame extends past the end of the tag
+	IssueFrameDropped = "frame-dropped" // an ID3v2 frame cannot be decoded, so its value is not read
+	IssueTextEncoding = "text-encoding" // an ID3v2 frame has an invalid text encoding byte
+	IssueGenre        = "genre"         // a numeric genre is out of range
+	IssueEmptyPicture = "empty-picture" // a picture has no image data
+	IssueAtom         = "atom"          // an MP4 atom is malformed, so the atoms after it are not checked
+)
+
+// Issue is a problem with the tags found by Validate.
+type Issue struct {
+	Severity string // SeverityError or SeverityWarning
+	Code     string // one of the Issue codes (i.e. IssueFrameSize)
+	Message  string
+
+	// Offset is the position in the data of the frame, atom or tag with the problem, or -1
+	// if unknown.  For ID3v2.2 and ID3v2.3 tags which are unsynchronised as a whole, frame
+	// offsets are within the tag after unsynchronisation is removed.
+	Offset int64
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%v: %v at offset %d: %v", i.Severity, i.Code, i.Offset, i.Message)
+}
+
+// Validate checks the tags in the ReadSeeker and returns the problems found, without
+// extracting the values.  The structure of ID3v2, ID3v1 and MP4 tags is checked; tags in
+// other formats are not checked, so use ReadFrom to find if they can be read.
+//
+// The returned error is only non-nil if r could not be read, or its format could not be
+// identified (ErrNoTagsFound), not for problems with the tags.
+func Validate(r io.ReadSeeker) ([]Issue, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	format, _, err := Identify(r)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{}
+	switch format {
+	case ID3v2_2, ID3v2_3, ID3v2_4:
+		err = v.id3v2(r)
+		if err == nil {
+			err = v.id3v1(r)
+		}
+
+	case ID3v1:
+		err = v.id3v1(r)
+
+	case MP4:
+		err = v.mp4(r, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.issues, nil
+}
+
+// validator collects the Issues found by Validate.
+type validator struct {
+	issues []Issue
+	base   int64 // offset of the ID3v2 tag being read
+}
+
+func (v *validator) add(severity, code string, offset int64, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Offset:   offset,
+	})
+}
+
+// id3v2Issue adds an Issue at offset in the ID3v2 tag being read.  v can be nil (when the
+// tags are read by ReadFrom), in which case nothing is done.
+func (v *validator) id3v2Issue(severity, code string, offset uint, format string, args ...interface{}) {
+	if v == nil {
+		return
+	}
+	v.add(severity, code, v.base+int64(offset), format, args...)
+}
+
+// id3v2 checks the ID3v2 tag at the current position of r (or after junk, see
+// seekID3v2Header).  The frames are read with readID3v2Tags, which reports the problems it
+// finds, and an error which stops it reading them is reported as an IssueReadFailed.
+func (v *validator) id3v2(r io.ReadSeeker) error {
+	ok, err := seekID3v2Header(r)
+	if err != nil || !ok {
+		return err
+	}
+	v.base, err = r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err := readID3v2Tags(r, Options{validator: v}); err != nil {
+		v.add(SeverityError, IssueReadFailed, v.base, "%v", err)
+	}
+	return nil
+}
+
+// id3v2Frame checks the data b of the ID3v2 frame name at offset in the tag, after any
+// unsynchronisation and data length indicator are removed (see readID3v2Frames).  v can be
+// nil, in which case nothing is done.
+func (v *validator) id3v2Frame(name string, b []byte, h *id3v2Header, offset uint) {
+	if v == nil {
+		return
+	}
+
+	switch name {
+	case "TXXX", "TXX", "COMM", "COM", "USLT", "ULT", "APIC", "PIC":
+	default:
+		if name[0] != 'T' && !id3v2iTunesTextFrames[name] {
+			return
+		}
+	}
+
+	if len(b) == 0 {
+		return
+	}
+	switch enc := b[0]; {
+	case enc > 3:
+		v.id3v2Issue(SeverityError, IssueTextEncoding, offset, "%v frame has invalid text encoding %d", name, enc)
+		return
+
+	case enc > 1 && h.Version != ID3v2_4:
+		v.id3v2Issue(SeverityWarning, IssueTextEncoding, offset, "%v frame has text encoding %d, which is only defined for ID3v2.4", name, enc)
+	}
+
+	switch name {
+	case "TCON", "TCO":
+		genre, err := decodeText(b[0], b[1:], nil)
+		if err != nil {
+			return
+		}
+		for s := genre; ; s = s[1:] {
+			i := strings.IndexByte(s, '(')
+			if i < 0 {
+				break
+			}
+			s = s[i:]
+			end := strings.IndexByte(s, ')')
+			if end < 0 {
+				break
+			}
+			if n, err := strconv.Atoi(s[1:end]); err == nil && n >= len(id3v2Genres) {
+				v.id3v2Issue(SeverityWarning, IssueGenre, offset, "%v frame genre reference (%d) is out of range", name, n)
+			}
+		}
+
+	case "APIC", "PIC":
+		var p *Picture
+		var err error
+		if name == "APIC" {
+			p, err = readAPICFrame(b, nil)
+		} else {
+			p, err = readPICFrame(b, nil)
+		}
+		if err == nil && len(p.Data) == 0 {
+			v.id3v2Issue(SeverityWarning, IssueEmptyPicture, offset, "%v frame has no image data", name)
+		}
+	}
+}
+
+// id3v1 checks the ID3v1 tag at the end of r, if there is one.
+func (v *validator) id3v1(r io.ReadSeeker) error {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil || size < 128 {
+		return err
+	}
+	_, err = r.Seek(-128, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	b, err := readBytes(r, 128)
+	if err != nil {
+		return err
+	}
+	if string(b[0:3]) != "TAG" {
+		return nil
+	}
+
+	// 255 is used for "no genre".
+	if g := int(b[127]); g >= len(id3v1Genres) && g != 255 {
+		v.add(SeverityWarning, IssueGenre, size-128, "ID3v1 genre %d is out of range", g)
+	}
+	return nil
+}
+
+// mp4 checks the ilst items in r, which starts at start.  A problem with the atom structure
+// is reported as an IssueAtom, and the atoms after it are not checked.
+func (v *validator) mp4(r io.ReadSeeker, start int64) error {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	err = v.mp4Atoms(r, end-start, "")
+	if errors.Is(err, ErrInvalidMP4) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		offset, seekErr := r.Seek(0, io.SeekCurrent)
+		if seekErr != nil {
+			offset = -1
+		}
+		v.add(SeverityError, IssueAtom, offset, "%v", err)
+		return nil
+	}
+	return err
+}
+
+func (v *validator) mp4Atoms(r io.ReadSeeker, size int64, parent string) error {
+	return walkAtoms(r, size, func(name string, size int64) error {
+		switch {
+		case parent == "" && name == "moov",
+			parent == "moov" && name == "udta",
+			parent == "meta" && name == "ilst":
+			return v.mp4Atoms(r, size, name)
+
+		case parent == "udta" && name == "meta":
+			pos, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			err = skipMetaVersionFlags(r)
+			if err != nil {
+				return err
+			}
+			n, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			return v.mp4Atoms(r, size-(n-pos), name)
+
+		case parent == "ilst" && (name == "covr" || name == "gnre"):
+			pos, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			b, err := readBytes(r, uint(size))
+			if err != nil {
+				return err
+			}
+			v.mp4Item(name, b, pos)
+		}
+		return nil
+	})
+}
+
+// mp4Item checks the data atoms b of the ilst item name, where b is at offset.
+func (v *validator) mp4Item(name string, b []byte, offset int64) {
+	for off := 0; off+16 <= len(b); {
+		size := getInt(b[off : off+4])
+		if size < 16 || size > len(b)-off {
+			return
+		}
+		if string(b[off+4:off+8]) == "data" {
+			data := b[off+16 : off+size]
+			switch {
+			case name == "covr" && len(data) == 0:
+				v.add(SeverityWarning, IssueEmptyPicture, offset+int64(off), "covr has no image data")
+
+			case name == "gnre" && len(data) > 0:
+				// gnre is the ID3v1 genre index + 1.
+				if g := getInt(data); g < 1 || g > len(id3v1Genres) {
+					v.add(SeverityWarning, IssueGenre, offset+int64(off), "gnre genre %d is out of range", g)
+				}
+			}
+		}
+		off += size
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// frame returns an ID3v2.3 frame, or an ID3v2.4 frame if v4 is set.
+	frame := func(v4 bool, name string, flags byte, b []byte) []byte {
+		size := binary.BigEndian.AppendUint32(nil, uint32(len(b)))
+		if v4 {
+			size = synchsafe(len(b))
+		}
+		return append(append(append([]byte(name), size...), 0x00, flags), b...)
+	}
+	// tag returns an ID3v2 tag with the frames (and 16 bytes of padding).
+	tag := func(v4 bool, frames ...[]byte) []byte {
+		b := append(bytes.Join(frames, nil), make([]byte, 16)...)
+		header := []byte("ID3\x03\x00\x00")
+		if v4 {
+			header = []byte("ID3\x04\x00\x00")
+		}
+		return append(append(header, synchsafe(len(b))...), b...)
+	}
+	title := frame(false, "TIT2", 0x00, []byte("\x00Test Title"))
+
+	// overshoot has a TPE1 frame which claims 100 bytes more than the tag holds.
+	overshoot := tag(false, title, frame(false, "TPE1", 0x00, []byte("\x00Test Artist")))
+	binary.BigEndian.PutUint32(overshoot[10+len(title)+4:], uint32(len("\x00Test Artist")+16+100))
+
+	id3v1 := func(genre byte) []byte {
+		b := append([]byte("TAG"), make([]byte, 125)...)
+		b[127] = genre
+		return b
+	}
+
+	data := func(class uint32, b []byte) []byte {
+		return mp4Atom("data", binary.BigEndian.AppendUint32(nil, class), make([]byte, 4), b)
+	}
+	m4a := func(items ...[]byte) []byte {
+		return append(
+			mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")),
+			mp4Atom("moov", mp4Atom("udta", mp4Atom("meta", make([]byte, 4), mp4Atom("ilst", items...))))...,
+		)
+	}
+
+	tests := []struct {
+		name  string
+		b     []byte
+		codes []string
+	}{
+		{"frame size", overshoot, []string{IssueFrameSize, IssueReadFailed}},
+		{"invalid text encoding", tag(false, frame(false, "TIT2", 0x00, []byte("\x07Test Title"))), []string{IssueTextEncoding}},
+		{"ID3v2.4 text encoding", tag(false, frame(false, "TIT2", 0x00, []byte("\x03Test Title"))), []string{IssueTextEncoding}},
+		{"ID3v2 genre", tag(false, title, frame(false, "TCON", 0x00, []byte("\x00(17)(200)"))), []string{IssueGenre}},
+		{"empty APIC", tag(true, frame(true, "APIC", 0x00, []byte("\x00image/jpeg\x00\x03\x00"))), []string{IssueEmptyPicture}},
+		{"compressed frame", tag(true, title, frame(true, "TPE1", 0x08|0x01, []byte("\x00\x00\x00\x10xxxxxxxxxxxx"))), []string{IssueFrameDropped}},
+		{"unsynchronised frame", tag(true, frame(true, "TIT2", 0x02|0x01, []byte("\x00\x00"))), []string{IssueFrameDropped, IssueReadFailed}},
+		{"ID3v1 genre", id3v1(200), []string{IssueGenre}},
+		{"ID3v1 no genre", id3v1(255), nil},
+		{"ID3v2 and ID3v1", append(tag(false, title), id3v1(200)...), []string{IssueGenre}},
+		{"empty covr", m4a(mp4Atom("covr", data(13, nil))), []string{IssueEmptyPicture}},
+		{"MP4 genre", m4a(mp4Atom("gnre", data(0, []byte{0x00, 0xff}))), []string{IssueGenre}},
+		{"MP4", m4a(mp4Atom("gnre", data(0, []byte{0x00, 0x09})), mp4Atom("covr", data(13, []byte{0xff, 0xd8, 0xff}))), nil},
+		{"MP4 atom size", append(m4a(), "\x00\x00\x01\x00free"...), []string{IssueAtom}},
+	}
+
+	for _, tt := range tests {
+		issues, err := Validate(bytes.NewReader(tt.b))
+		if err != nil {
+			t.Errorf("%v: Validate() returned unexpected error: %v", tt.name, err)
+			continue
+		}
+		var codes []string
+		for _, i := range issues {
+			codes = append(codes, i.Code)
+		}
+		if !reflect.DeepEqual(codes, tt.codes) {
+			t.Errorf("%v: Validate() = %v, expected codes: %v", tt.name, issues, tt.codes)
+		}
+	}
+
+	issues, err := Validate(bytes.NewReader(overshoot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Issue{
+		Severity: SeverityError,
+		Code:     IssueFrameSize,
+		Message:  "TPE1 frame size 128 overshoots the end of the tag by 100 bytes",
+		Offset:   int64(10 + len(title)),
+	}
+	if len(issues) == 0 || issues[0] != want {
+		t.Errorf("Validate() = %v, expected first issue: %v", issues, want)
+	}
+}
+
+func TestValidateFiles(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.id3v22.mp3",
+		"with_tags/sample.id3v23.mp3",
+		"with_tags/sample.id3v24.mp3",
+		"with_tags/sample.id3v11.mp3",
+		"with_tags/sample.pictures.mp3",
+		"with_tags/sample.m4a",
+		"with_tags/sample.gnre.m4a",
+		"with_tags/sample.flac",
+		"with_tags/sample.ogg",
+	} {
+		b, err := os.ReadFile("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issues, err := Validate(bytes.NewReader(b))
+		if err != nil || len(issues) != 0 {
+			t.Errorf("%v: Validate() = %v, %v, expected: [], <nil>", path, issues, err)
+		}
+	}
+
+	b, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Validate(bytes.NewReader(b))
+	if !errors.Is(err, ErrNoTagsFound) {
+		t.Errorf("Validate() returned error %v, expected: %v", err, ErrNoTagsFound)
+	}
+}
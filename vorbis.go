@@ -22,8 +22,16 @@ func newMetadataVorbis() *metadataVorbis {
 }
 
 type metadataVorbis struct {
-	c map[string]string // the vorbis comments
-	p *Picture
+	c        map[string]string // the vorbis comments (the last value of each field)
+	fields   []vorbisField     // all of the vorbis comments, in the order they were read
+	p        *Picture
+	pictures []*Picture // all pictures, in the order they were read
+}
+
+// vorbisField is a Vorbis comment, with the field name as it was read.
+type vorbisField struct {
+	name  string
+	value string
 }
 
 func (m *metadataVorbis) readVorbisComment(r io.Reader) error {
@@ -56,11 +64,17 @@ func (m *metadataVorbis) readVorbisComment(r io.Reader) error {
 		if err != nil {
 			return err
 		}
+		// Field names are case insensitive, so are stored in lower case for the
+		// accessors and Raw().
 		m.c[strings.ToLower(k)] = v
+		m.fields = append(m.fields, vorbisField{k, v})
 	}
 
-	if b64data, ok := m.c["metadata_block_picture"]; ok {
-		data, err := base64.StdEncoding.DecodeString(b64data)
+	for _, f := range m.fields {
+		if !strings.EqualFold(f.name, "metadata_block_picture") {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(f.value)
 		if err != nil {
 			return err
 		}
@@ -107,20 +121,19 @@ func (m *metadataVorbis) readPictureBlock(r io.Reader) error {
 		return err
 	}
 
-	// We skip width <32>, height <32>, colorDepth <32>, coloresUsed <32>
-	_, err = readInt(r, 4) // width
+	width, err := readInt(r, 4)
 	if err != nil {
 		return err
 	}
-	_, err = readInt(r, 4) // height
+	height, err := readInt(r, 4)
 	if err != nil {
 		return err
 	}
-	_, err = readInt(r, 4) // color depth
+	colorDepth, err := readInt(r, 4)
 	if err != nil {
 		return err
 	}
-	_, err = readInt(r, 4) // colors used
+	_, err = readInt(r, 4) // colors used (for indexed-color pictures)
 	if err != nil {
 		return err
 	}
@@ -141,7 +154,11 @@ func (m *metadataVorbis) readPictureBlock(r io.Reader) error {
 		Type:        pictureType,
 		Description: desc,
 		Data:        data,
+		Width:       width,
+		Height:      height,
+		ColorDepth:  colorDepth,
 	}
+	m.pictures = append(m.pictures, m.p)
 	return nil
 }
 
@@ -250,6 +267,39 @@ func (m *metadataVorbis) Disc() (int, int) {
 	return x, n
 }
 
+func (m *metadataVorbis) TrackPosition() Position {
+	return vorbisPosition(m.c["tracknumber"], m.c["tracktotal"])
+}
+
+func (m *metadataVorbis) DiscPosition() Position {
+	return vorbisPosition(m.c["discnumber"], m.c["disctotal"])
+}
+
+// vorbisPosition returns the Position for the number and total fields, where HasTotal is
+// set if the total is a number.
+func vorbisPosition(number, total string) Position {
+	x, _ := strconv.Atoi(number)
+	n, err := strconv.Atoi(total)
+	return Position{Number: x, Total: n, HasTotal: err == nil}
+}
+
+func (m *metadataVorbis) DiscSubtitle() string {
+	return m.c["discsubtitle"]
+}
+
+// Work returns the name of the work (WORK, i.e. a symphony) that the track is part of,
+// or an empty string if unavailable.
+func (m *metadataVorbis) Work() string {
+	return m.c["work"]
+}
+
+// Movement returns the movement name (MOVEMENTNAME), number (MOVEMENT) and count
+// (MOVEMENTTOTAL), or zero values if unavailable.
+func (m *metadataVorbis) Movement() (name string, index, count int) {
+	p := vorbisPosition(m.c["movement"], m.c["movementtotal"])
+	return m.c["movementname"], p.Number, p.Total
+}
+
 func (m *metadataVorbis) Lyrics() string {
 	return m.c["lyrics"]
 }
@@ -261,6 +311,55 @@ func (m *metadataVorbis) Comment() string {
 	return m.c["description"]
 }
 
+func (m *metadataVorbis) ISRC() string {
+	return m.c["isrc"]
+}
+
+// CatalogNumber returns the release catalog number (CATALOGNUMBER), or an empty string if
+// unavailable.
+func (m *metadataVorbis) CatalogNumber() string {
+	return m.c["catalognumber"]
+}
+
+// Barcode returns the release barcode, i.e. the UPC or EAN (BARCODE), or an empty string
+// if unavailable.
+func (m *metadataVorbis) Barcode() string {
+	return m.c["barcode"]
+}
+
 func (m *metadataVorbis) Picture() *Picture {
 	return m.p
 }
+
+// Pictures returns the pictures from each of the FLAC picture blocks (or the
+// METADATA_BLOCK_PICTURE comment).
+func (m *metadataVorbis) Pictures() []*Picture {
+	return m.pictures
+}
+
+// ReplayGain returns the ReplayGain values from the REPLAYGAIN_* comments.  When these are
+// not present the (Opus) EBU R128 comments R128_TRACK_GAIN and R128_ALBUM_GAIN are used
+// instead, converted to the ReplayGain reference level.
+func (m *metadataVorbis) ReplayGain() ReplayGainInfo {
+	rg := ReplayGainInfo{
+		TrackGain: parseReplayGain(m.c["replaygain_track_gain"]),
+		TrackPeak: parseReplayGain(m.c["replaygain_track_peak"]),
+		AlbumGain: parseReplayGain(m.c["replaygain_album_gain"]),
+		AlbumPeak: parseReplayGain(m.c["replaygain_album_peak"]),
+	}
+	if _, ok := m.c["replaygain_track_gain"]; !ok {
+		rg.TrackGain, _ = parseR128Gain(m.c["r128_track_gain"])
+	}
+	if _, ok := m.c["replaygain_album_gain"]; !ok {
+		rg.AlbumGain, _ = parseR128Gain(m.c["r128_album_gain"])
+	}
+	return rg
+}
+
+func (m *metadataVorbis) BPM() int {
+	return parseBPM(m.c["bpm"])
+}
+
+func (m *metadataVorbis) Compilation() bool {
+	return m.c["compilation"] == "1"
+}
@@ -0,0 +1,337 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// flacMaxBlockSize is the largest FLAC metadata block, as the length is 24 bits.
+const flacMaxBlockSize = 1<<24 - 1
+
+// SetVorbisComment sets the Vorbis comment key (which is case insensitive) to value, or
+// removes it if value is empty.  Every existing comment for key is replaced by the one
+// value, which takes the place of the first of them.  The change is seen by the accessors
+// (i.e. Title for "TITLE") and is written by WriteTo.  The vendor string is set with the
+// key "vendor".
+func (m *metadataVorbis) SetVorbisComment(key, value string) {
+	key = strings.ToLower(key)
+	if value == "" {
+		delete(m.c, key)
+	} else {
+		m.c[key] = value
+	}
+	if key == "vendor" {
+		return
+	}
+
+	var fields []vorbisField
+	for _, f := range m.fields {
+		if !strings.EqualFold(f.name, key) {
+			fields = append(fields, f)
+			continue
+		}
+		if value != "" {
+			fields = append(fields, vorbisField{strings.ToUpper(key), value})
+			value = ""
+		}
+	}
+	if value != "" {
+		fields = append(fields, vorbisField{strings.ToUpper(key), value})
+	}
+	m.fields = fields
+}
+
+// encodeVorbisComment returns the comments as a Vorbis comment block (without a framing
+// bit).  All of the comments read are written, in the same order and with the field names
+// as they were read, other than those changed by SetVorbisComment, which are written in
+// upper case.
+// Vendor length    <32> (little endian)
+// Vendor string    <vendor length bytes>
+// Comment count    <32> (little endian)
+// Comments:
+//
+//	Comment length <32> (little endian)
+//	Comment        <comment length bytes> (NAME=value)
+func (m *metadataVorbis) encodeVorbisComment() ([]byte, error) {
+	var b bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&b, binary.LittleEndian, uint32(len(s)))
+		b.WriteString(s)
+	}
+
+	writeString(m.c["vendor"])
+	binary.Write(&b, binary.LittleEndian, uint32(len(m.fields)))
+	for _, f := range m.fields {
+		if err := checkVorbisFieldName(f.name); err != nil {
+			return nil, err
+		}
+		writeString(f.name + "=" + f.value)
+	}
+	return b.Bytes(), nil
+}
+
+// checkVorbisFieldName returns an error if k is not a valid Vorbis comment field name,
+// which is one or more ASCII characters in the range 0x20 to 0x7D, other than '='.
+func checkVorbisFieldName(k string) error {
+	if k == "" {
+		return errors.New("invalid Vorbis comment: empty field name")
+	}
+	for i := 0; i < len(k); i++ {
+		if k[i] < 0x20 || k[i] > 0x7d || k[i] == '=' {
+			return fmt.Errorf("invalid Vorbis comment field name: %q", k)
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the FLAC file with the Vorbis comments (see SetVorbisComment) to w.  The
+// VORBIS_COMMENT block is replaced (or added after STREAMINFO if there is none), and the
+// other metadata blocks and the audio frames are copied from the io.ReadSeeker the metadata
+// was read from, which must still be open (so this cannot be used with ReadFromFile).
+// Ogg FLAC streams cannot be written.
+func (m *MetadataFLAC) WriteTo(w io.Writer) (int64, error) {
+	if m.src == nil {
+		return 0, errors.New("cannot write Ogg FLAC stream")
+	}
+	comment, err := m.encodeVorbisComment()
+	if err != nil {
+		return 0, err
+	}
+	if len(comment) > flacMaxBlockSize {
+		return 0, fmt.Errorf("VORBIS_COMMENT block too large: %d bytes", len(comment))
+	}
+
+	_, err = m.src.Seek(m.start+4, io.SeekStart) // "fLaC"
+	if err != nil {
+		return 0, err
+	}
+
+	type block struct {
+		t    blockType
+		data []byte
+	}
+	var blocks []block
+	replaced := false
+	for last := false; !last; {
+		h, err := readBytes(m.src, 4)
+		if err != nil {
+			return 0, err
+		}
+		last = getBit(h[0], 7)
+		t := blockType(h[0] &^ (1 << 7))
+		data, err := readBytes(m.src, uint(getInt(h[1:4])))
+		if err != nil {
+			return 0, err
+		}
+
+		if t == vorbisCommentBlock {
+			if replaced {
+				continue // there should only be one
+			}
+			data, replaced = comment, true
+		}
+		blocks = append(blocks, block{t, data})
+	}
+	if !replaced {
+		if len(blocks) == 0 || blocks[0].t != streamInfoBlock {
+			return 0, errors.New("expected STREAMINFO block")
+		}
+		blocks = append(blocks[:1], append([]block{{vorbisCommentBlock, comment}}, blocks[1:]...)...)
+	}
+
+	cw := &countWriter{w: w}
+	_, err = io.WriteString(cw, "fLaC")
+	if err != nil {
+		return cw.n, err
+	}
+	for i, b := range blocks {
+		// Block type <7>, with the "last block" bit set on the last block
+		// Length     <24>
+		h := byte(b.t)
+		if i == len(blocks)-1 {
+			h |= 1 << 7
+		}
+		n := len(b.data)
+		_, err = cw.Write(append([]byte{h, byte(n >> 16), byte(n >> 8), byte(n)}, b.data...))
+		if err != nil {
+			return cw.n, err
+		}
+	}
+
+	_, err = io.Copy(cw, m.src)
+	return cw.n, err
+}
+
+// WriteTo writes the OGG stream with the Vorbis comments (see SetVorbisComment) to w.  The
+// comment header packet is rebuilt and repaginated (along with any packets which share its
+// pages, i.e. the Vorbis setup header), and the sequence numbers of the following pages are
+// adjusted.  The other pages are copied from the io.ReadSeeker the metadata was read from,
+// which must still be open (so this cannot be used with ReadFromFile).
+func (m *MetadataOGG) WriteTo(w io.Writer) (int64, error) {
+	if m.src == nil {
+		return 0, errors.New("cannot write OGG stream which was not read from an io.ReadSeeker")
+	}
+	comment, err := m.encodeVorbisComment()
+	if err != nil {
+		return 0, err
+	}
+	comment = append(append(append([]byte{}, m.commentPrefix...), comment...), m.commentSuffix...)
+	if bytes.Equal(m.commentPrefix, vorbisCommentPrefix) {
+		comment = append(comment, 1) // framing bit
+	}
+
+	_, err = m.src.Seek(m.start, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	// The identification header is on the first page, on its own.
+	cw := &countWriter{w: w}
+	first, err := readOGGPage(m.src)
+	if err != nil {
+		return 0, err
+	}
+	_, err = cw.Write(first.bytes())
+	if err != nil {
+		return cw.n, err
+	}
+	serial := first.SerialNumber
+
+	// Read the pages up to the end of the page on which the comment header, and any packets
+	// following it on the same pages, ends.  The audio data starts on a new page.
+	var packets [][]byte
+	var packet []byte
+	var pages uint32
+	var partial bool // the last packet continues on the next page
+	for pending := true; pending; {
+		p, err := readOGGPage(m.src)
+		if err != nil {
+			if err == io.EOF {
+				return cw.n, fmt.Errorf("reading comment header: %w", ErrTruncated)
+			}
+			return cw.n, err
+		}
+		if p.SerialNumber != serial {
+			// Pages of other multiplexed streams are kept in place.
+			_, err = cw.Write(p.bytes())
+			if err != nil {
+				return cw.n, err
+			}
+			continue
+		}
+		pages++
+
+		var pos int
+		for _, s := range p.segmentTable {
+			packet = append(packet, p.data[pos:pos+int(s)]...)
+			pos += int(s)
+			if s < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+			partial = s == 255
+		}
+		pending = len(packets) == 0 || partial
+	}
+	if !bytes.HasPrefix(packets[0], m.commentPrefix) {
+		return cw.n, errors.New("expected comment header packet")
+	}
+	packets[0] = comment
+
+	headers := oggPages(packets, serial, first.SequenceNumber+1)
+	for _, p := range headers {
+		_, err = cw.Write(p.bytes())
+		if err != nil {
+			return cw.n, err
+		}
+	}
+
+	// The sequence numbers wrap, so delta is also added if there are now fewer pages.
+	delta := uint32(len(headers)) - pages
+	if delta == 0 {
+		_, err = io.Copy(cw, m.src)
+		return cw.n, err
+	}
+	for {
+		p, err := readOGGPage(m.src)
+		if err == io.EOF {
+			return cw.n, nil
+		}
+		if err != nil {
+			return cw.n, err
+		}
+		if p.SerialNumber == serial {
+			p.SequenceNumber += delta
+		}
+		_, err = cw.Write(p.bytes())
+		if err != nil {
+			return cw.n, err
+		}
+	}
+}
+
+// oggPages returns the packets split into pages of the stream serial, numbered from seq.
+// Pages on which a packet ends have granule position 0 (as for header packets).
+func oggPages(packets [][]byte, serial, seq uint32) []*oggPage {
+	var pages []*oggPage
+	var p *oggPage
+	for _, b := range packets {
+		for continued := false; ; continued = true {
+			if p == nil || len(p.segmentTable) == 255 {
+				p = &oggPage{
+					oggPageHeader: oggPageHeader{
+						Magic:           [4]byte{'O', 'g', 'g', 'S'},
+						GranulePosition: oggNoGranulePosition,
+						SerialNumber:    serial,
+						SequenceNumber:  seq + uint32(len(pages)),
+					},
+				}
+				if continued {
+					p.Flags = 0x1
+				}
+				pages = append(pages, p)
+			}
+
+			// Each packet is split into segments of 255 bytes, and ends with a
+			// shorter (possibly empty) segment.
+			n := len(b)
+			if n > 255 {
+				n = 255
+			}
+			p.segmentTable = append(p.segmentTable, byte(n))
+			p.data = append(p.data, b[:n]...)
+			b = b[n:]
+			if n < 255 {
+				p.GranulePosition = 0
+				break
+			}
+		}
+	}
+	return pages
+}
+
+// bytes returns the encoded page, with the segment count and CRC computed from the
+// segment table and data.
+func (p *oggPage) bytes() []byte {
+	h := p.oggPageHeader
+	h.Segments = uint8(len(p.segmentTable))
+	h.CRC = 0
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, h)
+	b.Write(p.segmentTable)
+	b.Write(p.data)
+
+	out := b.Bytes()
+	crc := oggCRCUpdate(0, oggCRC32Poly04c11db7, out)
+	binary.LittleEndian.PutUint32(out[22:26], crc)
+	return out
+}
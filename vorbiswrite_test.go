@@ -0,0 +1,241 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFLACWriteTo(t *testing.T) {
+	for _, path := range []string{
+		"testdata/with_tags/sample.flac",
+		"testdata/with_tags/sample.pictures.flac",
+		"testdata/without_tags/sample.flac",
+	} {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFLACTags(bytes.NewReader(original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := m.(*MetadataFLAC)
+
+		long := strings.Repeat("x", 100000)
+		want.SetVorbisComment("Title", "New Title")
+		want.SetVorbisComment("COMMENT", "")
+		want.SetVorbisComment("description", "")
+		want.SetVorbisComment("LYRICS", long)
+
+		var buf bytes.Buffer
+		n, err := want.WriteTo(&buf)
+		if err != nil {
+			t.Errorf("%v: WriteTo() returned unexpected error: %v", path, err)
+			continue
+		}
+		if n != int64(buf.Len()) {
+			t.Errorf("%v: WriteTo() = %d, expected: %d", path, n, buf.Len())
+		}
+
+		m, err = ReadFLACTags(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%v: ReadFLACTags() returned unexpected error: %v", path, err)
+			continue
+		}
+		got := m.(*MetadataFLAC)
+		testValue(t, "New Title", got.Title())
+		testValue(t, "", got.Comment())
+		testValue(t, long, got.Lyrics())
+		if !reflect.DeepEqual(got.Raw(), want.Raw()) {
+			t.Errorf("%v: Raw() = %v, expected: %v", path, got.Raw(), want.Raw())
+		}
+		if got.StreamInfo() != want.StreamInfo() {
+			t.Errorf("%v: StreamInfo() = %v, expected: %v", path, got.StreamInfo(), want.StreamInfo())
+		}
+		if !reflect.DeepEqual(got.Pictures(), want.Pictures()) {
+			t.Errorf("%v: Pictures() differ after WriteTo()", path)
+		}
+
+		if !bytes.Equal(audioAfterRegion(t, buf.Bytes()), audioAfterRegion(t, original)) {
+			t.Errorf("%v: audio data was not preserved", path)
+		}
+	}
+}
+
+func TestOGGWriteTo(t *testing.T) {
+	for _, path := range []string{
+		"testdata/with_tags/sample.ogg",
+		"testdata/with_tags/sample.multipage.ogg",
+		"testdata/with_tags/sample.r128.opus",
+		"testdata/with_tags/sample.spx",
+	} {
+		for _, title := range []string{"New Title", strings.Repeat("x", 100000), ""} {
+			original, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := ReadOGGTags(bytes.NewReader(original))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := m.(*MetadataOGG)
+			want.SetVorbisComment("TITLE", title)
+
+			var buf bytes.Buffer
+			_, err = want.WriteTo(&buf)
+			if err != nil {
+				t.Errorf("%v: WriteTo() returned unexpected error: %v", path, err)
+				continue
+			}
+
+			m, err = ReadOGGTags(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Errorf("%v: ReadOGGTags() returned unexpected error: %v", path, err)
+				continue
+			}
+			got := m.(*MetadataOGG)
+			testValue(t, len(title), len(got.Title()))
+			if !reflect.DeepEqual(got.Raw(), want.Raw()) {
+				t.Errorf("%v: Raw() = %v, expected: %v", path, got.Raw(), want.Raw())
+			}
+			testValue(t, want.FileType(), got.FileType())
+
+			// All but the comment header packet are unchanged, and the pages are numbered
+			// in sequence.
+			wantPackets := readOGGTestPackets(t, original)
+			gotPackets := readOGGTestPackets(t, buf.Bytes())
+			if len(gotPackets) != len(wantPackets) {
+				t.Errorf("%v: read %d packets, expected: %d", path, len(gotPackets), len(wantPackets))
+				continue
+			}
+			for i := range gotPackets {
+				if i != 1 && !bytes.Equal(gotPackets[i], wantPackets[i]) {
+					t.Errorf("%v: packet %d differs after WriteTo()", path, i)
+				}
+			}
+		}
+	}
+}
+
+func TestVorbisWriteFields(t *testing.T) {
+	// sample.fields.opus has two ARTIST comments (in different case), two pictures and
+	// binary data after the comments.
+	path := "testdata/with_tags/sample.fields.opus"
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadOGGTags(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := m.(*MetadataOGG)
+	if len(want.Pictures()) != 2 {
+		t.Fatalf("Pictures() returned %d pictures, expected: 2", len(want.Pictures()))
+	}
+	want.SetVorbisComment("artist", "New Artist")
+	want.SetVorbisComment("Genre", "Jazz")
+
+	var buf bytes.Buffer
+	_, err = want.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() returned unexpected error: %v", err)
+	}
+	m, err = ReadOGGTags(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadOGGTags() returned unexpected error: %v", err)
+	}
+	got := m.(*MetadataOGG)
+
+	var names []string
+	for _, f := range got.fields {
+		names = append(names, f.name)
+	}
+	wantNames := []string{"TITLE", "ARTIST", "METADATA_BLOCK_PICTURE", "METADATA_BLOCK_PICTURE", "ALBUM", "GENRE"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("fields = %v, expected: %v", names, wantNames)
+	}
+	testValue(t, "New Artist", got.Artist())
+	testValue(t, "Jazz", got.Genre())
+	if !reflect.DeepEqual(got.Pictures(), want.Pictures()) {
+		t.Errorf("Pictures() = %v, expected: %v", got.Pictures(), want.Pictures())
+	}
+	testValue(t, "\x01binary data", string(got.commentSuffix))
+}
+
+func TestVorbisWriteToInvalid(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFLACTags(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.(*MetadataFLAC).SetVorbisComment("A=B", "C")
+	_, err = m.(*MetadataFLAC).WriteTo(io.Discard)
+	if err == nil {
+		t.Errorf("WriteTo() with invalid field name returned nil error")
+	}
+
+	// Without a seekable source the stream cannot be copied.
+	b, err = os.ReadFile("testdata/with_tags/sample.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadOGGTags(struct{ io.Reader }{bytes.NewReader(b)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.(*MetadataOGG).WriteTo(io.Discard)
+	if err == nil {
+		t.Errorf("WriteTo() without source returned nil error")
+	}
+}
+
+// audioAfterRegion returns the data which follows the metadata region (see MetadataRegion) in b.
+func audioAfterRegion(t *testing.T, b []byte) []byte {
+	t.Helper()
+	start, length, _, err := MetadataRegion(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b[start+length:]
+}
+
+// readOGGTestPackets returns the packets of the OGG stream b, checking that the pages of
+// each stream have consecutive sequence numbers.
+func readOGGTestPackets(t *testing.T, b []byte) [][]byte {
+	t.Helper()
+	r := bytes.NewReader(b)
+	od := &oggDemuxer{}
+	seq := make(map[uint32]uint32)
+	var packets [][]byte
+	for r.Len() > 0 {
+		start := r.Size() - int64(r.Len())
+		page, err := readOGGPage(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, ok := seq[page.SerialNumber]; ok && page.SequenceNumber != n+1 {
+			t.Errorf("page sequence number %d, expected: %d", page.SequenceNumber, n+1)
+		}
+		seq[page.SerialNumber] = page.SequenceNumber
+
+		r.Seek(start, io.SeekStart)
+		bs, err := od.Read(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, bs...)
+	}
+	return packets
+}
@@ -0,0 +1,449 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Marker is a type which represents a cue point (marker) in a WAV file.
+type Marker struct {
+	Position uint32 // Position of the marker (sample offset).
+	Label    string // Label of the marker (from the LIST/adtl chunk), or an empty string.
+}
+
+// wavInfoNames maps the names used by the accessors onto the RIFF INFO chunk IDs, in
+// order of preference.
+var wavInfoNames = map[string][]string{
+	"title":     {"INAM"},
+	"artist":    {"IART"},
+	"album":     {"IPRD"},
+	"composer":  {"IMUS"},
+	"genre":     {"IGNR"},
+	"date":      {"ICRD"},
+	"track":     {"ITRK", "IPRT"},
+	"comment":   {"ICMT"},
+	"source":    {"ISRC"},
+	"copyright": {"ICOP"},
+	"software":  {"ISFT"},
+}
+
+// ReadWAVTags reads WAV (RIFF) metadata from the io.ReadSeeker, returning the resulting
+// metadata in a Metadata implementation, or non-nil error if there was a problem.
+// The Metadata returned can be asserted to *MetadataWAV to access WAV-specific metadata.
+// Both little-endian (RIFF) and big-endian (RIFX) files are supported.
+func ReadWAVTags(r io.ReadSeeker) (Metadata, error) {
+	return readWAVTags(r, Options{})
+}
+
+// readWAVTags implements ReadWAVTags, with the options opts (for the id3 chunk).
+func readWAVTags(r io.ReadSeeker, opts Options) (Metadata, error) {
+	riff, err := readString(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MetadataWAV{
+		labels: make(map[uint32]string),
+		info:   make(map[string]string),
+	}
+	switch riff {
+	case "RIFF":
+		m.order = binary.LittleEndian
+	case "RIFX":
+		m.order = binary.BigEndian
+	default:
+		return nil, errors.New("expected 'RIFF' or 'RIFX'")
+	}
+
+	// RIFF chunk size
+	_, err = readBytes(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	wave, err := readString(r, 4)
+	if err != nil {
+		return nil, err
+	}
+	if wave != "WAVE" {
+		return nil, errors.New("expected 'WAVE'")
+	}
+
+	for {
+		id, err := readString(r, 4)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		b, err := readBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		size := m.order.Uint32(b)
+
+		switch id {
+		case "fmt ":
+			err = m.readFmtChunk(r, size)
+
+		case "data":
+			m.dataSize = size
+			_, err = r.Seek(int64(size), io.SeekCurrent)
+
+		case "cue ":
+			err = m.readCueChunk(r, size)
+
+		case "LIST":
+			err = m.readListChunk(r, size)
+
+		case "id3 ", "ID3 ":
+			err = m.readID3Chunk(r, size, opts)
+
+		default:
+			_, err = r.Seek(int64(size), io.SeekCurrent)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Chunks are word aligned, the pad byte is not included in the size.
+		if size%2 == 1 {
+			_, err = r.Seek(1, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// MetadataWAV is the implementation of Metadata for WAV files.  The Metadata returned by
+// ReadWAVTags can be asserted to *MetadataWAV to access WAV-specific metadata.
+type MetadataWAV struct {
+	order     binary.ByteOrder  // little endian (RIFF) or big endian (RIFX)
+	info      map[string]string // LIST/INFO items, keyed by chunk ID
+	id3       Metadata          // embedded ID3v2 tag, or nil
+	cuePoints []wavCuePoint
+	labels    map[uint32]string // cue point labels, keyed by cue point ID
+	format    wavFormat
+	dataSize  uint32 // size of the data chunk
+}
+
+// wavFormat is the content of the fmt chunk (common to all format tags).
+type wavFormat struct {
+	FormatTag     uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+type wavCuePoint struct {
+	ID           uint32
+	Position     uint32
+	DataChunkID  [4]byte
+	ChunkStart   uint32
+	BlockStart   uint32
+	SampleOffset uint32
+}
+
+// fmt chunk
+// Format tag       <16>
+// Channels         <16>
+// Sample rate      <32>
+// Byte rate        <32>
+// Block align      <16>
+// Bits per sample  <16>
+// Extension        <size - 16 bytes> (not read)
+func (m *MetadataWAV) readFmtChunk(r io.Reader, size uint32) error {
+	b, err := readBytes(r, uint(size))
+	if err != nil {
+		return err
+	}
+	if len(b) < 16 {
+		return fmt.Errorf("invalid fmt chunk: expected at least %d bytes, got %d", 16, len(b))
+	}
+	return binary.Read(bytes.NewReader(b), m.order, &m.format)
+}
+
+// WAVStreamInfo is a type which contains the information from the WAV fmt chunk.
+type WAVStreamInfo struct {
+	FormatTag     int // Format tag (i.e. 1 for PCM, 3 for IEEE float, 0xFFFE for extensible).
+	Channels      int // Number of channels.
+	SampleRate    int // Sample rate in Hz.
+	ByteRate      int // Average bytes per second.
+	BlockAlign    int // Size of one sample for all channels, in bytes.
+	BitsPerSample int // Bits per sample.
+}
+
+// StreamInfo returns the information from the fmt chunk, which is zero if there is none.
+func (m *MetadataWAV) StreamInfo() WAVStreamInfo {
+	return WAVStreamInfo{
+		FormatTag:     int(m.format.FormatTag),
+		Channels:      int(m.format.Channels),
+		SampleRate:    int(m.format.SampleRate),
+		ByteRate:      int(m.format.ByteRate),
+		BlockAlign:    int(m.format.BlockAlign),
+		BitsPerSample: int(m.format.BitsPerSample),
+	}
+}
+
+// SampleRate returns the sample rate in Hz, or 0 if there is no fmt chunk.
+func (m *MetadataWAV) SampleRate() int {
+	return int(m.format.SampleRate)
+}
+
+// Channels returns the number of channels, or 0 if there is no fmt chunk.
+func (m *MetadataWAV) Channels() int {
+	return int(m.format.Channels)
+}
+
+// Duration returns the duration of the audio, computed from the size of the data chunk
+// and the byte rate from the fmt chunk, or 0 if either is unavailable.
+func (m *MetadataWAV) Duration() time.Duration {
+	if m.format.ByteRate == 0 {
+		return 0
+	}
+	rate := uint64(m.format.ByteRate)
+	size := uint64(m.dataSize)
+	return time.Duration(size/rate)*time.Second + time.Duration(size%rate)*time.Second/time.Duration(rate)
+}
+
+// cue chunk
+// Cue points count  <32>
+// Cue points        <count * 24 bytes>
+func (m *MetadataWAV) readCueChunk(r io.Reader, size uint32) error {
+	b, err := readBytes(r, uint(size))
+	if err != nil {
+		return err
+	}
+	if len(b) < 4 {
+		return fmt.Errorf("invalid cue chunk: expected at least %d bytes, got %d", 4, len(b))
+	}
+
+	n := m.order.Uint32(b[0:4])
+	if uint64(n)*24 > uint64(len(b)-4) {
+		return fmt.Errorf("invalid cue chunk: %d cue points do not fit in %d bytes", n, len(b)-4)
+	}
+
+	m.cuePoints = make([]wavCuePoint, n)
+	return binary.Read(bytes.NewReader(b[4:]), m.order, m.cuePoints)
+}
+
+// LIST chunk
+// List type   <4 bytes>
+// Sub-chunks  <size - 4 bytes>
+func (m *MetadataWAV) readListChunk(r io.Reader, size uint32) error {
+	b, err := readBytes(r, uint(size))
+	if err != nil {
+		return err
+	}
+	if len(b) < 4 {
+		return fmt.Errorf("invalid LIST chunk: expected at least %d bytes, got %d", 4, len(b))
+	}
+
+	listType := string(b[0:4])
+	if listType != "adtl" && listType != "INFO" {
+		return nil
+	}
+	b = b[4:]
+
+	for len(b) >= 8 {
+		id := string(b[0:4])
+		n := m.order.Uint32(b[4:8])
+		b = b[8:]
+		if uint64(n) > uint64(len(b)) {
+			return fmt.Errorf("invalid %q chunk in LIST/%v: expected %d bytes, got %d", id, listType, n, len(b))
+		}
+		data := b[:n]
+
+		if n%2 == 1 && n < uint32(len(b)) {
+			n++
+		}
+		b = b[n:]
+
+		switch {
+		case listType == "INFO":
+			// NUL-terminated text
+			m.info[id] = trimString(string(data))
+
+		case id == "labl" && len(data) >= 4:
+			// cue point ID <32>, NUL-terminated text
+			m.labels[m.order.Uint32(data[0:4])] = trimString(string(data[4:]))
+		}
+	}
+	return nil
+}
+
+// id3 chunk
+// ID3v2 tag  <size bytes>
+func (m *MetadataWAV) readID3Chunk(r io.Reader, size uint32, opts Options) error {
+	b, err := readBytes(r, uint(size))
+	if err != nil {
+		return err
+	}
+
+	m.id3, err = readID3v2Chunk(b, opts)
+	return err
+}
+
+// Markers returns the cue points (markers) in the file along with their labels, or nil if
+// there are none.
+func (m *MetadataWAV) Markers() []Marker {
+	if len(m.cuePoints) == 0 {
+		return nil
+	}
+
+	markers := make([]Marker, 0, len(m.cuePoints))
+	for _, c := range m.cuePoints {
+		markers = append(markers, Marker{
+			Position: c.SampleOffset,
+			Label:    m.labels[c.ID],
+		})
+	}
+	return markers
+}
+
+// getInfo returns the LIST/INFO value for the name n, falling back to the value
+// from the embedded ID3v2 tag (using id3) if there is one.
+func (m *MetadataWAV) getInfo(n string, id3 func(Metadata) string) string {
+	for _, id := range wavInfoNames[n] {
+		if m.info[id] != "" {
+			return m.info[id]
+		}
+	}
+	if m.id3 != nil && id3 != nil {
+		return id3(m.id3)
+	}
+	return ""
+}
+
+// Format returns RIFFINFO if the file has a LIST/INFO chunk, otherwise the format
+// of the embedded ID3v2 tag (if any).
+func (m *MetadataWAV) Format() Format {
+	if len(m.info) > 0 {
+		return RIFFINFO
+	}
+	if m.id3 != nil {
+		return m.id3.Format()
+	}
+	return UnknownFormat
+}
+
+func (*MetadataWAV) FileType() FileType { return WAV }
+
+func (m *MetadataWAV) Raw() map[string]interface{} {
+	raw := make(map[string]interface{}, len(m.info))
+	if m.id3 != nil {
+		for k, v := range m.id3.Raw() {
+			raw[k] = v
+		}
+	}
+	for k, v := range m.info {
+		raw[k] = v
+	}
+	return raw
+}
+
+func (m *MetadataWAV) Title() string       { return m.getInfo("title", Metadata.Title) }
+func (m *MetadataWAV) Album() string       { return m.getInfo("album", Metadata.Album) }
+func (m *MetadataWAV) Artist() string      { return m.getInfo("artist", Metadata.Artist) }
+func (m *MetadataWAV) AlbumArtist() string { return m.getInfo("", Metadata.AlbumArtist) }
+func (m *MetadataWAV) Composer() string    { return m.getInfo("composer", Metadata.Composer) }
+func (m *MetadataWAV) Genre() string       { return m.getInfo("genre", Metadata.Genre) }
+func (m *MetadataWAV) Lyrics() string      { return m.getInfo("", Metadata.Lyrics) }
+func (m *MetadataWAV) Comment() string     { return m.getInfo("comment", Metadata.Comment) }
+func (m *MetadataWAV) ISRC() string        { return m.getInfo("", isrc) }
+
+func (m *MetadataWAV) DiscSubtitle() string { return m.getInfo("", discSubtitle) }
+
+func (m *MetadataWAV) Year() int {
+	// ICRD is usually a full date (i.e. 2006-01-02).
+	date := m.getInfo("date", nil)
+	if len(date) >= 4 {
+		year, _ := strconv.Atoi(date[:4])
+		return year
+	}
+	if m.id3 != nil {
+		return m.id3.Year()
+	}
+	return 0
+}
+
+func (m *MetadataWAV) Track() (int, int) {
+	if t := m.getInfo("track", nil); t != "" {
+		return parseXofN(t)
+	}
+	if m.id3 != nil {
+		return m.id3.Track()
+	}
+	return 0, 0
+}
+
+func (m *MetadataWAV) Disc() (int, int) {
+	if m.id3 != nil {
+		return m.id3.Disc()
+	}
+	return 0, 0
+}
+
+func (m *MetadataWAV) TrackPosition() Position {
+	if t := m.getInfo("track", nil); t != "" {
+		return parsePosition(t)
+	}
+	if p, ok := m.id3.(PositionMetadata); ok {
+		return p.TrackPosition()
+	}
+	return Position{}
+}
+
+func (m *MetadataWAV) DiscPosition() Position {
+	if p, ok := m.id3.(PositionMetadata); ok {
+		return p.DiscPosition()
+	}
+	return Position{}
+}
+
+func (m *MetadataWAV) Picture() *Picture {
+	if m.id3 != nil {
+		return m.id3.Picture()
+	}
+	return nil
+}
+
+func (m *MetadataWAV) Pictures() []*Picture {
+	if p, ok := m.id3.(PicturesMetadata); ok {
+		return p.Pictures()
+	}
+	return nil
+}
+
+func (m *MetadataWAV) ReplayGain() ReplayGainInfo {
+	if r, ok := m.id3.(ReplayGainMetadata); ok {
+		return r.ReplayGain()
+	}
+	return ReplayGainInfo{}
+}
+
+func (m *MetadataWAV) BPM() int {
+	if b, ok := m.id3.(BPMMetadata); ok {
+		return b.BPM()
+	}
+	return 0
+}
+
+func (m *MetadataWAV) Compilation() bool {
+	return compilation(m.id3)
+}
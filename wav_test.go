@@ -0,0 +1,141 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWAVMarkers(t *testing.T) {
+	m := readTestFile[*MetadataWAV](t, "with_tags/sample.markers.wav")
+
+	want := []Marker{
+		{Position: 0, Label: "Intro"},
+		{Position: 400, Label: "Verse"},
+	}
+	if got := m.Markers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Markers() = %#v, expected: %#v", got, want)
+	}
+}
+
+// wavInfoMetadata is the metadata in the LIST/INFO chunk of the WAV fixtures, which
+// has no album artist or disc.
+var wavInfoMetadata = testMetadata{
+	Album:      "Test Album",
+	Artist:     "Test Artist",
+	Comment:    "Test Comment",
+	Composer:   "Test Composer",
+	Genre:      "Jazz",
+	Title:      "Test Title",
+	Track:      3,
+	TrackTotal: 6,
+	Year:       2000,
+}
+
+func TestWAVInfo(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.info.wav",
+		"with_tags/sample.rifx.wav", // big-endian
+	} {
+		m := readTestFile[*MetadataWAV](t, path)
+		if m.Format() != RIFFINFO {
+			t.Errorf("%v: Format() = %v, expected: %v", path, m.Format(), RIFFINFO)
+		}
+		compareMetadata(t, m, wavInfoMetadata)
+	}
+}
+
+func TestWAVInfoTrackPreference(t *testing.T) {
+	// sample.iprt.wav has both IPRT (5) and ITRK (3) chunks, and ITRK is preferred.
+	for i := 0; i < 10; i++ {
+		m := readTestFile[*MetadataWAV](t, "with_tags/sample.iprt.wav")
+		if n, _ := m.Track(); n != 3 {
+			t.Fatalf("Track() = %d, expected: %d", n, 3)
+		}
+	}
+}
+
+func TestWAVID3Chunk(t *testing.T) {
+	// sample.id3.wav has an odd length id3 chunk (followed by a pad byte), then cue and
+	// LIST/adtl chunks.
+	m := readTestFile[*MetadataWAV](t, "with_tags/sample.id3.wav")
+	if m.Format() != ID3v2_3 {
+		t.Errorf("Format() = %v, expected: %v", m.Format(), ID3v2_3)
+	}
+	compareMetadata(t, m, fullMetadata)
+
+	if got := len(m.Markers()); got != 2 {
+		t.Errorf("len(Markers()) = %d, expected: %d", got, 2)
+	}
+}
+
+func TestWAVDuration(t *testing.T) {
+	// 1600 bytes of 8kHz 16-bit mono audio.
+	want := 100 * time.Millisecond
+
+	for _, path := range []string{
+		"with_tags/sample.info.wav",
+		"with_tags/sample.rifx.wav",
+		"with_tags/sample.markers.wav",
+		"with_tags/sample.id3.wav",
+	} {
+		m := readTestFile[*MetadataWAV](t, path)
+		if got := m.Duration(); got != want {
+			t.Errorf("%v: Duration() = %v, expected: %v", path, got, want)
+		}
+	}
+}
+
+func TestWAVStreamInfo(t *testing.T) {
+	tests := []struct {
+		path     string
+		want     WAVStreamInfo
+		duration time.Duration
+	}{
+		{"with_tags/sample.info.wav", WAVStreamInfo{
+			FormatTag:     1,
+			Channels:      1,
+			SampleRate:    8000,
+			ByteRate:      16000,
+			BlockAlign:    2,
+			BitsPerSample: 16,
+		}, 100 * time.Millisecond},
+		{"with_tags/sample.rifx.wav", WAVStreamInfo{
+			FormatTag:     1,
+			Channels:      1,
+			SampleRate:    8000,
+			ByteRate:      16000,
+			BlockAlign:    2,
+			BitsPerSample: 16,
+		}, 100 * time.Millisecond},
+		// 11025 bytes of 22.05kHz 8-bit stereo audio.
+		{"without_tags/sample.stereo.wav", WAVStreamInfo{
+			FormatTag:     1,
+			Channels:      2,
+			SampleRate:    22050,
+			ByteRate:      44100,
+			BlockAlign:    2,
+			BitsPerSample: 8,
+		}, 250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		m := readTestFile[*MetadataWAV](t, tt.path)
+		if got := m.StreamInfo(); got != tt.want {
+			t.Errorf("%v: StreamInfo() = %+v, expected: %+v", tt.path, got, tt.want)
+		}
+		if got := m.SampleRate(); got != tt.want.SampleRate {
+			t.Errorf("%v: SampleRate() = %v, expected: %v", tt.path, got, tt.want.SampleRate)
+		}
+		if got := m.Channels(); got != tt.want.Channels {
+			t.Errorf("%v: Channels() = %v, expected: %v", tt.path, got, tt.want.Channels)
+		}
+		if got := m.Duration(); got != tt.duration {
+			t.Errorf("%v: Duration() = %v, expected: %v", tt.path, got, tt.duration)
+		}
+	}
+}